@@ -32,11 +32,11 @@ if [[ $1x = "gtas"x ]]; then
     buildtvm
     buildp2p
 
-    go build -o ${output_dir}/gtas $basepath/cmd/gtas &&
+    go build -tags tvm_python -o ${output_dir}/gtas $basepath/cmd/gtas &&
     echo build gtas successfully...
 
 elif [[ $1x = "tvmcli"x ]]; then
-    go build $basepath/cmd/tvmcli &&
+    go build -tags tvm_python $basepath/cmd/tvmcli &&
     echo build tvmcli successfully...
 elif [[ $1x = "clean"x ]]; then
     rm $basepath/tvm/tvm.h $basepath/tvm/libtvm.a
@@ -22,7 +22,10 @@ import (
 	"crypto/rand"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -37,13 +40,10 @@ import (
 const accountUnLockTime = time.Second * 120
 
 var encryptPrivateKey *common.PrivateKey
-var encryptPublicKey *common.PublicKey
 
-//Generate public and private keys based on passwords
+// Fixed key of the legacy LevelDB keystore, only used to read the accounts not yet migrated to the key files
 func init() {
 	encryptPrivateKey = common.HexToSecKey("0x04b851c3551779125a588b2274cfa6d71604fe6ae1f0df82175bcd6e6c2b23d92a69d507023628b59c15355f3cbc0d8f74633618facd28632a0fb3e9cc8851536c4b3f1ea7c7fd3666ce8334301236c2437d9bed14e5a0793b51a9a6e7a4c46e70")
-	pk := encryptPrivateKey.GetPubKey()
-	encryptPublicKey = &pk
 }
 
 const (
@@ -52,8 +52,11 @@ const (
 )
 const DefaultPassword = "123"
 
+// AccountManager manages the accounts in the keystore directory, each of which is sealed in a JSON key file
+// with its password. Accounts in the legacy LevelDB keystore are migrated to key files on the first unlock
 type AccountManager struct {
-	store    *tasdb.LDBDatabase
+	dir      string
+	legacy   *tasdb.LDBDatabase // nil if there is no legacy keystore in the directory
 	accounts sync.Map
 
 	unlockAccount *AccountInfo
@@ -75,11 +78,16 @@ func (ai *AccountInfo) resetExpireTime() {
 }
 
 type Account struct {
-	Address  string
-	Pk       string
-	Sk       string
+	Address string
+	Pk      string
+	Sk      string
+	Miner   *MinerRaw
+}
+
+// legacyAccount is the account stored in the legacy LevelDB keystore with the sha256 of the password
+type legacyAccount struct {
+	Account
 	Password string
-	Miner    *MinerRaw
 }
 
 type MinerRaw struct {
@@ -97,21 +105,37 @@ func dirExists(dir string) bool {
 	return f.IsDir()
 }
 
-func newAccountOp(ks string) (*AccountManager, error) {
-	options := &opt.Options{
-		OpenFilesCacheCapacity:        10,
-		WriteBuffer:                   8 * opt.MiB, // Two of these are used internally
-		Filter:                        filter.NewBloomFilter(10),
-		CompactionTableSize:           2 * opt.MiB,
-		CompactionTableSizeMultiplier: 2,
-	}
-	db, err := tasdb.NewLDBDatabase(ks, options)
+func fileExists(path string) bool {
+	f, err := os.Stat(path)
 	if err != nil {
-		return nil, fmt.Errorf("new ldb fail:%v", err.Error())
+		return false
+	}
+	return !f.IsDir()
+}
+
+func newAccountOp(ks string) (*AccountManager, error) {
+	if err := os.MkdirAll(ks, 0700); err != nil {
+		return nil, fmt.Errorf("create keystore dir fail:%v", err.Error())
+	}
+	am := &AccountManager{
+		dir: ks,
+	}
+	// Open the legacy LevelDB keystore if exists, the accounts in it are migrated on unlock
+	if fileExists(filepath.Join(ks, "CURRENT")) {
+		options := &opt.Options{
+			OpenFilesCacheCapacity:        10,
+			WriteBuffer:                   8 * opt.MiB, // Two of these are used internally
+			Filter:                        filter.NewBloomFilter(10),
+			CompactionTableSize:           2 * opt.MiB,
+			CompactionTableSizeMultiplier: 2,
+		}
+		db, err := tasdb.NewLDBDatabase(ks, options)
+		if err != nil {
+			return nil, fmt.Errorf("new ldb fail:%v", err.Error())
+		}
+		am.legacy = db
 	}
-	return &AccountManager{
-		store: db,
-	}, nil
+	return am, nil
 }
 
 func initAccountManager(keystore string, readyOnly bool) (accountOp, error) {
@@ -136,10 +160,18 @@ func initAccountManager(keystore string, readyOnly bool) (accountOp, error) {
 	return aop, nil
 }
 
-func (am *AccountManager) loadAccount(addr string) (*Account, error) {
-	v, err := am.store.Get([]byte(addr))
+func (am *AccountManager) keyFilePath(addr string) string {
+	return filepath.Join(am.dir, addr+keyFileSuffix)
+}
+
+// loadLegacyAccount reads the account from the legacy keystore without checking the password
+func (am *AccountManager) loadLegacyAccount(addr string) (*legacyAccount, error) {
+	if am.legacy == nil {
+		return nil, ErrAccountNotExist
+	}
+	v, err := am.legacy.Get([]byte(addr))
 	if err != nil {
-		return nil, err
+		return nil, ErrAccountNotExist
 	}
 
 	bs, err := encryptPrivateKey.Decrypt(rand.Reader, v)
@@ -147,7 +179,7 @@ func (am *AccountManager) loadAccount(addr string) (*Account, error) {
 		return nil, err
 	}
 
-	var acc = new(Account)
+	var acc = new(legacyAccount)
 	err = json.Unmarshal(bs, acc)
 	if err != nil {
 		return nil, err
@@ -155,33 +187,135 @@ func (am *AccountManager) loadAccount(addr string) (*Account, error) {
 	return acc, nil
 }
 
-func (am *AccountManager) storeAccount(account *Account) error {
-	bs, err := json.Marshal(account)
-	if err != nil {
+// migrateAccount seals the legacy account into the key file with the password and removes it from the legacy keystore
+func (am *AccountManager) migrateAccount(account *Account, password string) error {
+	if err := am.storeAccount(account, password); err != nil {
 		return err
 	}
+	if err := am.legacy.Delete([]byte(account.Address)); err != nil {
+		return err
+	}
+	fmt.Printf("account %v migrated to key file\n", account.Address)
+	return nil
+}
+
+// migrateAll migrates all the legacy accounts sealed with the password to key files, and returns the migrated
+// addresses and the reason why each of the others failed. Accounts with other passwords stay in the legacy keystore
+func (am *AccountManager) migrateAll(password string) (migrated []string, failed map[string]error) {
+	migrated = make([]string, 0)
+	failed = make(map[string]error)
+	if am.legacy == nil {
+		return
+	}
+	addrs := make([]string, 0)
+	iter := am.legacy.NewIterator()
+	for iter.Next() {
+		addrs = append(addrs, string(iter.Key()))
+	}
+	iter.Release()
+
+	for _, addr := range addrs {
+		acc, err := am.loadLegacyAccount(addr)
+		if err != nil {
+			failed[addr] = fmt.Errorf("read legacy account fail:%v", err)
+			continue
+		}
+		if acc.Password != passwordSha(password) {
+			failed[addr] = ErrPassword
+			continue
+		}
+		if fileExists(am.keyFilePath(addr)) {
+			failed[addr] = fmt.Errorf("key file already exists")
+			continue
+		}
+		if err := am.migrateAccount(&acc.Account, password); err != nil {
+			failed[addr] = fmt.Errorf("migrate account fail:%v", err)
+			continue
+		}
+		migrated = append(migrated, addr)
+	}
+	return
+}
+
+// loadAccount decrypts the account with the password, the legacy account is migrated if the password matches
+func (am *AccountManager) loadAccount(addr string, password string) (*Account, error) {
+	path := am.keyFilePath(addr)
+	if fileExists(path) {
+		kf, err := readKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return kf.open(password)
+	}
 
-	ct, err := common.Encrypt(rand.Reader, encryptPublicKey, bs)
+	acc, err := am.loadLegacyAccount(addr)
+	if err != nil {
+		return nil, err
+	}
+	if acc.Password != passwordSha(password) {
+		return nil, ErrPassword
+	}
+	if err := am.migrateAccount(&acc.Account, password); err != nil {
+		return nil, fmt.Errorf("migrate account fail:%v", err)
+	}
+	return &acc.Account, nil
+}
+
+func (am *AccountManager) storeAccount(account *Account, password string) error {
+	kf, err := sealAccount(account, password)
 	if err != nil {
 		return err
 	}
+	return writeKeyFile(am.keyFilePath(account.Address), kf)
+}
 
-	err = am.store.Put([]byte(account.Address), ct)
-	return err
+// addresses returns the addresses of the key files and the legacy accounts not yet migrated
+func (am *AccountManager) addresses() []string {
+	addrs := make([]string, 0)
+	files, _ := ioutil.ReadDir(am.dir)
+	for _, f := range files {
+		if !f.IsDir() && strings.HasSuffix(f.Name(), keyFileSuffix) {
+			addrs = append(addrs, strings.TrimSuffix(f.Name(), keyFileSuffix))
+		}
+	}
+	if am.legacy != nil {
+		iter := am.legacy.NewIterator()
+		defer iter.Release()
+		for iter.Next() {
+			addrs = append(addrs, string(iter.Key()))
+		}
+	}
+	return addrs
 }
 
-func (am *AccountManager) getFirstMinerAccount() *Account {
-	iter := am.store.NewIterator()
-	for iter.Next() {
-		if ac, err := am.getAccountInfo(string(iter.Key())); err != nil {
-			panic(fmt.Sprintf("getAccountInfo err,addr=%v,err=%v", string(iter.Key()), err.Error()))
-		} else {
-			if ac.Miner != nil {
-				return &ac.Account
-			}
+// isMiner checks whether the account is a miner account without the password
+func (am *AccountManager) isMiner(addr string) (bool, error) {
+	path := am.keyFilePath(addr)
+	if fileExists(path) {
+		kf, err := readKeyFile(path)
+		if err != nil {
+			return false, err
 		}
+		return kf.Miner, nil
 	}
-	return nil
+	acc, err := am.loadLegacyAccount(addr)
+	if err != nil {
+		return false, err
+	}
+	return acc.Miner != nil, nil
+}
+
+func (am *AccountManager) getFirstMinerAccount(password string) (*Account, error) {
+	for _, addr := range am.addresses() {
+		miner, err := am.isMiner(addr)
+		if err != nil {
+			return nil, fmt.Errorf("check account err,addr=%v,err=%v", addr, err.Error())
+		}
+		if miner {
+			return am.loadAccount(addr, password)
+		}
+	}
+	return nil, nil
 }
 
 func (am *AccountManager) resetExpireTime(addr string) {
@@ -192,21 +326,12 @@ func (am *AccountManager) resetExpireTime(addr string) {
 	acc.resetExpireTime()
 }
 
+// getAccountInfo returns the account unlocked before
 func (am *AccountManager) getAccountInfo(addr string) (*AccountInfo, error) {
-	var aci *AccountInfo
 	if v, ok := am.accounts.Load(addr); ok {
-		aci = v.(*AccountInfo)
-	} else {
-		acc, err := am.loadAccount(addr)
-		if err != nil {
-			return nil, err
-		}
-		aci = &AccountInfo{
-			Account: *acc,
-		}
-		am.accounts.Store(addr, aci)
+		return v.(*AccountInfo), nil
 	}
-	return aci, nil
+	return nil, ErrUnlocked
 }
 
 func (am *AccountManager) currentUnLockedAddr() string {
@@ -227,10 +352,9 @@ func (am *AccountManager) NewAccount(password string, miner bool) *Result {
 	address := pubkey.GetAddress()
 
 	account := &Account{
-		Address:  address.Hex(),
-		Pk:       pubkey.Hex(),
-		Sk:       privateKey.Hex(),
-		Password: passwordSha(password),
+		Address: address.Hex(),
+		Pk:      pubkey.Hex(),
+		Sk:      privateKey.Hex(),
 	}
 
 	if miner {
@@ -244,7 +368,7 @@ func (am *AccountManager) NewAccount(password string, miner bool) *Result {
 		}
 		account.Miner = minerRaw
 	}
-	if err := am.storeAccount(account); err != nil {
+	if err := am.storeAccount(account, password); err != nil {
 		return opError(err)
 	}
 
@@ -253,12 +377,7 @@ func (am *AccountManager) NewAccount(password string, miner bool) *Result {
 
 // AccountList show account list
 func (am *AccountManager) AccountList() *Result {
-	iter := am.store.NewIterator()
-	addrs := make([]string, 0)
-	for iter.Next() {
-		addrs = append(addrs, string(iter.Key()))
-	}
-	return opSuccess(addrs)
+	return opSuccess(am.addresses())
 }
 
 // Lock lock the account by address
@@ -273,22 +392,21 @@ func (am *AccountManager) Lock(addr string) *Result {
 
 // UnLock unlock the account by address and password
 func (am *AccountManager) UnLock(addr string, password string) *Result {
-	aci, err := am.getAccountInfo(addr)
+	acc, err := am.loadAccount(addr, password)
 	if err != nil {
 		return opError(err)
 	}
-	if aci.Password != passwordSha(password) {
-		return opError(ErrPassword)
-	}
 	am.mu.Lock()
 	defer am.mu.Unlock()
 
-	if am.unlockAccount != nil && aci.Address != am.unlockAccount.Address {
+	if am.unlockAccount != nil && acc.Address != am.unlockAccount.Address {
 		am.unlockAccount.Status = statusLocked
 	}
 
+	aci := &AccountInfo{Account: *acc}
 	aci.Status = statusUnLocked
 	aci.resetExpireTime()
+	am.accounts.Store(addr, aci)
 	am.unlockAccount = aci
 
 	return opSuccess(nil)
@@ -325,10 +443,71 @@ func (am *AccountManager) DeleteAccount() *Result {
 		return opError(ErrUnlocked)
 	}
 	am.accounts.Delete(addr)
-	am.store.Delete([]byte(addr))
+	if err := os.Remove(am.keyFilePath(addr)); err != nil && !os.IsNotExist(err) {
+		return opError(err)
+	}
+	if am.legacy != nil {
+		am.legacy.Delete([]byte(addr))
+	}
 	return opSuccess(nil)
 }
 
+// ExportAccount copies the key file of the account to the given path, the secrets stay sealed with the password.
+// Accounts in the legacy keystore need to be unlocked once to be migrated before exporting
+func (am *AccountManager) ExportAccount(addr string, path string) *Result {
+	kf, err := readKeyFile(am.keyFilePath(addr))
+	if err != nil {
+		if _, lerr := am.loadLegacyAccount(addr); lerr == nil {
+			return opError(fmt.Errorf("please unlock the account first to migrate it to key file"))
+		}
+		return opError(ErrAccountNotExist)
+	}
+	if fileExists(path) {
+		return opError(fmt.Errorf("file already exists: %v", path))
+	}
+	if err := writeKeyFile(path, kf); err != nil {
+		return opError(err)
+	}
+	return opSuccess(path)
+}
+
+// ImportAccount imports the key file at the given path into the keystore, the password is required to verify the file
+func (am *AccountManager) ImportAccount(path string, password string) *Result {
+	kf, err := readKeyFile(path)
+	if err != nil {
+		return opError(err)
+	}
+	if _, err := kf.open(password); err != nil {
+		return opError(err)
+	}
+	if fileExists(am.keyFilePath(kf.Address)) {
+		return opError(fmt.Errorf("account already exists: %v", kf.Address))
+	}
+	if _, err := am.loadLegacyAccount(kf.Address); err == nil {
+		return opError(fmt.Errorf("account already exists: %v", kf.Address))
+	}
+	if err := writeKeyFile(am.keyFilePath(kf.Address), kf); err != nil {
+		return opError(err)
+	}
+	return opSuccess(kf.Address)
+}
+
+// MigrateAccounts migrates all the legacy accounts sealed with the password to key files, the accounts failed are
+// listed with the reasons
+func (am *AccountManager) MigrateAccounts(password string) *Result {
+	migrated, failed := am.migrateAll(password)
+	reasons := make(map[string]string, len(failed))
+	for addr, err := range failed {
+		reasons[addr] = err.Error()
+	}
+	return opSuccess(map[string]interface{}{
+		"migrated": migrated,
+		"failed":   reasons,
+	})
+}
+
 func (am *AccountManager) Close() {
-	am.store.Close()
+	if am.legacy != nil {
+		am.legacy.Close()
+	}
 }
@@ -26,9 +26,12 @@ import (
 )
 
 // ExplorerAccount is used in the blockchain browser to query account information
-func (api *GtasAPI) ExplorerAccount(hash string) (*Result, error) {
+func (api *ExplorerAPI) ExplorerAccount(hash string, block *BlockSelector) (*Result, error) {
 
-	accoundDb := core.BlockChainImpl.LatestStateDB()
+	accoundDb, err := stateAt(block)
+	if err != nil {
+		return failResult(err.Error())
+	}
 	if accoundDb == nil {
 		return nil, nil
 	}
@@ -58,7 +61,7 @@ func (api *GtasAPI) ExplorerAccount(hash string) (*Result, error) {
 }
 
 // ExplorerBlockDetail is used in the blockchain browser to query block details
-func (api *GtasAPI) ExplorerBlockDetail(height uint64) (*Result, error) {
+func (api *ExplorerAPI) ExplorerBlockDetail(height uint64) (*Result, error) {
 	chain := core.BlockChainImpl
 	b := chain.QueryBlockCeil(height)
 	if b == nil {
@@ -92,7 +95,7 @@ func (api *GtasAPI) ExplorerBlockDetail(height uint64) (*Result, error) {
 
 // ExplorerGroupsAfter is used in the blockchain browser to
 // query groups after the specified height
-func (api *GtasAPI) ExplorerGroupsAfter(height uint64) (*Result, error) {
+func (api *ExplorerAPI) ExplorerGroupsAfter(height uint64) (*Result, error) {
 	groups := core.GroupChainImpl.GetGroupsAfterHeight(height, common.MaxInt64)
 
 	ret := make([]map[string]interface{}, 0)
@@ -128,7 +131,7 @@ func explorerConvertGroup(g *types.Group) map[string]interface{} {
 }
 
 // ExplorerBlockBonus export bonus transaction by block height
-func (api *GtasAPI) ExplorerBlockBonus(height uint64) (*Result, error) {
+func (api *ExplorerAPI) ExplorerBlockBonus(height uint64) (*Result, error) {
 	chain := core.BlockChainImpl
 	b := chain.QueryBlockCeil(height)
 	if b == nil {
@@ -157,7 +160,7 @@ func (api *GtasAPI) ExplorerBlockBonus(height uint64) (*Result, error) {
 }
 
 // MonitorBlocks monitoring platform calls block sync
-func (api *GtasAPI) MonitorBlocks(begin, end uint64) (*Result, error) {
+func (api *ExplorerAPI) MonitorBlocks(begin, end uint64) (*Result, error) {
 	chain := core.BlockChainImpl
 	if begin > end {
 		end = begin
@@ -198,13 +201,13 @@ func (api *GtasAPI) MonitorBlocks(begin, end uint64) (*Result, error) {
 	return successResult(blocks)
 }
 
-func (api *GtasAPI) MonitorNodeInfo() (*Result, error) {
+func (api *ExplorerAPI) MonitorNodeInfo() (*Result, error) {
 	bh := core.BlockChainImpl.Height()
 	gh := core.GroupChainImpl.LastGroup().GroupHeight
 
 	ni := &NodeInfo{}
 
-	ret, _ := api.NodeInfo()
+	ret, _ := api.chain.NodeInfo()
 	if ret != nil && ret.IsSuccess() {
 		ni = ret.Data.(*NodeInfo)
 	}
@@ -221,7 +224,7 @@ func (api *GtasAPI) MonitorNodeInfo() (*Result, error) {
 	return successResult(ni)
 }
 
-func (api *GtasAPI) MonitorAllMiners() (*Result, error) {
+func (api *ExplorerAPI) MonitorAllMiners() (*Result, error) {
 	miners := mediator.Proc.GetAllMinerDOs()
 	totalStake := uint64(0)
 	maxStake := uint64(0)
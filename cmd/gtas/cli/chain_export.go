@@ -0,0 +1,116 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/taschain/taschain/common"
+	"github.com/taschain/taschain/consensus/groupsig"
+	"github.com/taschain/taschain/consensus/mediator"
+	"github.com/taschain/taschain/consensus/model"
+	"github.com/taschain/taschain/core"
+	"github.com/taschain/taschain/middleware"
+	"github.com/taschain/taschain/middleware/types"
+)
+
+// progressInterval is the minimum interval of printing the export and import progress
+const progressInterval = 5 * time.Second
+
+func newProgressPrinter(action string) core.ChainIOProgress {
+	begin := time.Now()
+	last := begin
+	return func(height uint64, blocks int, groups int) {
+		if time.Since(last) < progressInterval {
+			return
+		}
+		last = time.Now()
+		speed := float64(blocks) / time.Since(begin).Seconds()
+		fmt.Printf("%v %v blocks, %v groups, height %v, %.1f blocks/s\n", action, blocks, groups, height, speed)
+	}
+}
+
+// exportChain writes the blocks of the local chain to the file, the node must not be running on the same instance
+func (gtas *Gtas) exportChain(file string, from, to uint64, instanceIndex int) error {
+	instanceInit(instanceIndex)
+	types.InitMiddleware()
+	middleware.InitMiddleware()
+
+	err := core.InitCore(false, mediator.NewConsensusHelper(groupsig.ID{}), nil)
+	if err != nil {
+		return err
+	}
+	defer core.BlockChainImpl.Close()
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var last uint64
+	var blocks, groups int
+	printer := newProgressPrinter("exported")
+	err = core.BlockChainImpl.(*core.FullBlockChain).ExportChain(f, from, to, func(height uint64, b int, g int) {
+		last, blocks, groups = height, b, g
+		printer(height, b, g)
+	})
+	if err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	fmt.Printf("Exported %v blocks and %v groups up to height %v to %v\n", blocks, groups, last, file)
+	return nil
+}
+
+// importChain adds the blocks of the export file to the local chain. The consensus module is initialized to verify
+// the blocks and groups, but not started
+func (gtas *Gtas) importChain(file string, instanceIndex int) error {
+	instanceInit(instanceIndex)
+	types.InitMiddleware()
+	middleware.InitMiddleware()
+
+	minerInfo := model.NewSelfMinerDO(common.HexToAddress(common.GlobalConf.GetString(Section, "miner", "")))
+	err := core.InitCore(false, mediator.NewConsensusHelper(minerInfo.ID), nil)
+	if err != nil {
+		return err
+	}
+	defer core.BlockChainImpl.Close()
+	if !mediator.ConsensusInit(minerInfo, common.GlobalConf) {
+		return errors.New("consensus module error")
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var last uint64
+	var blocks, groups int
+	printer := newProgressPrinter("imported")
+	err = core.BlockChainImpl.(*core.FullBlockChain).ImportChain(f, func(height uint64, b int, g int) {
+		last, blocks, groups = height, b, g
+		printer(height, b, g)
+	})
+	fmt.Printf("Imported %v blocks and %v groups, height %v, local top %v\n", blocks, groups, last, core.BlockChainImpl.Height())
+	return err
+}
@@ -17,12 +17,14 @@ package cli
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 
+	"github.com/taschain/taschain/cmd/gtas/rpc"
 	"github.com/taschain/taschain/common"
 	"github.com/taschain/taschain/consensus/base"
 	"github.com/taschain/taschain/consensus/groupsig"
@@ -36,6 +38,9 @@ type RemoteChainOpImpl struct {
 	base string
 	aop  accountOp
 	show bool
+
+	ipcPath   string
+	ipcClient *rpc.Client
 }
 
 // InitRemoteChainOp connect node by ip and port
@@ -53,19 +58,44 @@ func (ca *RemoteChainOpImpl) Connect(ip string, port int) error {
 	if ip == "" {
 		return nil
 	}
+	ca.closeIPC()
 	ca.host = ip
 	ca.port = port
 	ca.base = fmt.Sprintf("http://%v:%v", ip, port)
 	return nil
 }
 
+// AttachIPC connect node by the ipc socket file path
+func (ca *RemoteChainOpImpl) AttachIPC(path string) error {
+	client, err := rpc.DialIPC(context.Background(), path)
+	if err != nil {
+		return err
+	}
+	ca.closeIPC()
+	ca.ipcPath = path
+	ca.ipcClient = client
+	return nil
+}
+
+func (ca *RemoteChainOpImpl) closeIPC() {
+	if ca.ipcClient != nil {
+		ca.ipcClient.Close()
+		ca.ipcClient = nil
+		ca.ipcPath = ""
+	}
+}
+
+// request calls the method with the full namespaced name, e.g. GTAS_balance or debug_traceBlock
 func (ca *RemoteChainOpImpl) request(method string, params ...interface{}) *Result {
+	if ca.ipcClient != nil {
+		return ca.requestIPC(method, params...)
+	}
 	if ca.base == "" {
 		return opError(ErrUnConnected)
 	}
 
 	param := RPCReqObj{
-		Method:  "GTAS_" + method,
+		Method:  method,
 		Params:  params[:],
 		ID:      1,
 		Jsonrpc: "2.0",
@@ -99,8 +129,22 @@ func (ca *RemoteChainOpImpl) request(method string, params ...interface{}) *Resu
 	return ret.Result
 }
 
+func (ca *RemoteChainOpImpl) requestIPC(method string, params ...interface{}) *Result {
+	if ca.show {
+		fmt.Println("Request:")
+		bs, _ := json.MarshalIndent(RPCReqObj{Method: method, Params: params, ID: 1, Jsonrpc: "2.0"}, "", "\t")
+		fmt.Println(string(bs))
+		fmt.Println("==================================================================================")
+	}
+	ret := &Result{}
+	if err := ca.ipcClient.Call(ret, method, params...); err != nil {
+		return opError(err)
+	}
+	return ret
+}
+
 func (ca *RemoteChainOpImpl) nonce(addr string) (uint64, error) {
-	ret := ca.request("nonce", addr)
+	ret := ca.request("GTAS_nonce", addr)
 	if !ret.IsSuccess() {
 		return 0, fmt.Errorf(ret.Message)
 	}
@@ -109,6 +153,9 @@ func (ca *RemoteChainOpImpl) nonce(addr string) (uint64, error) {
 
 // Endpoint returns current connected ip and port
 func (ca *RemoteChainOpImpl) Endpoint() string {
+	if ca.ipcClient != nil {
+		return ca.ipcPath
+	}
 	return fmt.Sprintf("%v:%v", ca.host, ca.port)
 }
 
@@ -119,6 +166,45 @@ func (ca *RemoteChainOpImpl) SendRaw(tx *txRawData) *Result {
 		return r
 	}
 	aci := r.Data.(*Account)
+
+	nonce, err := ca.nonce(aci.Address)
+	if err != nil {
+		return opError(err)
+	}
+	tx.Nonce = nonce + 1
+	return ca.signAndSend(aci, tx)
+}
+
+// CancelTx replaces the pending transaction of the current unlocked account with a zero-value self transfer
+// built by the connected node, the minimum required replacement gas price is used if zero given
+func (ca *RemoteChainOpImpl) CancelTx(hash string, gasprice uint64) *Result {
+	r := ca.aop.AccountInfo()
+	if !r.IsSuccess() {
+		return r
+	}
+	aci := r.Data.(*Account)
+
+	r = ca.request("GTAS_cancelTx", hash, gasprice)
+	if !r.IsSuccess() {
+		return r
+	}
+	bs, err := json.Marshal(r.Data)
+	if err != nil {
+		return opError(err)
+	}
+	tx := new(txRawData)
+	if err := json.Unmarshal(bs, tx); err != nil {
+		return opError(err)
+	}
+	if tx.Source != aci.Address {
+		return opError(fmt.Errorf("the transaction is not sent by the current unlocked account"))
+	}
+	tx.Source = ""
+	return ca.signAndSend(aci, tx)
+}
+
+// signAndSend signs the transaction with the given unlocked account and sends it to the connected node
+func (ca *RemoteChainOpImpl) signAndSend(aci *Account, tx *txRawData) *Result {
 	privateKey := common.HexToSecKey(aci.Sk)
 	pubkey := common.HexToPubKey(aci.Pk)
 	if privateKey.GetPubKey().Hex() != pubkey.Hex() {
@@ -129,13 +215,7 @@ func (ca *RemoteChainOpImpl) SendRaw(tx *txRawData) *Result {
 		return opError(fmt.Errorf("address error"))
 	}
 
-	nonce, err := ca.nonce(aci.Address)
-	if err != nil {
-		return opError(err)
-	}
 	tranx := txRawToTransaction(tx)
-	tranx.Nonce = nonce + 1
-	tx.Nonce = nonce + 1
 	tranx.Hash = tranx.GenHash()
 	sign := privateKey.Sign(tranx.Hash.Bytes())
 	tranx.Sign = sign.Bytes()
@@ -148,37 +228,37 @@ func (ca *RemoteChainOpImpl) SendRaw(tx *txRawData) *Result {
 
 	ca.aop.(*AccountManager).resetExpireTime(aci.Address)
 	// Signature is required here
-	return ca.request("tx", string(jsonByte))
+	return ca.request("GTAS_tx", string(jsonByte))
 }
 
 // Balance query Balance by address
 func (ca *RemoteChainOpImpl) Balance(addr string) *Result {
-	return ca.request("balance", addr)
+	return ca.request("GTAS_balance", addr)
 }
 
 // MinerInfo query miner info by address
 func (ca *RemoteChainOpImpl) MinerInfo(addr string) *Result {
-	return ca.request("minerInfo", addr)
+	return ca.request("GTAS_minerInfo", addr)
 }
 
 func (ca *RemoteChainOpImpl) BlockHeight() *Result {
-	return ca.request("blockHeight")
+	return ca.request("GTAS_blockHeight")
 }
 
 func (ca *RemoteChainOpImpl) GroupHeight() *Result {
-	return ca.request("groupHeight")
+	return ca.request("GTAS_groupHeight")
 }
 
 func (ca *RemoteChainOpImpl) TxInfo(hash string) *Result {
-	return ca.request("transDetail", hash)
+	return ca.request("GTAS_transDetail", hash)
 }
 
 func (ca *RemoteChainOpImpl) BlockByHash(hash string) *Result {
-	return ca.request("getBlockByHash", hash)
+	return ca.request("GTAS_getBlockByHash", hash)
 }
 
 func (ca *RemoteChainOpImpl) BlockByHeight(h uint64) *Result {
-	return ca.request("getBlockByHeight", h)
+	return ca.request("GTAS_getBlockByHeight", h)
 }
 
 // ApplyMiner apply miner(mtype is MinerTypeLight or MinerStatusNormal)
@@ -329,9 +409,28 @@ func (ca *RemoteChainOpImpl) MinerCancelStake(mtype int, addrStr string, cancelV
 }
 
 func (ca *RemoteChainOpImpl) ViewContract(addr string) *Result {
-	return ca.request("explorerAccount", addr)
+	return ca.request("GTAS_explorerAccount", addr)
+}
+
+func (ca *RemoteChainOpImpl) ContractStorage(addr string, prefix string, cursor string, limit int, block string) *Result {
+	if block == "" {
+		return ca.request("GTAS_contractStorage", addr, prefix, cursor, limit)
+	}
+	return ca.request("GTAS_contractStorage", addr, prefix, cursor, limit, block)
 }
 
 func (ca *RemoteChainOpImpl) TxReceipt(hash string) *Result {
-	return ca.request("txReceipt", hash)
+	return ca.request("GTAS_txReceipt", hash)
+}
+
+func (ca *RemoteChainOpImpl) TraceTransaction(hash string) *Result {
+	return ca.request("debug_traceTransaction", hash)
+}
+
+func (ca *RemoteChainOpImpl) TraceBlock(hash string) *Result {
+	return ca.request("debug_traceBlock", hash)
+}
+
+func (ca *RemoteChainOpImpl) VerifySummary(from, to uint64) *Result {
+	return ca.request("debug_verifySummary", from, to)
 }
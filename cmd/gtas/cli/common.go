@@ -21,8 +21,20 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strings"
 )
 
+// splitAndTrim splits input separated by a comma and trims excessive white space from the substrings
+func splitAndTrim(input string) []string {
+	result := make([]string, 0)
+	for _, r := range strings.Split(input, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
 // rpcPost provides a general rpc request method
 func rpcPost(addr string, port uint, method string, params ...interface{}) (*RPCResObj, error) {
 	obj := RPCReqObj{
@@ -99,6 +99,60 @@ func (c *unlockCmd) parse(args []string) bool {
 	return true
 }
 
+type exportAccountCmd struct {
+	baseCmd
+	addr string
+	path string
+}
+
+func genExportAccountCmd() *exportAccountCmd {
+	c := &exportAccountCmd{
+		baseCmd: *genbaseCmd("exportaccount", "export the encrypted key file of the account"),
+	}
+	c.fs.StringVar(&c.addr, "addr", "", "the account address")
+	c.fs.StringVar(&c.path, "path", "", "the path of the exported key file")
+	return c
+}
+
+func (c *exportAccountCmd) parse(args []string) bool {
+	if err := c.fs.Parse(args); err != nil {
+		fmt.Println(err.Error())
+		return false
+	}
+	if strings.TrimSpace(c.addr) == "" || strings.TrimSpace(c.path) == "" {
+		fmt.Println("please input the address and the path")
+		c.fs.PrintDefaults()
+		return false
+	}
+	return true
+}
+
+type importAccountCmd struct {
+	baseCmd
+	path string
+}
+
+func genImportAccountCmd() *importAccountCmd {
+	c := &importAccountCmd{
+		baseCmd: *genbaseCmd("importaccount", "import the encrypted key file into the keystore"),
+	}
+	c.fs.StringVar(&c.path, "path", "", "the path of the key file")
+	return c
+}
+
+func (c *importAccountCmd) parse(args []string) bool {
+	if err := c.fs.Parse(args); err != nil {
+		fmt.Println(err.Error())
+		return false
+	}
+	if strings.TrimSpace(c.path) == "" {
+		fmt.Println("please input the path")
+		c.fs.PrintDefaults()
+		return false
+	}
+	return true
+}
+
 type balanceCmd struct {
 	baseCmd
 	addr string
@@ -361,6 +415,43 @@ func (c *sendTxCmd) parse(args []string) bool {
 	return true
 }
 
+type cancelTxCmd struct {
+	baseCmd
+	hash        string
+	gasPriceStr string
+	gasPrice    uint64
+}
+
+func genCancelTxCmd() *cancelTxCmd {
+	c := &cancelTxCmd{
+		baseCmd: *genbaseCmd("canceltx", "cancel the pending transaction of the current unlocked account"),
+	}
+	c.fs.StringVar(&c.hash, "hash", "", "the hex hash of the pending transaction")
+	c.fs.StringVar(&c.gasPriceStr, "gasprice", "", "gas price of the replacement, the minimum required one if not set")
+	return c
+}
+
+func (c *cancelTxCmd) parse(args []string) bool {
+	if err := c.fs.Parse(args); err != nil {
+		fmt.Println(err.Error())
+		return false
+	}
+	if strings.TrimSpace(c.hash) == "" {
+		fmt.Println("please input the transaction hash")
+		c.fs.PrintDefaults()
+		return false
+	}
+	if c.gasPriceStr != "" {
+		gp, err := common.ParseCoin(c.gasPriceStr)
+		if err != nil {
+			fmt.Println(fmt.Sprintf("%v:%v, correct example: 100RA,100kRA,1mRA,1TAS", err, c.gasPriceStr))
+			return false
+		}
+		c.gasPrice = gp
+	}
+	return true
+}
+
 type minerApplyCmd struct {
 	gasBaseCmd
 	stake uint64
@@ -508,6 +599,119 @@ func (c *viewContractCmd) parse(args []string) bool {
 	return true
 }
 
+type contractStorageCmd struct {
+	baseCmd
+	addr   string
+	prefix string
+	cursor string
+	limit  int
+	block  string
+}
+
+func genContractStorageCmd() *contractStorageCmd {
+	c := &contractStorageCmd{
+		baseCmd: *genbaseCmd("contractstorage", "view a page of the contract storage"),
+	}
+	c.fs.StringVar(&c.addr, "addr", "", "address of the contract")
+	c.fs.StringVar(&c.prefix, "prefix", "", "prefix of the storage keys")
+	c.fs.StringVar(&c.cursor, "cursor", "", "the next cursor returned by the previous page, empty for the first page")
+	c.fs.IntVar(&c.limit, "limit", 100, "max number of the keys in the page")
+	c.fs.StringVar(&c.block, "block", "", "height or hash of the block, the top block if not given")
+	return c
+}
+
+func (c *contractStorageCmd) parse(args []string) bool {
+	if err := c.fs.Parse(args); err != nil {
+		fmt.Println(err.Error())
+		return false
+	}
+	if c.addr == "" {
+		fmt.Println("please input the contract address")
+		return false
+	}
+	return true
+}
+
+type traceTxCmd struct {
+	baseCmd
+	hash string
+}
+
+func genTraceTxCmd() *traceTxCmd {
+	c := &traceTxCmd{
+		baseCmd: *genbaseCmd("tracetx", "trace the execution of the transaction, requires the debug namespace"),
+	}
+	c.fs.StringVar(&c.hash, "hash", "", "the hex transaction hash")
+	return c
+}
+
+func (c *traceTxCmd) parse(args []string) bool {
+	if err := c.fs.Parse(args); err != nil {
+		fmt.Println(err.Error())
+		return false
+	}
+	if strings.TrimSpace(c.hash) == "" {
+		fmt.Println("please input the transaction hash")
+		c.fs.PrintDefaults()
+		return false
+	}
+	return true
+}
+
+type traceBlockCmd struct {
+	baseCmd
+	hash string
+}
+
+func genTraceBlockCmd() *traceBlockCmd {
+	c := &traceBlockCmd{
+		baseCmd: *genbaseCmd("traceblock", "trace the execution of the transactions in the block, requires the debug namespace"),
+	}
+	c.fs.StringVar(&c.hash, "hash", "", "the hex block hash")
+	return c
+}
+
+func (c *traceBlockCmd) parse(args []string) bool {
+	if err := c.fs.Parse(args); err != nil {
+		fmt.Println(err.Error())
+		return false
+	}
+	if strings.TrimSpace(c.hash) == "" {
+		fmt.Println("please input the block hash")
+		c.fs.PrintDefaults()
+		return false
+	}
+	return true
+}
+
+type verifySummaryCmd struct {
+	baseCmd
+	from uint64
+	to   uint64
+}
+
+func genVerifySummaryCmd() *verifySummaryCmd {
+	c := &verifySummaryCmd{
+		baseCmd: *genbaseCmd("verifysummary", "summarize the verify groups of the blocks, requires the debug namespace"),
+	}
+	c.fs.Uint64Var(&c.from, "from", 1, "the begin height")
+	c.fs.Uint64Var(&c.to, "to", 0, "the end height, capped at the top block")
+	return c
+}
+
+func (c *verifySummaryCmd) parse(args []string) bool {
+	if err := c.fs.Parse(args); err != nil {
+		fmt.Println(err.Error())
+		return false
+	}
+	if c.to < c.from {
+		fmt.Println("please input the end height not less than the begin height")
+		c.fs.PrintDefaults()
+		return false
+	}
+	return true
+}
+
 var cmdNewAccount = genNewAccountCmd()
 var cmdExit = genbaseCmd("exit", "quit  gtas")
 var cmdHelp = genbaseCmd("help", "show help info")
@@ -516,6 +720,9 @@ var cmdUnlock = genUnlockCmd()
 var cmdBalance = genBalanceCmd()
 var cmdAccountInfo = genbaseCmd("accountinfo", "get the info of the current unlocked account")
 var cmdDelAccount = genbaseCmd("delaccount", "delete the info of the current unlocked account")
+var cmdExportAccount = genExportAccountCmd()
+var cmdImportAccount = genImportAccountCmd()
+var cmdMigrate = genbaseCmd("migrate", "migrate the accounts in the legacy keystore sealed with the password to key files")
 var cmdMinerInfo = genMinerInfoCmd()
 var cmdConnect = genConnectCmd()
 var cmdBlockHeight = genbaseCmd("blockheight", "the current block height")
@@ -523,6 +730,7 @@ var cmdGroupHeight = genbaseCmd("groupheight", "the current group height")
 var cmdTx = genTxCmd()
 var cmdBlock = genBlockCmd()
 var cmdSendTx = genSendTxCmd()
+var cmdCancelTx = genCancelTxCmd()
 
 var cmdMinerApply = genMinerApplyCmd()
 var cmdMinerAbort = genMinerAbortCmd()
@@ -530,6 +738,10 @@ var cmdMinerRefund = genMinerRefundCmd()
 var cmdMinerStake = genMinerStakeCmd()
 var cmdMinerCancelStake = genMinerCancelStakeCmd()
 var cmdViewContract = genViewContractCmd()
+var cmdContractStorage = genContractStorageCmd()
+var cmdTraceTx = genTraceTxCmd()
+var cmdTraceBlock = genTraceBlockCmd()
+var cmdVerifySummary = genVerifySummaryCmd()
 
 var list = make([]*baseCmd, 0)
 
@@ -541,6 +753,9 @@ func init() {
 	list = append(list, &cmdBalance.baseCmd)
 	list = append(list, cmdAccountInfo)
 	list = append(list, cmdDelAccount)
+	list = append(list, &cmdExportAccount.baseCmd)
+	list = append(list, &cmdImportAccount.baseCmd)
+	list = append(list, cmdMigrate)
 	list = append(list, &cmdMinerInfo.baseCmd)
 	list = append(list, &cmdConnect.baseCmd)
 	list = append(list, cmdBlockHeight)
@@ -548,12 +763,17 @@ func init() {
 	list = append(list, &cmdTx.baseCmd)
 	list = append(list, &cmdBlock.baseCmd)
 	list = append(list, &cmdSendTx.baseCmd)
+	list = append(list, &cmdCancelTx.baseCmd)
 	list = append(list, &cmdMinerApply.baseCmd)
 	list = append(list, &cmdMinerAbort.baseCmd)
 	list = append(list, &cmdMinerRefund.baseCmd)
 	list = append(list, &cmdViewContract.baseCmd)
+	list = append(list, &cmdContractStorage.baseCmd)
 	list = append(list, &cmdMinerCancelStake.baseCmd)
 	list = append(list, &cmdMinerStake.baseCmd)
+	list = append(list, &cmdTraceTx.baseCmd)
+	list = append(list, &cmdTraceBlock.baseCmd)
+	list = append(list, &cmdVerifySummary.baseCmd)
 	list = append(list, cmdExit)
 }
 
@@ -566,14 +786,19 @@ func Usage() {
 	}
 }
 
-func ConsoleInit(keystore, host string, port int, show bool, rpcport int) error {
+func ConsoleInit(keystore, host string, port int, ipcPath string, show bool, rpcport int) error {
 	aop, err := initAccountManager(keystore, false)
 	if err != nil {
 		return err
 	}
-	chainop := InitRemoteChainOp(host, port, show, aop)
-	if chainop.base != "" {
-
+	var chainop *RemoteChainOpImpl
+	if ipcPath != "" {
+		chainop = InitRemoteChainOp("", port, show, aop)
+		if err := chainop.AttachIPC(ipcPath); err != nil {
+			return err
+		}
+	} else {
+		chainop = InitRemoteChainOp(host, port, show, aop)
 	}
 
 	if rpcport > 0 {
@@ -704,6 +929,34 @@ func loop(acm accountOp, chainOp chainOp) {
 			handleCmd(func() *Result {
 				return acm.DeleteAccount()
 			})
+		case cmdExportAccount.name:
+			cmd := genExportAccountCmd()
+			if cmd.parse(args) {
+				handleCmd(func() *Result {
+					return acm.ExportAccount(cmd.addr, cmd.path)
+				})
+			}
+		case cmdImportAccount.name:
+			cmd := genImportAccountCmd()
+			if cmd.parse(args) {
+				bs, err := gopass.GetPasswdPrompt("please input password of the key file: ", true, os.Stdin, os.Stdout)
+				if err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					break
+				}
+				handleCmd(func() *Result {
+					return acm.ImportAccount(cmd.path, string(bs))
+				})
+			}
+		case cmdMigrate.name:
+			bs, err := gopass.GetPasswdPrompt("please input password of the legacy accounts: ", true, os.Stdin, os.Stdout)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				break
+			}
+			handleCmd(func() *Result {
+				return acm.MigrateAccounts(string(bs))
+			})
 		case cmdConnect.name:
 			cmd := genConnectCmd()
 			if cmd.parse(args) {
@@ -759,6 +1012,13 @@ func loop(acm accountOp, chainOp chainOp) {
 					return chainOp.SendRaw(cmd.toTxRaw())
 				})
 			}
+		case cmdCancelTx.name:
+			cmd := genCancelTxCmd()
+			if cmd.parse(args) {
+				handleCmd(func() *Result {
+					return chainOp.CancelTx(cmd.hash, cmd.gasPrice)
+				})
+			}
 		case cmdMinerApply.name:
 			cmd := genMinerApplyCmd()
 			if cmd.parse(args) {
@@ -801,6 +1061,34 @@ func loop(acm accountOp, chainOp chainOp) {
 					return chainOp.ViewContract(cmd.addr)
 				})
 			}
+		case cmdContractStorage.name:
+			cmd := genContractStorageCmd()
+			if cmd.parse(args) {
+				handleCmd(func() *Result {
+					return chainOp.ContractStorage(cmd.addr, cmd.prefix, cmd.cursor, cmd.limit, cmd.block)
+				})
+			}
+		case cmdTraceTx.name:
+			cmd := genTraceTxCmd()
+			if cmd.parse(args) {
+				handleCmd(func() *Result {
+					return chainOp.TraceTransaction(cmd.hash)
+				})
+			}
+		case cmdTraceBlock.name:
+			cmd := genTraceBlockCmd()
+			if cmd.parse(args) {
+				handleCmd(func() *Result {
+					return chainOp.TraceBlock(cmd.hash)
+				})
+			}
+		case cmdVerifySummary.name:
+			cmd := genVerifySummaryCmd()
+			if cmd.parse(args) {
+				handleCmd(func() *Result {
+					return chainOp.VerifySummary(cmd.from, cmd.to)
+				})
+			}
 		default:
 			fmt.Printf("not supported command %v\n", cmdStr)
 			Usage()
@@ -18,7 +18,9 @@ package cli
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	"github.com/taschain/taschain/common"
 	"github.com/taschain/taschain/core"
@@ -34,6 +36,7 @@ import (
 	"runtime"
 	"runtime/debug"
 	"strconv"
+	"strings"
 
 	"github.com/taschain/taschain/consensus/groupsig"
 	"github.com/taschain/taschain/consensus/model"
@@ -62,27 +65,59 @@ const (
 	databaseKey = "database"
 	// ini configuration file statistics section
 	statisticsSection = "statistics"
+	// Default ipc socket file name in the data directory
+	defaultIPCFile = "gtas.ipc"
 )
 
 var walletManager wallets
 var lightMiner bool
 
+// rpcConfig is the configuration of the HTTP, websocket and IPC RPC services
+type rpcConfig struct {
+	modules     []string // namespaces exposed on the HTTP and websocket endpoints
+	corsDomains []string
+	vhosts      []string
+	auth        *rpcAuth // bearer token auth of the HTTP and websocket endpoints, disabled if nil
+
+	enableWS  bool
+	wsAddr    string
+	wsPort    uint
+	wsOrigins []string
+
+	ipcPath string // IPC endpoint is disabled if empty
+}
+
 type Gtas struct {
 	inited  bool
 	account Account
+	genesis *types.Genesis // Genesis spec given on the command line, nil if not given
 }
 
 // miner start miner node
-func (gtas *Gtas) miner(rpc, super, testMode bool, rpcAddr, natIP string, natPort uint16, seedIP string, seedID string, rpcPort uint, light bool, apply string, keystore string, enableLog bool, chainID uint16) {
+func (gtas *Gtas) miner(rpc, super, testMode bool, rpcAddr, natIP string, natPort uint16, seedIP string, seedID string, rpcPort uint, light bool, apply string, keystore string, password string, enableLog bool, chainID uint16, conf *rpcConfig) {
 	gtas.runtimeInit()
-	err := gtas.fullInit(super, testMode, natIP, natPort, seedIP, seedID, light, keystore, enableLog, chainID)
+	err := gtas.fullInit(super, testMode, natIP, natPort, seedIP, seedID, light, keystore, password, enableLog, chainID)
 	if err != nil {
 		fmt.Println(err.Error())
 		common.DefaultLogger.Error(err.Error())
 		return
 	}
 	if rpc {
-		err = StartRPC(rpcAddr, rpcPort)
+		err = StartRPC(rpcAddr, rpcPort, conf)
+		if err != nil {
+			common.DefaultLogger.Errorf(err.Error())
+			return
+		}
+	}
+	if conf.enableWS {
+		err = StartWS(conf.wsAddr, conf.wsPort, conf.wsOrigins, conf)
+		if err != nil {
+			common.DefaultLogger.Errorf(err.Error())
+			return
+		}
+	}
+	if conf.ipcPath != "" {
+		err = StartIPC(conf.ipcPath)
 		if err != nil {
 			common.DefaultLogger.Errorf(err.Error())
 			return
@@ -92,6 +127,7 @@ func (gtas *Gtas) miner(rpc, super, testMode bool, rpcAddr, natIP string, natPor
 
 	fmt.Println("Syncing block and group info from tas net.Waiting...")
 	core.InitGroupSyncer(core.GroupChainImpl, core.BlockChainImpl.(*core.FullBlockChain))
+	core.InitStateSyncer(core.BlockChainImpl.(*core.FullBlockChain))
 	core.InitBlockSyncer(core.BlockChainImpl.(*core.FullBlockChain))
 
 	var appFun applyFunc
@@ -158,6 +194,7 @@ func (gtas *Gtas) Run() {
 	showRequest := consoleCmd.Flag("show", "show the request json").Short('v').Bool()
 	remoteHost := consoleCmd.Flag("host", "the node host address to connect").Short('i').String()
 	remotePort := consoleCmd.Flag("port", "the node host port to connect").Short('p').Default("8101").Int()
+	remoteIPC := consoleCmd.Flag("ipc", "the ipc socket file path of the node to attach, host and port are ignored if set").String()
 	rpcPort := consoleCmd.Flag("rpcport", "gtas console will listen at the port for wallet service").Short('r').Default("0").Int()
 
 	// Version
@@ -171,6 +208,24 @@ func (gtas *Gtas) Run() {
 	enableLogSrv := mineCmd.Flag("monitor", "enable monitor").Default("false").Bool()
 	addrRPC := mineCmd.Flag("rpcaddr", "rpc host").Short('r').Default("0.0.0.0").IP()
 	portRPC := mineCmd.Flag("rpcport", "rpc port").Short('p').Default("8088").Uint()
+	rpcAPI := mineCmd.Flag("rpcapi", "namespaces exposed on the http and websocket rpc, comma separated, available: chain,tx,miner,explorer,wallet,debug").Default(strings.Join(defaultRPCModules, ",")).String()
+	rpcCors := mineCmd.Flag("rpccorsdomain", "domains from which to accept cross origin requests, comma separated").Default("*").String()
+	rpcVhosts := mineCmd.Flag("rpcvhosts", "virtual hostnames from which to accept requests, comma separated, accept all if empty").Default("").String()
+	rpcAuthSecret := mineCmd.Flag("rpcauthsecret", "static bearer token required by the protected namespaces").Default("").String()
+	rpcJWTSecret := mineCmd.Flag("rpcjwtsecret", "key verifying the HS256 json web token required by the protected namespaces").Default("").String()
+	rpcAuthAPI := mineCmd.Flag("rpcauthapi", "namespaces requiring the bearer token if auth enabled, comma separated").Default(strings.Join(defaultAuthModules, ",")).String()
+	ws := mineCmd.Flag("ws", "start websocket rpc server").Bool()
+	addrWS := mineCmd.Flag("wsaddr", "websocket rpc host").Default("0.0.0.0").IP()
+	portWS := mineCmd.Flag("wsport", "websocket rpc port").Default("8089").Uint()
+	wsOrigins := mineCmd.Flag("wsorigins", "origins from which to accept websocket requests, comma separated").Default("").String()
+	ipcDisable := mineCmd.Flag("ipcdisable", "disable the ipc rpc server").Bool()
+	ipcPath := mineCmd.Flag("ipcpath", "ipc socket file path, default is gtas.ipc in the data directory").Default("").String()
+	prune := mineCmd.Flag("prune", "only keep the recent block states instead of the state of every block, up to the retention plus the flush interval blocks are synced again after a crash").Bool()
+	stateRetention := mineCmd.Flag("stateretention", "number of recent block states kept in pruned mode, the config is used if 0").Default("0").Uint()
+	syncMode := mineCmd.Flag("syncmode", "block sync mode, full or fast. Fast mode downloads the state of a recent block instead of executing all the blocks, it only works on an empty chain").Default("").Enum("", core.SyncModeFull, core.SyncModeFast)
+	addrIndex := mineCmd.Flag("addrindex", "index the transactions of each address for GTAS_getAddressTxs, only the blocks added afterwards are indexed").Bool()
+	minerGenesis := mineCmd.Flag("genesis", "genesis spec json or yaml file, refuse to start if it differs from the one the chain initialized with").Default("").String()
+	passwordFile := mineCmd.Flag("passwordfile", "file containing the password of the miner account, the default password is used if not set").Default("").String()
 	super := mineCmd.Flag("super", "start super node").Bool()
 	instanceIndex := mineCmd.Flag("instance", "instance index").Short('i').Default("0").Int()
 	apply := mineCmd.Flag("apply", "apply heavy or light miner").String()
@@ -193,6 +248,23 @@ func (gtas *Gtas) Run() {
 
 	clearCmd := app.Command("clear", "Clear the data of blockchain")
 
+	// Init the chain data with the genesis spec
+	initCmd := app.Command("init", "write the genesis block of the genesis spec")
+	initGenesis := initCmd.Flag("genesis", "genesis spec json or yaml file").Required().String()
+	initInstance := initCmd.Flag("instance", "instance index").Short('i').Default("0").Int()
+
+	// Export the blocks to a file
+	exportCmd := app.Command("export", "export the blocks and groups to a compressed file")
+	exportFile := exportCmd.Arg("file", "export file").Required().String()
+	exportFrom := exportCmd.Arg("from", "first block height").Default("1").Uint64()
+	exportTo := exportCmd.Arg("to", "last block height, 0 means the top").Default("0").Uint64()
+	exportInstance := exportCmd.Flag("instance", "instance index").Short('i').Default("0").Int()
+
+	// Import the blocks from a file
+	importCmd := app.Command("import", "import and verify the blocks and groups of an export file")
+	importFile := importCmd.Arg("file", "export file").Required().String()
+	importInstance := importCmd.Flag("instance", "instance index").Short('i').Default("0").Int()
+
 	command, err := app.Parse(os.Args[1:])
 	if err != nil {
 		kingpin.Fatalf("%s, try --help", err)
@@ -205,23 +277,52 @@ func (gtas *Gtas) Run() {
 		fmt.Println("Gtas Version:", common.GtasVersion)
 		os.Exit(0)
 	case consoleCmd.FullCommand():
-		err := ConsoleInit(*keystore, *remoteHost, *remotePort, *showRequest, *rpcPort)
+		err := ConsoleInit(*keystore, *remoteHost, *remotePort, *remoteIPC, *showRequest, *rpcPort)
+		if err != nil {
+			fmt.Println(err.Error())
+		}
+	case initCmd.FullCommand():
+		err := gtas.initGenesis(*initGenesis, *initInstance)
 		if err != nil {
 			fmt.Println(err.Error())
+			os.Exit(1)
 		}
+		os.Exit(0)
+	case exportCmd.FullCommand():
+		err := gtas.exportChain(*exportFile, *exportFrom, *exportTo, *exportInstance)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+		os.Exit(0)
+	case importCmd.FullCommand():
+		err := gtas.importChain(*importFile, *importInstance)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+		os.Exit(0)
 	case mineCmd.FullCommand():
-		common.InstanceIndex = *instanceIndex
 		go func() {
 			http.ListenAndServe(fmt.Sprintf(":%d", *pprofPort), nil)
 			runtime.SetBlockProfileRate(1)
 			runtime.SetMutexProfileFraction(1)
 		}()
 
-		common.GlobalConf.SetInt(instanceSection, indexKey, *instanceIndex)
-		databaseValue := "d" + strconv.Itoa(*instanceIndex)
-		common.GlobalConf.SetString(chainSection, databaseKey, databaseValue)
+		databaseValue := instanceInit(*instanceIndex)
 		common.GlobalConf.SetBool(statisticsSection, "enable", *statisticsEnable)
-		common.DefaultLogger = taslog.GetLoggerByIndex(taslog.DefaultConfig, common.GlobalConf.GetString("instance", "index", ""))
+		if *prune {
+			common.GlobalConf.SetBool(chainSection, "state_pruning", true)
+		}
+		if *stateRetention > 0 {
+			common.GlobalConf.SetInt(chainSection, "state_retention", int(*stateRetention))
+		}
+		if *syncMode != "" {
+			common.GlobalConf.SetString(chainSection, "sync_mode", *syncMode)
+		}
+		if *addrIndex {
+			common.GlobalConf.SetBool(chainSection, "address_index", true)
+		}
 		BonusLogger = taslog.GetLoggerByIndex(taslog.BonusStatConfig, common.GlobalConf.GetString("instance", "index", ""))
 		types.InitMiddleware()
 
@@ -230,7 +331,39 @@ func (gtas *Gtas) Run() {
 		}
 		lightMiner = *light
 		// Light node and heavy node
-		gtas.miner(*rpc, *super, *testMode, addrRPC.String(), *nat, *natPort, *seedIP, *seedID, *portRPC, *light, *apply, *keystore, *enableLogSrv, *chainID)
+		rpcConf := &rpcConfig{
+			modules:     splitAndTrim(*rpcAPI),
+			corsDomains: splitAndTrim(*rpcCors),
+			vhosts:      splitAndTrim(*rpcVhosts),
+			auth:        newRPCAuth(*rpcAuthSecret, *rpcJWTSecret, splitAndTrim(*rpcAuthAPI)),
+			enableWS:    *ws,
+			wsAddr:      addrWS.String(),
+			wsPort:      *portWS,
+			wsOrigins:   splitAndTrim(*wsOrigins),
+		}
+		if !*ipcDisable {
+			rpcConf.ipcPath = *ipcPath
+			if rpcConf.ipcPath == "" {
+				rpcConf.ipcPath = filepath.Join(databaseValue, defaultIPCFile)
+			}
+		}
+		if *minerGenesis != "" {
+			gtas.genesis, err = types.LoadGenesis(*minerGenesis)
+			if err != nil {
+				fmt.Println(err.Error())
+				return
+			}
+		}
+		password := DefaultPassword
+		if *passwordFile != "" {
+			bs, err := ioutil.ReadFile(*passwordFile)
+			if err != nil {
+				fmt.Println("read password file fail:", err.Error())
+				return
+			}
+			password = strings.TrimRight(string(bs), "\r\n")
+		}
+		gtas.miner(*rpc, *super, *testMode, addrRPC.String(), *nat, *natPort, *seedIP, *seedID, *portRPC, *light, *apply, *keystore, password, *enableLogSrv, *chainID, rpcConf)
 	case clearCmd.FullCommand():
 		err := ClearBlock(*light)
 		if err != nil {
@@ -242,9 +375,41 @@ func (gtas *Gtas) Run() {
 	<-quitChan
 }
 
+// instanceInit sets up the config and the default logger of the instance, returns its data directory
+func instanceInit(instanceIndex int) string {
+	common.InstanceIndex = instanceIndex
+	common.GlobalConf.SetInt(instanceSection, indexKey, instanceIndex)
+	databaseValue := "d" + strconv.Itoa(instanceIndex)
+	common.GlobalConf.SetString(chainSection, databaseKey, databaseValue)
+	common.DefaultLogger = taslog.GetLoggerByIndex(taslog.DefaultConfig, common.GlobalConf.GetString("instance", "index", ""))
+	return databaseValue
+}
+
+// initGenesis writes the genesis block of the spec into the chain data of the instance.
+// It fails if the chain has been initialized with a different spec
+func (gtas *Gtas) initGenesis(genesisFile string, instanceIndex int) error {
+	genesis, err := types.LoadGenesis(genesisFile)
+	if err != nil {
+		return err
+	}
+	instanceInit(instanceIndex)
+	types.InitMiddleware()
+	middleware.InitMiddleware()
+
+	err = core.InitCore(false, mediator.NewConsensusHelper(groupsig.ID{}), genesis)
+	if err != nil {
+		return err
+	}
+	defer core.BlockChainImpl.Close()
+
+	genesisBlock := core.BlockChainImpl.QueryBlockByHeight(0)
+	fmt.Printf("Genesis block written, hash %v\n", genesisBlock.Header.Hash.Hex())
+	return nil
+}
+
 // ClearBlock delete local blockchain data
 func ClearBlock(light bool) error {
-	err := core.InitCore(light, mediator.NewConsensusHelper(groupsig.ID{}))
+	err := core.InitCore(light, mediator.NewConsensusHelper(groupsig.ID{}), nil)
 	if err != nil {
 		return err
 	}
@@ -256,7 +421,7 @@ func (gtas *Gtas) simpleInit(configPath string) {
 	walletManager = newWallets()
 }
 
-func (gtas *Gtas) checkAddress(keystore, address string) error {
+func (gtas *Gtas) checkAddress(keystore, address, password string) error {
 	aop, err := initAccountManager(keystore, true)
 	if err != nil {
 		return err
@@ -264,34 +429,50 @@ func (gtas *Gtas) checkAddress(keystore, address string) error {
 	defer aop.Close()
 
 	acm := aop.(*AccountManager)
+	// Migrate the legacy accounts sealed with the miner password, the others need the migrate command of the console
+	migrated, failed := acm.migrateAll(password)
+	if len(migrated) > 0 {
+		common.DefaultLogger.Infof("%v legacy accounts migrated to key files: %v", len(migrated), migrated)
+	}
+	for addr, err := range failed {
+		fmt.Printf("legacy account %v not migrated: %v\n", addr, err)
+		common.DefaultLogger.Warnf("legacy account %v not migrated: %v", addr, err)
+	}
+	if len(failed) > 0 {
+		fmt.Println("run the migrate command of the console with the password of each account left to migrate it")
+	}
+
 	if address != "" {
-		aci, err := acm.getAccountInfo(address)
+		acc, err := acm.loadAccount(address, password)
 		if err != nil {
 			return fmt.Errorf("cannot get miner, err:%v", err.Error())
 		}
-		if aci.Miner == nil {
+		if acc.Miner == nil {
 			return fmt.Errorf("the address is not a miner account: %v", address)
 		}
-		gtas.account = aci.Account
+		gtas.account = *acc
 		return nil
 
 	}
-	aci := acm.getFirstMinerAccount()
-	if aci != nil {
-		gtas.account = *aci
+	acc, err := acm.getFirstMinerAccount(password)
+	if err != nil {
+		return fmt.Errorf("cannot get miner, err:%v", err.Error())
+	}
+	if acc != nil {
+		gtas.account = *acc
 		return nil
 	}
 	return fmt.Errorf("please create a miner account first")
 }
 
-func (gtas *Gtas) fullInit(isSuper, testMode bool, natIP string, natPort uint16, seedIP string, seedID string, light bool, keystore string, enableLog bool, chainID uint16) error {
+func (gtas *Gtas) fullInit(isSuper, testMode bool, natIP string, natPort uint16, seedIP string, seedID string, light bool, keystore string, password string, enableLog bool, chainID uint16) error {
 	var err error
 
 	// Initialization middleware
 	middleware.InitMiddleware()
 
 	addressConfig := common.GlobalConf.GetString(Section, "miner", "")
-	err = gtas.checkAddress(keystore, addressConfig)
+	err = gtas.checkAddress(keystore, addressConfig, password)
 	if err != nil {
 		return err
 	}
@@ -301,10 +482,16 @@ func (gtas *Gtas) fullInit(isSuper, testMode bool, natIP string, natPort uint16,
 
 	minerInfo := model.NewSelfMinerDO(common.HexToAddress(gtas.account.Address))
 
-	err = core.InitCore(light, mediator.NewConsensusHelper(minerInfo.ID))
+	err = core.InitCore(light, mediator.NewConsensusHelper(minerInfo.ID), gtas.genesis)
 	if err != nil {
 		return err
 	}
+	if genesisChainID := core.BlockChainImpl.Genesis().ChainID; genesisChainID != 0 {
+		if chainID != 0 && chainID != genesisChainID {
+			return fmt.Errorf("chain id %v differs from %v of the genesis", chainID, genesisChainID)
+		}
+		chainID = genesisChainID
+	}
 	id := minerInfo.ID.GetHexString()
 
 	netCfg := network.NetworkConfig{IsSuper: isSuper,
@@ -414,7 +601,7 @@ func (gtas *Gtas) autoApplyMiner(mtype int) {
 	}
 
 	nonce := core.BlockChainImpl.GetNonce(miner.ID.ToAddress()) + 1
-	ret, err := GtasAPIImpl.TxUnSafe(gtas.account.Sk, "", 0, 20000, 100, nonce, types.TransactionTypeMinerApply, common.ToHex(data))
+	ret, err := (&WalletAPI{}).TxUnSafe(gtas.account.Sk, "", 0, 20000, 100, nonce, types.TransactionTypeMinerApply, common.ToHex(data))
 	common.DefaultLogger.Debugf("apply result", ret, err)
 
 }
@@ -0,0 +1,186 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cli
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	keyFileVersion = 1
+	keyFileSuffix  = ".json"
+
+	keyCipher   = "aes-256-gcm"
+	keyKDF      = "scrypt"
+	scryptR     = 8
+	scryptDKLen = 32
+)
+
+// Cost parameters of scrypt used on sealing new keys, the parameters recorded in the key file are used on opening
+var (
+	scryptN = 1 << 18
+	scryptP = 1
+)
+
+// keyFile is the JSON key file of an account. The secrets of the account are sealed with AES-GCM
+// by the key derived from the password with scrypt, and the address is authenticated as the additional data
+type keyFile struct {
+	Address string     `json:"address"`
+	Miner   bool       `json:"miner"`
+	Crypto  cryptoJSON `json:"crypto"`
+	Version int        `json:"version"`
+}
+
+type cryptoJSON struct {
+	Cipher     string       `json:"cipher"`
+	CipherText string       `json:"ciphertext"`
+	Nonce      string       `json:"nonce"`
+	KDF        string       `json:"kdf"`
+	KDFParams  scryptParams `json:"kdfparams"`
+}
+
+type scryptParams struct {
+	N     int    `json:"n"`
+	R     int    `json:"r"`
+	P     int    `json:"p"`
+	DKLen int    `json:"dklen"`
+	Salt  string `json:"salt"`
+}
+
+func newKeyCipher(password string, params scryptParams) (cipher.AEAD, error) {
+	salt, err := hex.DecodeString(params.Salt)
+	if err != nil {
+		return nil, err
+	}
+	key, err := scrypt.Key([]byte(password), salt, params.N, params.R, params.P, params.DKLen)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealAccount encrypts the account with the password into a key file
+func sealAccount(account *Account, password string) (*keyFile, error) {
+	plain, err := json.Marshal(account)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	params := scryptParams{N: scryptN, R: scryptR, P: scryptP, DKLen: scryptDKLen, Salt: hex.EncodeToString(salt)}
+	aead, err := newKeyCipher(password, params)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return &keyFile{
+		Address: account.Address,
+		Miner:   account.Miner != nil,
+		Crypto: cryptoJSON{
+			Cipher:     keyCipher,
+			CipherText: hex.EncodeToString(aead.Seal(nil, nonce, plain, []byte(account.Address))),
+			Nonce:      hex.EncodeToString(nonce),
+			KDF:        keyKDF,
+			KDFParams:  params,
+		},
+		Version: keyFileVersion,
+	}, nil
+}
+
+// open decrypts the account with the password, ErrPassword returned if the password is wrong
+func (kf *keyFile) open(password string) (*Account, error) {
+	if kf.Version != keyFileVersion || kf.Crypto.Cipher != keyCipher || kf.Crypto.KDF != keyKDF {
+		return nil, fmt.Errorf("unsupported key file: version %v, cipher %v, kdf %v", kf.Version, kf.Crypto.Cipher, kf.Crypto.KDF)
+	}
+	aead, err := newKeyCipher(password, kf.Crypto.KDFParams)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hex.DecodeString(kf.Crypto.Nonce)
+	if err != nil || len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce in key file")
+	}
+	cipherText, err := hex.DecodeString(kf.Crypto.CipherText)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ciphertext in key file")
+	}
+	plain, err := aead.Open(nil, nonce, cipherText, []byte(kf.Address))
+	if err != nil {
+		return nil, ErrPassword
+	}
+	account := new(Account)
+	if err := json.Unmarshal(plain, account); err != nil {
+		return nil, err
+	}
+	if account.Address != kf.Address {
+		return nil, fmt.Errorf("address mismatch in key file")
+	}
+	return account, nil
+}
+
+func readKeyFile(path string) (*keyFile, error) {
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	kf := new(keyFile)
+	if err := json.Unmarshal(bs, kf); err != nil {
+		return nil, fmt.Errorf("invalid key file %v: %v", path, err)
+	}
+	return kf, nil
+}
+
+// writeKeyFile writes the key file readable only by the owner, the file is replaced atomically
+func writeKeyFile(path string, kf *keyFile) error {
+	bs, err := json.MarshalIndent(kf, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(bs); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	f.Close()
+	return os.Rename(f.Name(), path)
+}
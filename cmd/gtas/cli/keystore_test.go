@@ -0,0 +1,155 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cli
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/taschain/taschain/common"
+	"github.com/taschain/taschain/storage/tasdb"
+)
+
+func init() {
+	// Light scrypt parameters to make the tests fast
+	scryptN = 1 << 10
+}
+
+func TestKeyFileSealOpen(t *testing.T) {
+	account := &Account{Address: "0x01", Pk: "0x02", Sk: "0x03", Miner: &MinerRaw{BSk: "0x04", VrfSk: "0x05"}}
+	kf, err := sealAccount(account, "pass")
+	if err != nil {
+		t.Fatalf("seal account error:%v", err)
+	}
+	if !kf.Miner || kf.Address != account.Address {
+		t.Fatalf("key file header error:%+v", kf)
+	}
+	if _, err := kf.open("wrong"); err != ErrPassword {
+		t.Fatalf("open with wrong password should fail with ErrPassword, got %v", err)
+	}
+	opened, err := kf.open("pass")
+	if err != nil {
+		t.Fatalf("open key file error:%v", err)
+	}
+	if opened.Sk != account.Sk || opened.Miner.BSk != account.Miner.BSk || opened.Miner.VrfSk != account.Miner.VrfSk {
+		t.Fatalf("opened account mismatch:%+v", opened)
+	}
+
+	// The address is authenticated, tampering it fails the opening
+	kf.Address = "0x06"
+	if _, err := kf.open("pass"); err == nil {
+		t.Fatalf("open tampered key file should fail")
+	}
+}
+
+func TestLegacyAccountMigration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keystore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Write an account in the format of the legacy LevelDB keystore
+	db, err := tasdb.NewLDBDatabase(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy := &legacyAccount{Account: Account{Address: "0xabc", Sk: "0x01"}, Password: passwordSha("pass")}
+	bs, _ := json.Marshal(legacy)
+	pk := encryptPrivateKey.GetPubKey()
+	ct, err := common.Encrypt(rand.Reader, &pk, bs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Put([]byte(legacy.Address), ct)
+	db.Close()
+
+	am, err := newAccountOp(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer am.Close()
+	if addrs := am.addresses(); len(addrs) != 1 || addrs[0] != legacy.Address {
+		t.Fatalf("legacy account should be listed, got %v", addrs)
+	}
+	if ret := am.UnLock(legacy.Address, "wrong"); ret.IsSuccess() {
+		t.Fatalf("unlock with wrong password should fail")
+	}
+	if ret := am.UnLock(legacy.Address, "pass"); !ret.IsSuccess() {
+		t.Fatalf("unlock legacy account error:%v", ret.Message)
+	}
+
+	// Migrated to key file, and removed from the legacy keystore
+	kf, err := readKeyFile(filepath.Join(dir, legacy.Address+keyFileSuffix))
+	if err != nil {
+		t.Fatalf("key file not written:%v", err)
+	}
+	if acc, err := kf.open("pass"); err != nil || acc.Sk != legacy.Sk {
+		t.Fatalf("open migrated key file error:%v", err)
+	}
+	if _, err := am.loadLegacyAccount(legacy.Address); err == nil {
+		t.Fatalf("legacy account should be removed after migration")
+	}
+	if addrs := am.addresses(); len(addrs) != 1 {
+		t.Fatalf("account should be listed once, got %v", addrs)
+	}
+}
+
+func TestMigrateAllLegacyAccounts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keystore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := tasdb.NewLDBDatabase(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk := encryptPrivateKey.GetPubKey()
+	for addr, password := range map[string]string{"0xabc": "pass", "0xdef": "other"} {
+		bs, _ := json.Marshal(&legacyAccount{Account: Account{Address: addr, Sk: "0x01"}, Password: passwordSha(password)})
+		ct, err := common.Encrypt(rand.Reader, &pk, bs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		db.Put([]byte(addr), ct)
+	}
+	db.Close()
+
+	am, err := newAccountOp(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer am.Close()
+	migrated, failed := am.migrateAll("pass")
+	if len(migrated) != 1 || migrated[0] != "0xabc" {
+		t.Fatalf("expect 0xabc migrated, got %v", migrated)
+	}
+	if len(failed) != 1 || failed["0xdef"] != ErrPassword {
+		t.Fatalf("expect 0xdef failed with password error, got %v", failed)
+	}
+	if !fileExists(filepath.Join(dir, "0xabc"+keyFileSuffix)) {
+		t.Fatalf("key file of the migrated account not written")
+	}
+	if _, err := am.loadLegacyAccount("0xdef"); err != nil {
+		t.Fatalf("account not migrated should stay in the legacy keystore: %v", err)
+	}
+}
@@ -26,6 +26,8 @@ var (
 	ErrPassword    = fmt.Errorf("password error")
 	ErrUnlocked    = fmt.Errorf("please unlock the account first")
 	ErrUnConnected = fmt.Errorf("please connect to one node first")
+
+	ErrAccountNotExist = fmt.Errorf("account not exist")
 )
 
 type txRawData struct {
@@ -38,6 +40,7 @@ type txRawData struct {
 	Data      string `json:"data"`
 	Sign      string `json:"sign"`
 	ExtraData string `json:"extra_data"`
+	Source    string `json:"source,omitempty"` // Only used by the unsigned transactions of simulation and cancellation
 }
 
 func opError(err error) *Result {
@@ -99,6 +102,13 @@ type accountOp interface {
 
 	DeleteAccount() *Result
 
+	ExportAccount(addr string, path string) *Result
+
+	ImportAccount(path string, password string) *Result
+
+	// MigrateAccounts migrates all the legacy accounts sealed with the password to key files
+	MigrateAccounts(password string) *Result
+
 	Close()
 }
 
@@ -109,6 +119,8 @@ type chainOp interface {
 	Endpoint() string
 	// SendRaw send transaction to connected node
 	SendRaw(tx *txRawData) *Result
+	// CancelTx replaces the pending transaction of the current unlocked account with a zero-value self transfer
+	CancelTx(hash string, gasprice uint64) *Result
 	// Balance query Balance by address
 	Balance(addr string) *Result
 	// MinerInfo query miner info by address
@@ -136,5 +148,15 @@ type chainOp interface {
 
 	ViewContract(addr string) *Result
 
+	// ContractStorage query a page of the contract storage, the block is empty for the top block
+	ContractStorage(addr string, prefix string, cursor string, limit int, block string) *Result
+
 	TxReceipt(hash string) *Result
+
+	// TraceTransaction re-executes the transaction and returns its call trace, the debug namespace must be enabled
+	TraceTransaction(hash string) *Result
+	// TraceBlock re-executes the transactions of the block and returns their call traces
+	TraceBlock(hash string) *Result
+	// VerifySummary summarizes the verify groups of the blocks in the height range
+	VerifySummary(from, to uint64) *Result
 }
@@ -26,12 +26,9 @@ import (
 	"github.com/taschain/taschain/common"
 )
 
-// startHTTP initializes and starts the HTTP RPC endpoint.
-func startHTTP(endpoint string, apis []rpc.API, modules []string, cors []string, vhosts []string) error {
-	// Short circuit if the HTTP endpoint isn't being exposed
-	if endpoint == "" {
-		return nil
-	}
+// newRPCHandler creates the rpc server with the APIs allowed by the modules whitelist registered, the public
+// APIs are allowed if the whitelist is empty. Calls to the protected namespaces are checked by the authenticator if any
+func newRPCHandler(apis []rpc.API, modules []string, auth *rpcAuth) (*rpc.Server, error) {
 	// Generate the whitelist based on the allowed modules
 	whitelist := make(map[string]bool)
 	for _, module := range modules {
@@ -42,35 +39,183 @@ func startHTTP(endpoint string, apis []rpc.API, modules []string, cors []string,
 	for _, api := range apis {
 		if whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
 			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
-				return err
+				return nil, err
 			}
 		}
 	}
+	if auth != nil {
+		handler.SetAuthenticator(auth.authenticator, auth.namespaces)
+	}
+	return handler, nil
+}
+
+// startHTTP initializes and starts the HTTP RPC endpoint.
+func startHTTP(endpoint string, apis []rpc.API, modules []string, cors []string, vhosts []string, auth *rpcAuth) error {
+	// Short circuit if the HTTP endpoint isn't being exposed
+	if endpoint == "" {
+		return nil
+	}
+	handler, err := newRPCHandler(apis, modules, auth)
+	if err != nil {
+		return err
+	}
 	// All APIs registered, start the HTTP listener
-	var (
-		listener net.Listener
-		err      error
-	)
-	if listener, err = net.Listen("tcp", endpoint); err != nil {
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
 		return err
 	}
 	go rpc.NewHTTPServer(cors, vhosts, handler).Serve(listener)
 	return nil
 }
 
-var GtasAPIImpl *GtasAPI
+// startWS initializes and starts the websocket RPC endpoint.
+func startWS(endpoint string, apis []rpc.API, modules []string, wsOrigins []string, auth *rpcAuth) error {
+	// Short circuit if the WS endpoint isn't being exposed
+	if endpoint == "" {
+		return nil
+	}
+	handler, err := newRPCHandler(apis, modules, auth)
+	if err != nil {
+		return err
+	}
+	// All APIs registered, start the websocket listener
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return err
+	}
+	go rpc.NewWSServer(wsOrigins, handler).Serve(listener)
+	return nil
+}
+
+// startIPC initializes and starts the IPC RPC endpoint, all the given APIs are exposed.
+func startIPC(endpoint string, apis []rpc.API) error {
+	// Short circuit if the IPC endpoint isn't being exposed
+	if endpoint == "" {
+		return nil
+	}
+	// Register all the APIs exposed by the services
+	handler := rpc.NewServer()
+	for _, api := range apis {
+		if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
+			return err
+		}
+	}
+	// All APIs registered, start the IPC listener
+	listener, err := rpc.CreateIPCListener(endpoint)
+	if err != nil {
+		return err
+	}
+	go handler.ServeListener(listener)
+	return nil
+}
+
+// legacyNamespace is the namespace under which all the methods were exposed before the api split
+const legacyNamespace = "GTAS"
+
+// defaultRPCModules are the namespaces exposed on the HTTP and websocket endpoints by default,
+// the sensitive wallet and debug namespaces must be enabled explicitly
+var defaultRPCModules = []string{"chain", "tx", "miner", "explorer"}
+
+// defaultAuthModules are the namespaces requiring the bearer token if the auth is enabled
+var defaultAuthModules = []string{"wallet", "debug"}
+
+// rpcAuth is the bearer token auth setting of the HTTP and websocket endpoints
+type rpcAuth struct {
+	authenticator rpc.Authenticator
+	namespaces    []string
+}
+
+// newRPCAuth creates the auth setting from the static secret and the jwt key, nil returned if both are empty
+func newRPCAuth(secret string, jwtKey string, namespaces []string) *rpcAuth {
+	auths := make([]rpc.Authenticator, 0)
+	if secret != "" {
+		auths = append(auths, rpc.NewStaticAuthenticator(secret))
+	}
+	if jwtKey != "" {
+		auths = append(auths, rpc.NewJWTAuthenticator([]byte(jwtKey)))
+	}
+	if len(auths) == 0 {
+		return nil
+	}
+	return &rpcAuth{
+		authenticator: rpc.NewAnyAuthenticator(auths...),
+		namespaces:    namespaces,
+	}
+}
+
+func (auth *rpcAuth) protects(namespace string) bool {
+	if auth == nil {
+		return false
+	}
+	for _, ns := range auth.namespaces {
+		if ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+func gtasAPIs() []rpc.API {
+	chain := &ChainAPI{}
+	return []rpc.API{
+		{Namespace: "chain", Version: "1", Service: chain, Public: true},
+		{Namespace: "tx", Version: "1", Service: &TxAPI{}, Public: true},
+		{Namespace: "miner", Version: "1", Service: &MinerAPI{}, Public: true},
+		{Namespace: "explorer", Version: "1", Service: &ExplorerAPI{chain: chain}, Public: true},
+		{Namespace: "wallet", Version: "1", Service: &WalletAPI{}, Public: false},
+		{Namespace: "debug", Version: "1", Service: &DebugAPI{}, Public: false},
+	}
+}
+
+// withLegacyAPIs appends the enabled public APIs once more under the legacy GTAS namespace, so that the existing
+// clients keep working. The protected namespaces are left out because the auth is checked by namespace
+func withLegacyAPIs(apis []rpc.API, modules []string, auth *rpcAuth) ([]rpc.API, []string) {
+	enabled := make(map[string]bool)
+	for _, module := range modules {
+		enabled[module] = true
+	}
+	result := append([]rpc.API{}, apis...)
+	for _, api := range apis {
+		if !api.Public || auth.protects(api.Namespace) || (len(enabled) > 0 && !enabled[api.Namespace]) {
+			continue
+		}
+		result = append(result, rpc.API{Namespace: legacyNamespace, Version: api.Version, Service: api.Service, Public: true})
+	}
+	if len(modules) > 0 {
+		modules = append(append([]string{}, modules...), legacyNamespace)
+	}
+	return result, modules
+}
+
+// StartIPC starts the IPC RPC service on the given socket file, which exposes all the namespaces including debug
+func StartIPC(endpoint string) error {
+	apis, _ := withLegacyAPIs(gtasAPIs(), nil, nil)
+	if err := startIPC(endpoint, apis); err != nil {
+		return err
+	}
+	common.DefaultLogger.Infof("IPC serving on %s\n", endpoint)
+	return nil
+}
+
+// StartWS starts the websocket RPC service, which supports subscriptions besides the methods of the enabled modules
+func StartWS(host string, port uint, origins []string, conf *rpcConfig) error {
+	endpoint := fmt.Sprintf("%s:%d", host, port)
+	apis, modules := withLegacyAPIs(gtasAPIs(), conf.modules, conf.auth)
+	if err := startWS(endpoint, apis, modules, origins, conf.auth); err != nil {
+		return err
+	}
+	common.DefaultLogger.Infof("WebSocket RPC serving on ws://%s\n", endpoint)
+	return nil
+}
 
 // StartRPC RPC function
-func StartRPC(host string, port uint) error {
+func StartRPC(host string, port uint, conf *rpcConfig) error {
 	var err error
-	GtasAPIImpl = &GtasAPI{}
-	apis := []rpc.API{
-		{Namespace: "GTAS", Version: "1", Service: GtasAPIImpl, Public: true},
-	}
+	apis, modules := withLegacyAPIs(gtasAPIs(), conf.modules, conf.auth)
 	for plus := 0; plus < 40; plus++ {
-		err = startHTTP(fmt.Sprintf("%s:%d", host, port+uint(plus)), apis, []string{}, []string{}, []string{})
+		err = startHTTP(fmt.Sprintf("%s:%d", host, port+uint(plus)), apis, modules, conf.corsDomains, conf.vhosts, conf.auth)
 		if err == nil {
-			common.DefaultLogger.Errorf("RPC serving on http://%s:%d\n", host, port+uint(plus))
+			common.DefaultLogger.Infof("RPC serving on http://%s:%d\n", host, port+uint(plus))
 			return nil
 		}
 		if strings.Contains(err.Error(), "address already in use") {
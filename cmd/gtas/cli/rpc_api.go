@@ -52,12 +52,29 @@ func failResult(err string) (*Result, error) {
 	}, nil
 }
 
-// GtasAPI is a single-method API handler to be returned by test services.
-type GtasAPI struct {
+// ChainAPI provides the chain data query methods
+type ChainAPI struct {
+}
+
+// TxAPI provides the methods to submit signed transactions
+type TxAPI struct {
+}
+
+// MinerAPI provides the miner query methods, the stake operations taking the signing key are in WalletAPI
+type MinerAPI struct {
+}
+
+// WalletAPI provides the methods managing the node wallets and signing with private keys
+type WalletAPI struct {
+}
+
+// ExplorerAPI provides the methods serving the explorer and monitor
+type ExplorerAPI struct {
+	chain *ChainAPI
 }
 
 // Tx is user transaction interface
-func (api *GtasAPI) Tx(txRawjson string) (*Result, error) {
+func (api *TxAPI) Tx(txRawjson string) (*Result, error) {
 	var txRaw = new(txRawData)
 	if err := json.Unmarshal([]byte(txRawjson), txRaw); err != nil {
 		return failResult(err.Error())
@@ -74,11 +91,84 @@ func (api *GtasAPI) Tx(txRawjson string) (*Result, error) {
 	return successResult(trans.Hash.Hex())
 }
 
-// Balance is query balance interface
-func (api *GtasAPI) Balance(account string) (*Result, error) {
-	balance, err := walletManager.getBalance(account)
+// CancelTx builds the unsigned zero-value transfer to the sender self with the same nonce as the pending
+// transaction of the given hash, which replaces the pending one once signed by the sender and sent. The
+// replacement gas price should be high enough to replace the pending one, and the minimum required one will
+// be used if zero given
+func (api *TxAPI) CancelTx(hash string, gasprice uint64) (*Result, error) {
+	pool := core.BlockChainImpl.GetTransactionPool()
+	tx := pool.GetTransaction(false, common.HexToHash(hash))
+	if tx == nil || tx.Source == nil {
+		return failResult("transaction not exists in pool")
+	}
+	if gasprice == 0 {
+		gasprice = pool.ReplacementGasPrice(tx.GasPrice)
+	}
+	txRaw := &txRawData{
+		Target:   tx.Source.Hex(),
+		Value:    0,
+		Gas:      core.TransactionGasCost,
+		Gasprice: gasprice,
+		TxType:   types.TransactionTypeTransfer,
+		Nonce:    tx.Nonce,
+		Source:   tx.Source.Hex(),
+	}
+	return successResult(txRaw)
+}
+
+// SimulateTx executes the signed or unsigned transaction on a throwaway state at the top block without submitting it,
+// and returns the receipt, the balance and nonce changes and the error explaining the failure if any.
+// Source is required for unsigned transaction, and the next nonce of the source is used if nonce not set
+func (api *TxAPI) SimulateTx(txRawjson string) (*Result, error) {
+	var txRaw = new(txRawData)
+	if err := json.Unmarshal([]byte(txRawjson), txRaw); err != nil {
+		return failResult(err.Error())
+	}
+	trans := txRawToTransaction(txRaw)
+	trans.Hash = trans.GenHash()
+	if trans.Sign != nil {
+		if err := trans.RecoverSource(); err != nil {
+			return failResult(err.Error())
+		}
+	} else if txRaw.Source != "" {
+		source := common.HexToAddress(txRaw.Source)
+		trans.Source = &source
+	} else {
+		return failResult("source required for unsigned transaction")
+	}
+	if trans.Nonce == 0 {
+		trans.Nonce = core.BlockChainImpl.GetNonce(*trans.Source) + 1
+	}
+
+	ret, err := core.BlockChainImpl.SimulateTransaction(trans)
 	if err != nil {
-		return nil, err
+		return failResult(err.Error())
+	}
+	result := &SimulateResult{
+		Receipt: ret.Receipt,
+		Deltas:  make([]*AccountDelta, len(ret.Deltas)),
+	}
+	if ret.Err != nil {
+		result.ErrorCode = ret.Err.Code
+		result.ErrorMsg = ret.Err.Message
+	}
+	for i, d := range ret.Deltas {
+		result.Deltas[i] = &AccountDelta{
+			Address:       d.Address.Hex(),
+			BalanceBefore: d.BalanceBefore,
+			BalanceAfter:  d.BalanceAfter,
+			NonceBefore:   d.NonceBefore,
+			NonceAfter:    d.NonceAfter,
+		}
+	}
+	return successResult(result)
+}
+
+// Balance is query balance interface, the balance at the state of the given block if specified
+func (api *ChainAPI) Balance(account string, block *BlockSelector) (*Result, error) {
+	balance, err := walletManager.getBalanceAt(account, block)
+	if err != nil {
+		return failResult(err.Error())
 	}
 	return &Result{
 		Message: fmt.Sprintf("The balance of account: %s is %v TAS", account, balance),
@@ -87,7 +177,7 @@ func (api *GtasAPI) Balance(account string) (*Result, error) {
 }
 
 // NewWallet is create a new account interface
-func (api *GtasAPI) NewWallet() (*Result, error) {
+func (api *WalletAPI) NewWallet() (*Result, error) {
 	privKey, addr := walletManager.newWallet()
 	data := make(map[string]string)
 	data["private_key"] = privKey
@@ -96,30 +186,30 @@ func (api *GtasAPI) NewWallet() (*Result, error) {
 }
 
 // GetWallets get the wallets of the current node
-func (api *GtasAPI) GetWallets() (*Result, error) {
+func (api *WalletAPI) GetWallets() (*Result, error) {
 	return successResult(walletManager)
 }
 
 // DeleteWallet delete the address of the specified serial number of the local node
-func (api *GtasAPI) DeleteWallet(key string) (*Result, error) {
+func (api *WalletAPI) DeleteWallet(key string) (*Result, error) {
 	walletManager.deleteWallet(key)
 	return successResult(walletManager)
 }
 
 // BlockHeight query block height
-func (api *GtasAPI) BlockHeight() (*Result, error) {
+func (api *ChainAPI) BlockHeight() (*Result, error) {
 	height := core.BlockChainImpl.QueryTopBlock().Height
 	return successResult(height)
 }
 
 // GroupHeight query group height
-func (api *GtasAPI) GroupHeight() (*Result, error) {
+func (api *ChainAPI) GroupHeight() (*Result, error) {
 	height := core.GroupChainImpl.Height()
 	return successResult(height)
 }
 
 // ConnectedNodes query the information of the linked node
-func (api *GtasAPI) ConnectedNodes() (*Result, error) {
+func (api *ChainAPI) ConnectedNodes() (*Result, error) {
 
 	nodes := network.GetNetInstance().ConnInfo()
 	conns := make([]ConnInfo, 0)
@@ -130,7 +220,7 @@ func (api *GtasAPI) ConnectedNodes() (*Result, error) {
 }
 
 // TransPool query buffer transaction information
-func (api *GtasAPI) TransPool() (*Result, error) {
+func (api *ChainAPI) TransPool() (*Result, error) {
 	transactions := core.BlockChainImpl.GetTransactionPool().GetReceived()
 	transList := make([]Transactions, 0, len(transactions))
 	for _, v := range transactions {
@@ -146,7 +236,7 @@ func (api *GtasAPI) TransPool() (*Result, error) {
 }
 
 // get transaction by hash
-func (api *GtasAPI) GetTransaction(hash string) (*Result, error) {
+func (api *ChainAPI) GetTransaction(hash string) (*Result, error) {
 	transaction := core.BlockChainImpl.GetTransactionByHash(false, true, common.HexToHash(hash))
 	if transaction == nil {
 		return failResult("transaction not exists")
@@ -164,24 +254,25 @@ func (api *GtasAPI) GetTransaction(hash string) (*Result, error) {
 	return successResult(detail)
 }
 
-func (api *GtasAPI) GetBlockByHeight(height uint64) (*Result, error) {
-	b := core.BlockChainImpl.QueryBlockByHeight(height)
-	if b == nil {
+// GetBlockByHeight returns the block at the height, which is also accepted as "latest" or "finalized"
+func (api *ChainAPI) GetBlockByHeight(height BlockSelector) (*Result, error) {
+	header := height.header()
+	if header == nil {
 		return failResult("height not exists")
 	}
-	bh := b.Header
-	preBH := core.BlockChainImpl.QueryBlockHeaderByHash(bh.PreHash)
-	block := convertBlockHeader(b)
-	if preBH != nil {
-		block.Qn = bh.TotalQN - preBH.TotalQN
-	} else {
-		block.Qn = bh.TotalQN
+	return api.blockResult(core.BlockChainImpl.QueryBlockByHash(header.Hash))
+}
+
+// FinalizedBlock returns the latest finalized block, the chain never rewinds below it
+func (api *ChainAPI) FinalizedBlock() (*Result, error) {
+	header := core.BlockChainImpl.FinalizedBlock()
+	if header == nil {
+		return failResult("finalized block not exists, the finality may be disabled")
 	}
-	return successResult(block)
+	return api.blockResult(core.BlockChainImpl.QueryBlockByHash(header.Hash))
 }
 
-func (api *GtasAPI) GetBlockByHash(hash string) (*Result, error) {
-	b := core.BlockChainImpl.QueryBlockByHash(common.HexToHash(hash))
+func (api *ChainAPI) blockResult(b *types.Block) (*Result, error) {
 	if b == nil {
 		return failResult("height not exists")
 	}
@@ -196,7 +287,11 @@ func (api *GtasAPI) GetBlockByHash(hash string) (*Result, error) {
 	return successResult(block)
 }
 
-func (api *GtasAPI) GetBlocks(from uint64, to uint64) (*Result, error) {
+func (api *ChainAPI) GetBlockByHash(hash string) (*Result, error) {
+	return api.blockResult(core.BlockChainImpl.QueryBlockByHash(common.HexToHash(hash)))
+}
+
+func (api *ChainAPI) GetBlocks(from uint64, to uint64) (*Result, error) {
 	blocks := make([]*Block, 0)
 	var preBH *types.BlockHeader
 	for h := from; h <= to; h++ {
@@ -218,7 +313,7 @@ func (api *GtasAPI) GetBlocks(from uint64, to uint64) (*Result, error) {
 	return successResult(blocks)
 }
 
-func (api *GtasAPI) GetTopBlock() (*Result, error) {
+func (api *ChainAPI) GetTopBlock() (*Result, error) {
 	bh := core.BlockChainImpl.QueryTopBlock()
 	b := core.BlockChainImpl.QueryBlockByHash(bh.Hash)
 	bh = b.Header
@@ -243,7 +338,7 @@ func (api *GtasAPI) GetTopBlock() (*Result, error) {
 	return successResult(blockDetail)
 }
 
-func (api *GtasAPI) WorkGroupNum(height uint64) (*Result, error) {
+func (api *ChainAPI) WorkGroupNum(height uint64) (*Result, error) {
 	groups := mediator.Proc.GetCastQualifiedGroups(height)
 	return successResult(groups)
 }
@@ -271,7 +366,7 @@ func convertGroup(g *types.Group) map[string]interface{} {
 	return gmap
 }
 
-func (api *GtasAPI) GetGroupsAfter(height uint64) (*Result, error) {
+func (api *ChainAPI) GetGroupsAfter(height uint64) (*Result, error) {
 	groups := core.GroupChainImpl.GetGroupsAfterHeight(height, math.MaxInt64)
 
 	ret := make([]map[string]interface{}, 0)
@@ -285,12 +380,12 @@ func (api *GtasAPI) GetGroupsAfter(height uint64) (*Result, error) {
 	return successResult(ret)
 }
 
-func (api *GtasAPI) GetCurrentWorkGroup() (*Result, error) {
+func (api *ChainAPI) GetCurrentWorkGroup() (*Result, error) {
 	height := core.BlockChainImpl.Height()
 	return api.GetWorkGroup(height)
 }
 
-func (api *GtasAPI) GetWorkGroup(height uint64) (*Result, error) {
+func (api *ChainAPI) GetWorkGroup(height uint64) (*Result, error) {
 	groups := mediator.Proc.GetCastQualifiedGroupsFromChain(height)
 	ret := make([]map[string]interface{}, 0)
 	h := height
@@ -303,8 +398,8 @@ func (api *GtasAPI) GetWorkGroup(height uint64) (*Result, error) {
 	return successResult(ret)
 }
 
-// deprecated
-func (api *GtasAPI) MinerApply(sign string, bpk string, vrfpk string, stake uint64, mtype int32) (*Result, error) {
+// deprecated, the raw signing key is only accepted in the protected wallet namespace
+func (api *WalletAPI) MinerApply(sign string, bpk string, vrfpk string, stake uint64, mtype int32) (*Result, error) {
 	id := IDFromSign(sign)
 	address := common.BytesToAddress(id)
 
@@ -344,10 +439,15 @@ func (api *GtasAPI) MinerApply(sign string, bpk string, vrfpk string, stake uint
 	return successResult(nil)
 }
 
-func (api *GtasAPI) MinerQuery(mtype int32) (*Result, error) {
+// MinerQuery returns the stake details of the local miner, at the state of the given block if specified
+func (api *MinerAPI) MinerQuery(mtype int32, block *BlockSelector) (*Result, error) {
+	db, err := stateAt(block)
+	if err != nil {
+		return failResult(err.Error())
+	}
 	minerInfo := mediator.Proc.GetMinerInfo()
 	address := common.BytesToAddress(minerInfo.ID.Serialize())
-	miner := core.MinerManagerImpl.GetMinerByID(address[:], byte(mtype), nil)
+	miner := core.MinerManagerImpl.GetMinerByID(address[:], byte(mtype), db)
 	js, err := json.Marshal(miner)
 	if err != nil {
 		return &Result{Message: err.Error(), Data: nil}, err
@@ -355,8 +455,8 @@ func (api *GtasAPI) MinerQuery(mtype int32) (*Result, error) {
 	return &Result{Message: address.Hex(), Data: string(js)}, nil
 }
 
-// deprecated
-func (api *GtasAPI) MinerAbort(sign string, mtype int32) (*Result, error) {
+// deprecated, the raw signing key is only accepted in the protected wallet namespace
+func (api *WalletAPI) MinerAbort(sign string, mtype int32) (*Result, error) {
 	id := IDFromSign(sign)
 	address := common.BytesToAddress(id)
 
@@ -376,8 +476,8 @@ func (api *GtasAPI) MinerAbort(sign string, mtype int32) (*Result, error) {
 	return successResult(nil)
 }
 
-// deprecated
-func (api *GtasAPI) MinerRefund(sign string, mtype int32) (*Result, error) {
+// deprecated, the raw signing key is only accepted in the protected wallet namespace
+func (api *WalletAPI) MinerRefund(sign string, mtype int32) (*Result, error) {
 	id := IDFromSign(sign)
 	address := common.BytesToAddress(id)
 
@@ -398,7 +498,7 @@ func (api *GtasAPI) MinerRefund(sign string, mtype int32) (*Result, error) {
 }
 
 // CastStat cast block statistics
-func (api *GtasAPI) CastStat(begin uint64, end uint64) (*Result, error) {
+func (api *MinerAPI) CastStat(begin uint64, end uint64) (*Result, error) {
 	proposerStat := make(map[string]int32)
 	groupStat := make(map[string]int32)
 
@@ -443,21 +543,26 @@ func (api *GtasAPI) CastStat(begin uint64, end uint64) (*Result, error) {
 	return successResult(ret)
 }
 
-func (api *GtasAPI) MinerInfo(addr string) (*Result, error) {
+// MinerInfo returns the stake details of the miner, at the state of the given block if specified
+func (api *MinerAPI) MinerInfo(addr string, block *BlockSelector) (*Result, error) {
+	db, err := stateAt(block)
+	if err != nil {
+		return failResult(err.Error())
+	}
 	morts := make([]MortGage, 0)
 	id := common.HexToAddress(addr).Bytes()
-	heavyInfo := core.MinerManagerImpl.GetMinerByID(id, types.MinerTypeHeavy, nil)
+	heavyInfo := core.MinerManagerImpl.GetMinerByID(id, types.MinerTypeHeavy, db)
 	if heavyInfo != nil {
 		morts = append(morts, *NewMortGageFromMiner(heavyInfo))
 	}
-	lightInfo := core.MinerManagerImpl.GetMinerByID(id, types.MinerTypeLight, nil)
+	lightInfo := core.MinerManagerImpl.GetMinerByID(id, types.MinerTypeLight, db)
 	if lightInfo != nil {
 		morts = append(morts, *NewMortGageFromMiner(lightInfo))
 	}
 	return successResult(morts)
 }
 
-func (api *GtasAPI) NodeInfo() (*Result, error) {
+func (api *ChainAPI) NodeInfo() (*Result, error) {
 	ni := &NodeInfo{}
 	p := mediator.Proc
 	ni.ID = p.GetMinerID().GetHexString()
@@ -500,7 +605,7 @@ func (api *GtasAPI) NodeInfo() (*Result, error) {
 
 }
 
-func (api *GtasAPI) PageGetBlocks(page, limit int) (*Result, error) {
+func (api *ExplorerAPI) PageGetBlocks(page, limit int) (*Result, error) {
 	chain := core.BlockChainImpl
 	total := chain.Height() + 1
 	pageObject := PageObjects{
@@ -530,7 +635,7 @@ func (api *GtasAPI) PageGetBlocks(page, limit int) (*Result, error) {
 	return successResult(pageObject)
 }
 
-func (api *GtasAPI) PageGetGroups(page, limit int) (*Result, error) {
+func (api *ExplorerAPI) PageGetGroups(page, limit int) (*Result, error) {
 	chain := core.GroupChainImpl
 	total := chain.Height()
 	pageObject := PageObjects{
@@ -576,7 +681,7 @@ func (api *GtasAPI) PageGetGroups(page, limit int) (*Result, error) {
 	return successResult(pageObject)
 }
 
-func (api *GtasAPI) BlockDetail(h string) (*Result, error) {
+func (api *ChainAPI) BlockDetail(h string) (*Result, error) {
 	chain := core.BlockChainImpl
 	b := chain.QueryBlockByHash(common.HexToHash(h))
 	if b == nil {
@@ -685,7 +790,7 @@ func (api *GtasAPI) BlockDetail(h string) (*Result, error) {
 	return successResult(bd)
 }
 
-func (api *GtasAPI) BlockReceipts(h string) (*Result, error) {
+func (api *ChainAPI) BlockReceipts(h string) (*Result, error) {
 	chain := core.BlockChainImpl
 	b := chain.QueryBlockByHash(common.HexToHash(h))
 	if b == nil {
@@ -704,7 +809,7 @@ func (api *GtasAPI) BlockReceipts(h string) (*Result, error) {
 	return successResult(br)
 }
 
-func (api *GtasAPI) TransDetail(h string) (*Result, error) {
+func (api *ChainAPI) TransDetail(h string) (*Result, error) {
 	tx := core.BlockChainImpl.GetTransactionByHash(false, true, common.HexToHash(h))
 
 	if tx != nil {
@@ -714,12 +819,12 @@ func (api *GtasAPI) TransDetail(h string) (*Result, error) {
 	return successResult(nil)
 }
 
-func (api *GtasAPI) Dashboard() (*Result, error) {
+func (api *ExplorerAPI) Dashboard() (*Result, error) {
 	blockHeight := core.BlockChainImpl.Height()
 	groupHeight := core.GroupChainImpl.Height()
 	workNum := len(mediator.Proc.GetCastQualifiedGroups(blockHeight))
-	nodeResult, _ := api.NodeInfo()
-	consResult, _ := api.ConnectedNodes()
+	nodeResult, _ := api.chain.NodeInfo()
+	consResult, _ := api.chain.ConnectedNodes()
 	dash := &Dashboard{
 		BlockHeight: blockHeight,
 		GroupHeight: groupHeight,
@@ -730,26 +835,151 @@ func (api *GtasAPI) Dashboard() (*Result, error) {
 	return successResult(dash)
 }
 
-func (api *GtasAPI) Nonce(addr string) (*Result, error) {
-	address := common.HexToAddress(addr)
-	nonce := core.BlockChainImpl.GetNonce(address)
+// Nonce returns the nonce of the address, at the state of the given block if specified
+func (api *ChainAPI) Nonce(addr string, block *BlockSelector) (*Result, error) {
+	db, err := stateAt(block)
+	if err != nil {
+		return failResult(err.Error())
+	}
+	nonce := db.GetNonce(common.HexToAddress(addr))
 	return successResult(nonce)
 }
 
-func (api *GtasAPI) TxReceipt(h string) (*Result, error) {
+func (api *ChainAPI) TxReceipt(h string) (*Result, error) {
 	hash := common.HexToHash(h)
-	rc := core.BlockChainImpl.GetTransactionPool().GetReceipt(hash)
-	if rc != nil {
-		tx := core.BlockChainImpl.GetTransactionByHash(false, true, hash)
-		return successResult(&core.ExecutedTransaction{
-			Receipt:     rc,
-			Transaction: tx,
-		})
+	rc, err := core.BlockChainImpl.GetReceipt(hash)
+	if err != nil {
+		return failResult(err.Error())
 	}
-	return failResult("tx not exist")
+	tx := core.BlockChainImpl.GetTransactionByHash(false, true, hash)
+	return successResult(&core.ExecutedTransaction{
+		Receipt:     rc,
+		Transaction: tx,
+	})
 }
 
-func (api *GtasAPI) RPCSyncBlocks(height uint64, limit int, version int) (*Result, error) {
+// maxLogsQueryRange is the maximum number of blocks a log query can cover
+const maxLogsQueryRange = 100000
+
+// GetLogs returns the logs in the height range emitted by any of the given contract addresses and
+// matching the topics by position. Empty addresses or topics at a position match anything
+func (api *ChainAPI) GetLogs(from, to uint64, addresses []string, topics [][]string) (*Result, error) {
+	chain := core.BlockChainImpl
+	if top := chain.Height(); to > top {
+		to = top
+	}
+	if from > to {
+		return failResult("invalid height range")
+	}
+	if to-from >= maxLogsQueryRange {
+		return failResult(fmt.Sprintf("height range should not exceed %v", maxLogsQueryRange))
+	}
+	return successResult(chain.GetLogs(buildLogFilter(from, to, addresses, topics)))
+}
+
+func buildLogFilter(from, to uint64, addresses []string, topics [][]string) *core.LogFilter {
+	filter := &core.LogFilter{
+		FromHeight: from,
+		ToHeight:   to,
+		Addresses:  make([]common.Address, len(addresses)),
+		Topics:     make([][]common.Hash, len(topics)),
+	}
+	for i, addr := range addresses {
+		filter.Addresses[i] = common.HexToAddress(addr)
+	}
+	for i, sub := range topics {
+		filter.Topics[i] = make([]common.Hash, len(sub))
+		for j, topic := range sub {
+			filter.Topics[i][j] = common.HexToHash(topic)
+		}
+	}
+	return filter
+}
+
+func (args *CallArgs) toTransaction() (*types.Transaction, error) {
+	if args.To == "" {
+		return nil, fmt.Errorf("contract address required")
+	}
+	source := common.Address{}
+	if args.From != "" {
+		source = common.HexToAddress(args.From)
+	}
+	target := common.HexToAddress(args.To)
+	return &types.Transaction{
+		Data:     []byte(args.Data),
+		Value:    args.Value,
+		Source:   &source,
+		Target:   &target,
+		Type:     types.TransactionTypeContractCall,
+		GasLimit: args.Gas,
+	}, nil
+}
+
+// callHeader returns the header of the selected block, or the top block if not specified
+func callHeader(block *BlockSelector) (*types.BlockHeader, error) {
+	header := block.header()
+	if header == nil {
+		return nil, fmt.Errorf("block not found")
+	}
+	return header, nil
+}
+
+func convertCallResult(ret *core.CallResult) *CallResult {
+	result := &CallResult{
+		Logs:    ret.Logs,
+		GasUsed: ret.GasUsed,
+	}
+	if ret.Result != nil {
+		result.Result = ret.Result.Content
+		result.ResultType = ret.Result.ResultType
+	}
+	if ret.Err != nil {
+		result.ErrorCode = ret.Err.Code
+		result.ErrorMsg = ret.Err.Message
+	}
+	return result
+}
+
+// Call executes the contract abi call against the state at the given block, or the top block if not specified,
+// and returns the result and logs without committing anything. The max gas limit of the pool is used if gas not set
+func (api *ChainAPI) Call(args CallArgs, block *BlockSelector) (*Result, error) {
+	tx, err := args.toTransaction()
+	if err != nil {
+		return failResult(err.Error())
+	}
+	header, err := callHeader(block)
+	if err != nil {
+		return failResult(err.Error())
+	}
+	ret, err := core.BlockChainImpl.CallContract(tx, header)
+	if err != nil {
+		return failResult(err.Error())
+	}
+	return successResult(convertCallResult(ret))
+}
+
+// EstimateGas returns the lowest gas limit with which the contract call succeeds at the given block,
+// or the top block if not specified
+func (api *ChainAPI) EstimateGas(args CallArgs, block *BlockSelector) (*Result, error) {
+	tx, err := args.toTransaction()
+	if err != nil {
+		return failResult(err.Error())
+	}
+	header, err := callHeader(block)
+	if err != nil {
+		return failResult(err.Error())
+	}
+	gas, ret, err := core.BlockChainImpl.EstimateGas(tx, header)
+	if err != nil {
+		return failResult(err.Error())
+	}
+	if ret.Failed() {
+		return failResult(fmt.Sprintf("call fails with the max gas limit, error code %v:%v", ret.Err.Code, ret.Err.Message))
+	}
+	return successResult(gas)
+}
+
+func (api *ChainAPI) RPCSyncBlocks(height uint64, limit int, version int) (*Result, error) {
 	chain := core.BlockChainImpl
 	v := chain.Version()
 	if version != v {
@@ -758,3 +988,172 @@ func (api *GtasAPI) RPCSyncBlocks(height uint64, limit int, version int) (*Resul
 	blocks := chain.BatchGetBlocksAfterHeight(height, limit)
 	return successResult(blocks)
 }
+
+func hexProof(proof [][]byte) []string {
+	ret := make([]string, len(proof))
+	for i, node := range proof {
+		ret[i] = common.ToHex(node)
+	}
+	return ret
+}
+
+// GetProof returns the merkle proof of the account and its data keys under the state at the given block, or the
+// top block if not specified. The proof of the absent account or key proves its absence
+func (api *ChainAPI) GetProof(addr string, keys []string, block *BlockSelector) (*Result, error) {
+	header, err := callHeader(block)
+	if err != nil {
+		return failResult(err.Error())
+	}
+	proof, err := core.BlockChainImpl.GetProof(common.HexToAddress(addr), keys, header)
+	if err != nil {
+		return failResult(err.Error())
+	}
+	ret := &AccountProof{
+		Height:       proof.Height,
+		BlockHash:    proof.BlockHash.Hex(),
+		StateRoot:    proof.StateRoot.Hex(),
+		Address:      proof.Address.Hex(),
+		Balance:      new(big.Int),
+		StorageHash:  proof.StorageHash.Hex(),
+		AccountProof: hexProof(proof.Proof),
+		StorageProof: make([]*StorageProof, len(proof.StorageProofs)),
+	}
+	if proof.Account != nil {
+		ret.Nonce = proof.Account.Nonce
+		ret.CodeHash = common.ToHex(proof.Account.CodeHash)
+		if proof.Account.Balance != nil {
+			ret.Balance = proof.Account.Balance
+		}
+	}
+	for i, sp := range proof.StorageProofs {
+		ret.StorageProof[i] = &StorageProof{
+			Key:   sp.Key,
+			Value: common.ToHex(sp.Value),
+			Proof: hexProof(sp.Proof),
+		}
+	}
+	return successResult(ret)
+}
+
+// GetTxProof returns the proof of the transaction included in its block, which can be verified against the tx tree
+// of the block header
+func (api *ChainAPI) GetTxProof(h string) (*Result, error) {
+	proof, err := core.BlockChainImpl.GetTxProof(common.HexToHash(h))
+	if err != nil {
+		return failResult(err.Error())
+	}
+	ret := &TxProof{
+		BlockHash: proof.BlockHash.Hex(),
+		Height:    proof.Height,
+		TxTree:    proof.TxTree.Hex(),
+		Index:     proof.Index,
+		TxHashes:  make([]string, len(proof.TxHashes)),
+	}
+	for i, hash := range proof.TxHashes {
+		ret.TxHashes[i] = hash.Hex()
+	}
+	return successResult(ret)
+}
+
+// GetReceiptProof returns the proof of the transaction receipt included in its block, which can be verified
+// against the receipt tree of the block header
+func (api *ChainAPI) GetReceiptProof(h string) (*Result, error) {
+	proof, err := core.BlockChainImpl.GetReceiptProof(common.HexToHash(h))
+	if err != nil {
+		return failResult(err.Error())
+	}
+	return successResult(&ReceiptProof{
+		BlockHash:   proof.BlockHash.Hex(),
+		Height:      proof.Height,
+		ReceiptTree: proof.ReceiptTree.Hex(),
+		Index:       proof.Index,
+		Receipt:     proof.Receipt,
+		Proof:       hexProof(proof.Proof),
+	})
+}
+
+// GetAddressTxs returns the transactions the address sent, received or created by, from the newest. Page starts
+// from 1 and at most 100 transactions are returned in a page. The node must be started with the address index
+func (api *ChainAPI) GetAddressTxs(addr string, page, limit int) (*Result, error) {
+	txs, err := core.BlockChainImpl.GetAddressTxs(common.HexToAddress(addr), page, limit)
+	if err != nil {
+		return failResult(err.Error())
+	}
+	ret := make([]*AddressTx, len(txs))
+	for i, tx := range txs {
+		ret[i] = &AddressTx{
+			Hash:    tx.Hash.Hex(),
+			Height:  tx.Height,
+			TxIndex: tx.TxIndex,
+		}
+	}
+	return successResult(ret)
+}
+
+// StateDiff re-executes the block at the height and returns the balance, nonce, code and storage changes of every
+// account changed by it, including the proposal bonus of the castor and the bonus transactions. The state of the
+// parent block must not have been pruned
+func (api *ChainAPI) StateDiff(height uint64) (*Result, error) {
+	diff, err := core.BlockChainImpl.StateDiff(height)
+	if err != nil {
+		return failResult(err.Error())
+	}
+	ret := &StateDiff{
+		Height:    diff.Height,
+		BlockHash: diff.BlockHash.Hex(),
+		Accounts:  make([]*AccountDiff, len(diff.Accounts)),
+	}
+	for i, ad := range diff.Accounts {
+		account := &AccountDiff{
+			Address:       ad.Address.Hex(),
+			BalanceBefore: ad.BalanceBefore,
+			BalanceAfter:  ad.BalanceAfter,
+			NonceBefore:   ad.NonceBefore,
+			NonceAfter:    ad.NonceAfter,
+			CodeChanged:   ad.CodeChanged,
+			Storage:       make([]*StorageDiff, len(ad.Storage)),
+		}
+		if ad.CodeChanged {
+			account.CodeBefore = common.ToHex(ad.CodeBefore)
+			account.CodeAfter = common.ToHex(ad.CodeAfter)
+		}
+		for j, sd := range ad.Storage {
+			account.Storage[j] = &StorageDiff{
+				Key:    sd.Key,
+				Before: common.ToHex(sd.Before),
+				After:  common.ToHex(sd.After),
+			}
+		}
+		ret.Accounts[i] = account
+	}
+	return successResult(ret)
+}
+
+// maxStoragePageLimit is the max number of the keys in a page of GTAS_contractStorage
+const maxStoragePageLimit = 1000
+
+// ContractStorage returns a page of the contract storage at the given block, or the top block if not specified. The
+// keys with the prefix are returned in order from the cursor, which is empty for the first page and the next of the
+// previous page for the following ones. The limit is capped at 1000
+func (api *ChainAPI) ContractStorage(addr string, prefix string, cursor string, limit int, block *BlockSelector) (*Result, error) {
+	db, err := stateAt(block)
+	if err != nil {
+		return failResult(err.Error())
+	}
+	if limit <= 0 || limit > maxStoragePageLimit {
+		limit = maxStoragePageLimit
+	}
+	address := common.HexToAddress(addr)
+	keys, values, next := db.DataPage(address, prefix, string(common.FromHex(cursor)), limit)
+	ret := &ContractStorage{
+		Address: address.Hex(),
+		Entries: make([]*StorageEntry, len(keys)),
+	}
+	for i, key := range keys {
+		ret.Entries[i] = &StorageEntry{Key: key, Value: string(values[i])}
+	}
+	if next != "" {
+		ret.Next = common.ToHex([]byte(next))
+	}
+	return successResult(ret)
+}
@@ -29,6 +29,10 @@ import (
 	"github.com/taschain/taschain/middleware/types"
 )
 
+// DebugAPI provides the debugging methods, which are only exposed through IPC by default
+type DebugAPI struct {
+}
+
 type SysWorkSummary struct {
 	BeginHeight         uint64                `json:"begin_height"`
 	ToHeight            uint64                `json:"to_height"`
@@ -171,7 +175,7 @@ func selectNextVerifyGroup(gs map[string]*types.Group, preBH *types.BlockHeader,
 	return groupsig.DeserializeID(gid), qualifiedGs
 }
 
-func (api *GtasAPI) DebugVerifySummary(from, to uint64) (*Result, error) {
+func (api *DebugAPI) VerifySummary(from, to uint64) (*Result, error) {
 	if from == 0 {
 		from = 1
 	}
@@ -242,12 +246,12 @@ func (api *GtasAPI) DebugVerifySummary(from, to uint64) (*Result, error) {
 	return successResult(summary)
 }
 
-func (api *GtasAPI) DebugJoinGroupInfo(gid string) (*Result, error) {
+func (api *DebugAPI) JoinGroupInfo(gid string) (*Result, error) {
 	jg := mediator.Proc.GetJoinGroupInfo(gid)
 	return successResult(jg)
 }
 
-func (api *GtasAPI) DebugRemoveBlock(h uint64) (*Result, error) {
+func (api *DebugAPI) RemoveBlock(h uint64) (*Result, error) {
 	bh := core.BlockChainImpl.QueryBlockHeaderByHeight(h)
 	if bh != nil {
 		b := core.BlockChainImpl.QueryBlockByHash(bh.Hash)
@@ -259,7 +263,7 @@ func (api *GtasAPI) DebugRemoveBlock(h uint64) (*Result, error) {
 	return successResult("not exist")
 }
 
-func (api *GtasAPI) DebugGetTxs(limit int) (*Result, error) {
+func (api *DebugAPI) GetTxs(limit int) (*Result, error) {
 	txs := core.BlockChainImpl.GetTransactionPool().GetReceived()
 
 	hashs := make([]string, 0)
@@ -272,7 +276,7 @@ func (api *GtasAPI) DebugGetTxs(limit int) (*Result, error) {
 	return successResult(hashs)
 }
 
-func (api *GtasAPI) DebugGetBonusTxs(limit int) (*Result, error) {
+func (api *DebugAPI) GetBonusTxs(limit int) (*Result, error) {
 	txs := core.BlockChainImpl.GetTransactionPool().GetBonusTxs()
 
 	type bonusTxHash struct {
@@ -293,7 +297,7 @@ func (api *GtasAPI) DebugGetBonusTxs(limit int) (*Result, error) {
 	return successResult(hashs)
 }
 
-func (api *GtasAPI) DebugPrintCheckProve(height, preheight uint64, gids string) (*Result, error) {
+func (api *DebugAPI) PrintCheckProve(height, preheight uint64, gids string) (*Result, error) {
 	pre := core.BlockChainImpl.QueryBlockHeaderByHeight(preheight)
 	if pre == nil {
 		return failResult("nil pre block")
@@ -306,7 +310,7 @@ func (api *GtasAPI) DebugPrintCheckProve(height, preheight uint64, gids string)
 	return successResult(ss)
 }
 
-func (api *GtasAPI) DebugGetRawTx(hash string) (*Result, error) {
+func (api *DebugAPI) GetRawTx(hash string) (*Result, error) {
 	tx := core.BlockChainImpl.GetTransactionByHash(false, false, common.HexToHash(hash))
 
 	if tx != nil {
@@ -315,3 +319,39 @@ func (api *GtasAPI) DebugGetRawTx(hash string) (*Result, error) {
 	}
 	return successResult(nil)
 }
+
+func convertTxTrace(trace *core.TxTrace) *TxTrace {
+	ret := &TxTrace{
+		TxHash:  trace.TxHash.Hex(),
+		Receipt: trace.Receipt,
+		Call:    trace.Call,
+	}
+	if trace.Err != nil {
+		ret.ErrorCode = trace.Err.Code
+		ret.ErrorMsg = trace.Err.Message
+	}
+	return ret
+}
+
+// TraceTransaction re-executes the transaction on the state it was executed on, and returns the call tree with the
+// gas, storage accesses, value transfers and events of each contract call. The state must not have been pruned
+func (api *DebugAPI) TraceTransaction(hash string) (*Result, error) {
+	trace, err := core.BlockChainImpl.TraceTransaction(common.HexToHash(hash))
+	if err != nil {
+		return failResult(err.Error())
+	}
+	return successResult(convertTxTrace(trace))
+}
+
+// TraceBlock re-executes the transactions of the block and returns their traces in order
+func (api *DebugAPI) TraceBlock(hash string) (*Result, error) {
+	traces, err := core.BlockChainImpl.TraceBlock(common.HexToHash(hash))
+	if err != nil {
+		return failResult(err.Error())
+	}
+	ret := make([]*TxTrace, len(traces))
+	for i, trace := range traces {
+		ret[i] = convertTxTrace(trace)
+	}
+	return successResult(ret)
+}
@@ -16,13 +16,17 @@
 package cli
 
 import (
+	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/taschain/taschain/common"
 	"github.com/taschain/taschain/consensus/groupsig"
 	"github.com/taschain/taschain/consensus/mediator"
 	"github.com/taschain/taschain/core"
 	"github.com/taschain/taschain/middleware/types"
+	"github.com/taschain/taschain/storage/vm"
 )
 
 func convertTransaction(tx *types.Transaction) *Transaction {
@@ -119,3 +123,66 @@ func sendTransaction(trans *types.Transaction) error {
 	}
 	return nil
 }
+
+// BlockSelector is the optional rpc param selecting the block whose state is queried. It's either a json number of
+// the block height, or a string of the block hash, the decimal height, "latest" or "finalized"
+type BlockSelector struct {
+	Height    *uint64
+	Hash      *common.Hash
+	Finalized bool
+}
+
+func (s *BlockSelector) UnmarshalJSON(data []byte) error {
+	var height uint64
+	if err := json.Unmarshal(data, &height); err == nil {
+		s.Height = &height
+		return nil
+	}
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return fmt.Errorf("block should be a height or hash")
+	}
+	switch {
+	case str == "latest":
+	case str == "finalized":
+		s.Finalized = true
+	case strings.HasPrefix(str, "0x") && len(str) == 2*common.HashLength+2:
+		hash := common.HexToHash(str)
+		s.Hash = &hash
+	default:
+		height, err := strconv.ParseUint(str, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid block %v", str)
+		}
+		s.Height = &height
+	}
+	return nil
+}
+
+// header returns the selected block header, the top one if nothing selected
+func (s *BlockSelector) header() *types.BlockHeader {
+	chain := core.BlockChainImpl
+	switch {
+	case s == nil:
+		return chain.QueryTopBlock()
+	case s.Hash != nil:
+		return chain.QueryBlockHeaderByHash(*s.Hash)
+	case s.Height != nil:
+		return chain.QueryBlockHeaderByHeight(*s.Height)
+	case s.Finalized:
+		return chain.FinalizedBlock()
+	}
+	return chain.QueryTopBlock()
+}
+
+// stateAt returns the state of the selected block, the latest state if nothing selected
+func stateAt(s *BlockSelector) (vm.AccountDB, error) {
+	if s == nil || (s.Hash == nil && s.Height == nil && !s.Finalized) {
+		return core.BlockChainImpl.LatestStateDB(), nil
+	}
+	header := s.header()
+	if header == nil {
+		return nil, fmt.Errorf("block not found")
+	}
+	return core.BlockChainImpl.StateAt(header)
+}
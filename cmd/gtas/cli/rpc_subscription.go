@@ -0,0 +1,158 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cli
+
+import (
+	"context"
+	"sync"
+
+	"github.com/taschain/taschain/cmd/gtas/rpc"
+	"github.com/taschain/taschain/common"
+	"github.com/taschain/taschain/core"
+	"github.com/taschain/taschain/middleware/notify"
+	"github.com/taschain/taschain/middleware/types"
+)
+
+type eventHandler func(message notify.Message)
+
+// eventHub dispatches the events from the notify bus to the rpc subscriptions.
+// Only one handler is registered to the bus for each topic no matter how many subscriptions there are
+type eventHub struct {
+	handlers map[string]map[rpc.ID]eventHandler
+	lock     sync.RWMutex
+}
+
+var subscriptionHub = &eventHub{
+	handlers: make(map[string]map[rpc.ID]eventHandler),
+}
+
+func (hub *eventHub) subscribe(topic string, id rpc.ID, handler eventHandler) {
+	hub.lock.Lock()
+	defer hub.lock.Unlock()
+
+	if _, ok := hub.handlers[topic]; !ok {
+		hub.handlers[topic] = make(map[rpc.ID]eventHandler)
+		notify.BUS.Subscribe(topic, func(message notify.Message) {
+			hub.dispatch(topic, message)
+		})
+	}
+	hub.handlers[topic][id] = handler
+}
+
+func (hub *eventHub) unsubscribe(topic string, id rpc.ID) {
+	hub.lock.Lock()
+	defer hub.lock.Unlock()
+
+	delete(hub.handlers[topic], id)
+}
+
+func (hub *eventHub) dispatch(topic string, message notify.Message) {
+	hub.lock.RLock()
+	defer hub.lock.RUnlock()
+
+	for _, handler := range hub.handlers[topic] {
+		handler(message)
+	}
+}
+
+// LogsQuery is the criteria of the logs subscription
+type LogsQuery struct {
+	Addresses []string   `json:"addresses"`
+	Topics    [][]string `json:"topics"`
+}
+
+// subscribe creates a subscription on the connection of the context, the handle function is called on every
+// event of the topic and uses send function to notify the client
+func (api *ChainAPI) subscribe(ctx context.Context, topic string, handle func(message notify.Message, send func(data interface{}))) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	subscription := notifier.CreateSubscription()
+	send := func(data interface{}) {
+		if err := notifier.Notify(subscription.ID, data); err != nil {
+			common.DefaultLogger.Debugf("notify subscription %v error:%v", subscription.ID, err)
+		}
+	}
+	subscriptionHub.subscribe(topic, subscription.ID, func(message notify.Message) {
+		handle(message, send)
+	})
+
+	go func() {
+		select {
+		case <-subscription.Err():
+		case <-notifier.Closed():
+		}
+		subscriptionHub.unsubscribe(topic, subscription.ID)
+	}()
+	return subscription, nil
+}
+
+// NewHeads notifies the header of each block added on chain
+func (api *ChainAPI) NewHeads(ctx context.Context) (*rpc.Subscription, error) {
+	return api.subscribe(ctx, notify.BlockAddSucc, func(message notify.Message, send func(data interface{})) {
+		block := message.GetData().(*types.Block)
+		send(convertBlockHeader(block))
+	})
+}
+
+// NewGroups notifies each group added on chain
+func (api *ChainAPI) NewGroups(ctx context.Context) (*rpc.Subscription, error) {
+	return api.subscribe(ctx, notify.GroupAddSucc, func(message notify.Message, send func(data interface{})) {
+		group := message.GetData().(*types.Group)
+		send(convertGroup(group))
+	})
+}
+
+// Logs notifies the logs matching the query in each block added on chain
+func (api *ChainAPI) Logs(ctx context.Context, query LogsQuery) (*rpc.Subscription, error) {
+	return api.subscribe(ctx, notify.BlockAddSucc, func(message notify.Message, send func(data interface{})) {
+		block := message.GetData().(*types.Block)
+		height := block.Header.Height
+		filter := buildLogFilter(height, height, query.Addresses, query.Topics)
+		for _, log := range core.BlockChainImpl.GetLogs(filter) {
+			send(log)
+		}
+	})
+}
+
+// PendingTransactions notifies the hash of each transaction added into the pool
+func (api *ChainAPI) PendingTransactions(ctx context.Context) (*rpc.Subscription, error) {
+	return api.subscribe(ctx, notify.TxPoolAddSucc, func(message notify.Message, send func(data interface{})) {
+		tx := message.GetData().(*types.Transaction)
+		send(tx.Hash.Hex())
+	})
+}
+
+// ChainReorg notifies the hashes of the blocks removed and added each time the chain is rewound
+func (api *ChainAPI) ChainReorg(ctx context.Context) (*rpc.Subscription, error) {
+	return api.subscribe(ctx, notify.ChainReorg, func(message notify.Message, send func(data interface{})) {
+		msg := message.GetData().(*notify.ChainReorgMessage)
+		reorg := &ChainReorg{
+			AncestorHash:   msg.Ancestor.Hash.Hex(),
+			AncestorHeight: msg.Ancestor.Height,
+			Removed:        make([]string, 0, len(msg.Removed)),
+			Added:          make([]string, 0, len(msg.Added)),
+		}
+		for _, bh := range msg.Removed {
+			reorg.Removed = append(reorg.Removed, bh.Hash.Hex())
+		}
+		for _, bh := range msg.Added {
+			reorg.Added = append(reorg.Added, bh.Hash.Hex())
+		}
+		send(reorg)
+	})
+}
@@ -0,0 +1,66 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cli
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/taschain/taschain/cmd/gtas/rpc"
+	"github.com/taschain/taschain/middleware/notify"
+	"github.com/taschain/taschain/middleware/types"
+)
+
+func TestPendingTransactionsSubscription(t *testing.T) {
+	if notify.BUS == nil {
+		notify.BUS = notify.NewBus()
+	}
+	server := rpc.NewServer()
+	if err := server.RegisterName("chain", &ChainAPI{}); err != nil {
+		t.Fatalf("register api error:%v", err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	ch := make(chan string)
+	sub, err := client.Subscribe(context.Background(), "chain", ch, "pendingTransactions")
+	if err != nil {
+		t.Fatalf("subscribe error:%v", err)
+	}
+	defer sub.Unsubscribe()
+
+	tx := &types.Transaction{Nonce: 1, GasPrice: 1}
+	tx.Hash = tx.GenHash()
+
+	// The subscription becomes active after the id sent to the client, keep publishing until notified
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case hash := <-ch:
+			if hash != tx.Hash.Hex() {
+				t.Fatalf("notified hash error, expect %v, got %v", tx.Hash.Hex(), hash)
+			}
+			return
+		case <-ticker.C:
+			notify.BUS.Publish(notify.TxPoolAddSucc, &notify.TransactionMessage{Tx: tx})
+		case <-timeout:
+			t.Fatalf("wait for notification timeout")
+		}
+	}
+}
@@ -33,7 +33,7 @@ func TestRPC(t *testing.T) {
 	gtas := NewGtas()
 	gtas.simpleInit("tas.ini")
 	common.DefaultLogger = taslog.GetLoggerByIndex(taslog.DefaultConfig, common.GlobalConf.GetString("instance", "index", ""))
-	err := gtas.fullInit(true, true, "", 0, "127.0.0.1", "super", false, "testkey", false, 100)
+	err := gtas.fullInit(true, true, "", 0, "127.0.0.1", "super", false, "testkey", DefaultPassword, false, 100)
 	if err != nil {
 		t.Error(err)
 	}
@@ -56,16 +56,16 @@ func TestRPC(t *testing.T) {
 		t.Error(err)
 	}
 	var port uint = 8080
-	StartRPC(host, port)
+	StartRPC(host, port, &rpcConfig{modules: append(defaultRPCModules, "wallet")})
 	tests := []struct {
 		method string
 		params []interface{}
 	}{
-		{"GTAS_newWallet", nil},
+		{"wallet_newWallet", nil},
 		{"GTAS_tx", []interface{}{string(txdata)}},
 		{"GTAS_balance", []interface{}{"0x8ad32757d4dbcea703ba4b982f6fd08dad84bfcb"}},
 		{"GTAS_blockHeight", nil},
-		{"GTAS_getWallets", nil},
+		{"wallet_getWallets", nil},
 		//{},
 	}
 	for _, test := range tests {
@@ -112,3 +112,46 @@ func resetDb(dbPath string) error {
 
 	return nil
 }
+
+func TestBlockSelector(t *testing.T) {
+	hash := common.BytesToHash([]byte{1, 2, 3})
+	cases := []struct {
+		param     string
+		height    uint64
+		hash      *common.Hash
+		finalized bool
+		err       bool
+	}{
+		{`100`, 100, nil, false, false},
+		{`"100"`, 100, nil, false, false},
+		{`"latest"`, 0, nil, false, false},
+		{`"finalized"`, 0, nil, true, false},
+		{`"` + hash.Hex() + `"`, 0, &hash, false, false},
+		{`"0x01"`, 0, nil, false, true},
+		{`-1`, 0, nil, false, true},
+		{`{}`, 0, nil, false, true},
+	}
+	for _, c := range cases {
+		var s BlockSelector
+		err := json.Unmarshal([]byte(c.param), &s)
+		if (err != nil) != c.err {
+			t.Errorf("param %v: unexpected error %v", c.param, err)
+			continue
+		}
+		if c.err {
+			continue
+		}
+		if s.Finalized != c.finalized {
+			t.Errorf("param %v: finalized mismatch", c.param)
+		}
+		if c.hash != nil {
+			if s.Hash == nil || *s.Hash != *c.hash {
+				t.Errorf("param %v: hash mismatch", c.param)
+			}
+		} else if c.height > 0 && (s.Height == nil || *s.Height != c.height) {
+			t.Errorf("param %v: height mismatch", c.param)
+		} else if c.height == 0 && (s.Height != nil || s.Hash != nil) {
+			t.Errorf("param %v: latest expected", c.param)
+		}
+	}
+}
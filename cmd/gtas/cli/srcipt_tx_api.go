@@ -21,11 +21,11 @@ import (
 	"github.com/taschain/taschain/common"
 )
 
-func (api *GtasAPI) ScriptTransferTx(privateKey string, from string, to string, amount uint64, nonce uint64, txType int, gasPrice uint64) (*Result, error) {
+func (api *WalletAPI) ScriptTransferTx(privateKey string, from string, to string, amount uint64, nonce uint64, txType int, gasPrice uint64) (*Result, error) {
 	return api.TxUnSafe(privateKey, to, amount, gasPrice, gasPrice, nonce, txType, "")
 }
 
-func (api *GtasAPI) TxUnSafe(privateKey, target string, value, gas, gasprice, nonce uint64, txType int, data string) (*Result, error) {
+func (api *WalletAPI) TxUnSafe(privateKey, target string, value, gas, gasprice, nonce uint64, txType int, data string) (*Result, error) {
 	txRaw := &txRawData{
 		Target:   target,
 		Value:    common.TAS2RA(value),
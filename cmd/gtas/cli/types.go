@@ -22,6 +22,7 @@ import (
 	"github.com/taschain/taschain/common"
 	"github.com/taschain/taschain/consensus/groupsig"
 	"github.com/taschain/taschain/middleware/types"
+	"github.com/taschain/taschain/tvm"
 )
 
 // Result is rpc request successfully returns the variable parameter
@@ -248,3 +249,140 @@ type ExploreBlockBonus struct {
 	ProposalBonus uint64           `json:"proposal_bonus"`
 	VerifierBonus BonusTransaction `json:"verifier_bonus"`
 }
+
+// CallArgs is the contract call executed by GTAS_call and GTAS_estimateGas, data is the abi json
+type CallArgs struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Value uint64 `json:"value"`
+	Gas   uint64 `json:"gas"`
+	Data  string `json:"data"`
+}
+
+type CallResult struct {
+	Result     string       `json:"result"`
+	ResultType int          `json:"result_type"`
+	Logs       []*types.Log `json:"logs"`
+	GasUsed    uint64       `json:"gas_used"`
+	ErrorCode  int          `json:"error_code"`
+	ErrorMsg   string       `json:"error_msg"`
+}
+
+type AccountDelta struct {
+	Address       string   `json:"address"`
+	BalanceBefore *big.Int `json:"balance_before"`
+	BalanceAfter  *big.Int `json:"balance_after"`
+	NonceBefore   uint64   `json:"nonce_before"`
+	NonceAfter    uint64   `json:"nonce_after"`
+}
+
+type SimulateResult struct {
+	Receipt   *types.Receipt  `json:"receipt"`
+	ErrorCode int             `json:"error_code"`
+	ErrorMsg  string          `json:"error_msg"`
+	Deltas    []*AccountDelta `json:"deltas"`
+}
+
+// TxTrace is the result of debug_traceTransaction, the call tree is null if the transaction runs no contract
+type TxTrace struct {
+	TxHash    string         `json:"tx_hash"`
+	Receipt   *types.Receipt `json:"receipt"`
+	ErrorCode int            `json:"error_code"`
+	ErrorMsg  string         `json:"error_msg"`
+	Call      *tvm.CallFrame `json:"call"`
+}
+
+type StorageProof struct {
+	Key   string   `json:"key"`
+	Value string   `json:"value"`
+	Proof []string `json:"proof"`
+}
+
+// AccountProof is the result of GTAS_getProof, the proof nodes are hex encoded and ordered from the root
+type AccountProof struct {
+	Height       uint64          `json:"height"`
+	BlockHash    string          `json:"block_hash"`
+	StateRoot    string          `json:"state_root"`
+	Address      string          `json:"address"`
+	Balance      *big.Int        `json:"balance"`
+	Nonce        uint64          `json:"nonce"`
+	CodeHash     string          `json:"code_hash"`
+	StorageHash  string          `json:"storage_hash"`
+	AccountProof []string        `json:"account_proof"`
+	StorageProof []*StorageProof `json:"storage_proof"`
+}
+
+// TxProof is the result of GTAS_getTxProof, the tx tree is the hash of all the tx hashes of the block in order
+type TxProof struct {
+	BlockHash string   `json:"block_hash"`
+	Height    uint64   `json:"height"`
+	TxTree    string   `json:"tx_tree"`
+	Index     int      `json:"index"`
+	TxHashes  []string `json:"tx_hashes"`
+}
+
+// ReceiptProof is the result of GTAS_getReceiptProof, the proof nodes are hex encoded and ordered from the root
+type ReceiptProof struct {
+	BlockHash   string         `json:"block_hash"`
+	Height      uint64         `json:"height"`
+	ReceiptTree string         `json:"receipt_tree"`
+	Index       int            `json:"index"`
+	Receipt     *types.Receipt `json:"receipt"`
+	Proof       []string       `json:"proof"`
+}
+
+// StorageDiff is the hex encoded value of the storage key before and after the block, 0x0 if absent
+type StorageDiff struct {
+	Key    string `json:"key"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// AccountDiff is the change of an account in the result of GTAS_stateDiff, the code is only given if changed
+type AccountDiff struct {
+	Address       string         `json:"address"`
+	BalanceBefore *big.Int       `json:"balance_before"`
+	BalanceAfter  *big.Int       `json:"balance_after"`
+	NonceBefore   uint64         `json:"nonce_before"`
+	NonceAfter    uint64         `json:"nonce_after"`
+	CodeChanged   bool           `json:"code_changed"`
+	CodeBefore    string         `json:"code_before,omitempty"`
+	CodeAfter     string         `json:"code_after,omitempty"`
+	Storage       []*StorageDiff `json:"storage"`
+}
+
+// StateDiff is the result of GTAS_stateDiff, covering every account changed by the block
+type StateDiff struct {
+	Height    uint64         `json:"height"`
+	BlockHash string         `json:"block_hash"`
+	Accounts  []*AccountDiff `json:"accounts"`
+}
+
+// StorageEntry is a key-value pair of the contract storage
+type StorageEntry struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// ContractStorage is a page of the contract storage in the result of GTAS_contractStorage, the next is the hex cursor
+// of the following page, empty if it's the last page
+type ContractStorage struct {
+	Address string          `json:"address"`
+	Entries []*StorageEntry `json:"entries"`
+	Next    string          `json:"next"`
+}
+
+type AddressTx struct {
+	Hash    string `json:"hash"`
+	Height  uint64 `json:"height"`
+	TxIndex uint16 `json:"tx_index"`
+}
+
+// ChainReorg is the notification of the ChainReorg subscription. Removed lists the reverted blocks from the old
+// top, and added lists the blocks of the new branch added in the same operation
+type ChainReorg struct {
+	AncestorHash   string   `json:"ancestor_hash"`
+	AncestorHeight uint64   `json:"ancestor_height"`
+	Removed        []string `json:"removed"`
+	Added          []string `json:"added"`
+}
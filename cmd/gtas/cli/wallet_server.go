@@ -43,7 +43,7 @@ func (ws *WalletServer) Start() error {
 		{Namespace: "GTASWallet", Version: "1", Service: ws, Public: true},
 	}
 	host := fmt.Sprintf("127.0.0.1:%d", ws.Port)
-	err := startHTTP(host, apis, []string{}, []string{}, []string{})
+	err := startHTTP(host, apis, []string{}, []string{}, []string{}, nil)
 	if err == nil {
 		fmt.Printf("Wallet RPC serving on http://%s\n", host)
 		return nil
@@ -20,7 +20,6 @@ import (
 	"sync"
 
 	"github.com/taschain/taschain/common"
-	"github.com/taschain/taschain/core"
 )
 
 // Wallets contains wallets
@@ -61,10 +60,19 @@ func (ws *wallets) newWallet() (privKeyStr, walletAddress string) {
 }
 
 func (ws *wallets) getBalance(account string) (float64, error) {
+	return ws.getBalanceAt(account, nil)
+}
+
+// getBalanceAt returns the balance of the account at the state of the selected block
+func (ws *wallets) getBalanceAt(account string, block *BlockSelector) (float64, error) {
 	if account == "" && len(walletManager) > 0 {
 		account = walletManager[0].Address
 	}
-	balance := core.BlockChainImpl.GetBalance(common.HexToAddress(account))
+	db, err := stateAt(block)
+	if err != nil {
+		return 0, err
+	}
+	balance := db.GetBalance(common.HexToAddress(account))
 
 	return common.RA2TAS(balance.Uint64()), nil
 }
@@ -0,0 +1,174 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	errMissingToken = errors.New("missing bearer token")
+	errInvalidToken = errors.New("invalid bearer token")
+	errTokenExpired = errors.New("bearer token expired")
+)
+
+// Authenticator verifies the bearer token carried by the requests to the protected namespaces
+type Authenticator interface {
+	Authenticate(token string) error
+}
+
+type authTokenKey struct{}
+
+// contextWithToken returns a copy of the context carrying the bearer token of the http request if any
+func contextWithToken(ctx context.Context, r *http.Request) context.Context {
+	if r == nil {
+		return ctx
+	}
+	header := r.Header.Get("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "bearer ") {
+		return context.WithValue(ctx, authTokenKey{}, strings.TrimSpace(header[7:]))
+	}
+	return ctx
+}
+
+func tokenFromContext(ctx context.Context) string {
+	token, _ := ctx.Value(authTokenKey{}).(string)
+	return token
+}
+
+// staticAuthenticator accepts the token equal to the configured secret
+type staticAuthenticator struct {
+	secret []byte
+}
+
+// NewStaticAuthenticator creates an authenticator which accepts the given secret as the bearer token
+func NewStaticAuthenticator(secret string) Authenticator {
+	return &staticAuthenticator{secret: []byte(secret)}
+}
+
+func (a *staticAuthenticator) Authenticate(token string) error {
+	if subtle.ConstantTimeCompare([]byte(token), a.secret) != 1 {
+		return errInvalidToken
+	}
+	return nil
+}
+
+// jwtAuthenticator accepts the HS256 signed JSON web token, the exp and nbf claims are checked if present
+type jwtAuthenticator struct {
+	key []byte
+}
+
+// NewJWTAuthenticator creates an authenticator which accepts the JSON web tokens signed by the given key with HS256
+func NewJWTAuthenticator(key []byte) Authenticator {
+	return &jwtAuthenticator{key: key}
+}
+
+func (a *jwtAuthenticator) Authenticate(token string) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return errInvalidToken
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeJWTSegment(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return errInvalidToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return errInvalidToken
+	}
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return errInvalidToken
+	}
+
+	var claims struct {
+		Exp *int64 `json:"exp"`
+		Nbf *int64 `json:"nbf"`
+	}
+	if err := decodeJWTSegment(parts[1], &claims); err != nil {
+		return errInvalidToken
+	}
+	now := time.Now().Unix()
+	if claims.Exp != nil && now >= *claims.Exp {
+		return errTokenExpired
+	}
+	if claims.Nbf != nil && now < *claims.Nbf {
+		return errInvalidToken
+	}
+	return nil
+}
+
+func decodeJWTSegment(seg string, v interface{}) error {
+	bs, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bs, v)
+}
+
+// anyAuthenticator accepts the token accepted by any of the authenticators
+type anyAuthenticator []Authenticator
+
+// NewAnyAuthenticator combines the authenticators, the token passes if any of them accepts it
+func NewAnyAuthenticator(auths ...Authenticator) Authenticator {
+	return anyAuthenticator(auths)
+}
+
+func (as anyAuthenticator) Authenticate(token string) error {
+	err := errInvalidToken
+	for _, a := range as {
+		if err = a.Authenticate(token); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// SetAuthenticator requires the calls to the given namespaces to carry a bearer token accepted by the authenticator
+func (s *Server) SetAuthenticator(auth Authenticator, namespaces []string) {
+	s.auth = auth
+	s.authNamespaces = make(map[string]bool)
+	for _, ns := range namespaces {
+		s.authNamespaces[ns] = true
+	}
+}
+
+// authorize checks the token in the context if the namespace is protected
+func (s *Server) authorize(ctx context.Context, namespace string) Error {
+	if s.auth == nil || !s.authNamespaces[namespace] {
+		return nil
+	}
+	token := tokenFromContext(ctx)
+	if token == "" {
+		return &unauthorizedError{errMissingToken.Error()}
+	}
+	if err := s.auth.Authenticate(token); err != nil {
+		return &unauthorizedError{err.Error()}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func signJWT(key []byte, alg string, claims string) string {
+	enc := base64.RawURLEncoding
+	payload := enc.EncodeToString([]byte(fmt.Sprintf(`{"alg":"%v","typ":"JWT"}`, alg))) + "." + enc.EncodeToString([]byte(claims))
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload))
+	return payload + "." + enc.EncodeToString(mac.Sum(nil))
+}
+
+func TestJWTAuthenticator(t *testing.T) {
+	key := []byte("jwt secret")
+	auth := NewJWTAuthenticator(key)
+	now := time.Now().Unix()
+
+	tokens := []struct {
+		token string
+		valid bool
+	}{
+		{signJWT(key, "HS256", `{}`), true},
+		{signJWT(key, "HS256", fmt.Sprintf(`{"exp":%v}`, now+60)), true},
+		{signJWT(key, "HS256", fmt.Sprintf(`{"exp":%v}`, now-60)), false},
+		{signJWT(key, "HS256", fmt.Sprintf(`{"nbf":%v}`, now+60)), false},
+		{signJWT(key, "none", `{}`), false},
+		{signJWT([]byte("other key"), "HS256", `{}`), false},
+		{"abc", false},
+	}
+	for i, tk := range tokens {
+		if err := auth.Authenticate(tk.token); (err == nil) != tk.valid {
+			t.Errorf("authenticate token %v error, expect valid %v, got %v", i, tk.valid, err)
+		}
+	}
+}
+
+func TestHTTPAuthNamespace(t *testing.T) {
+	server := NewServer()
+	server.RegisterName("open", new(Service))
+	server.RegisterName("admin", new(Service))
+	server.SetAuthenticator(NewAnyAuthenticator(NewStaticAuthenticator("secret"), NewJWTAuthenticator([]byte("key"))), []string{"admin"})
+
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	call := func(method string, token string) *jsonError {
+		body := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"%v","params":[]}`, method)
+		req, _ := http.NewRequest(http.MethodPost, httpServer.URL, strings.NewReader(body))
+		req.Header.Set("content-type", contentType)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("http request error:%v", err)
+		}
+		defer resp.Body.Close()
+		var msg jsonrpcMessage
+		if err := json.NewDecoder(resp.Body).Decode(&msg); err != nil {
+			t.Fatalf("decode response error:%v", err)
+		}
+		return msg.Error
+	}
+
+	if err := call("open_noArgsRets", ""); err != nil {
+		t.Errorf("open namespace should not require token, got %v", err)
+	}
+	if err := call("admin_noArgsRets", ""); err == nil || err.Code != -32001 {
+		t.Errorf("admin namespace should require token, got %v", err)
+	}
+	if err := call("admin_noArgsRets", "wrong"); err == nil || err.Code != -32001 {
+		t.Errorf("admin namespace should reject wrong token, got %v", err)
+	}
+	if err := call("admin_noArgsRets", "secret"); err != nil {
+		t.Errorf("admin namespace should accept static secret, got %v", err)
+	}
+	if err := call("admin_noArgsRets", signJWT([]byte("key"), "HS256", `{}`)); err != nil {
+		t.Errorf("admin namespace should accept jwt, got %v", err)
+	}
+}
@@ -518,7 +518,7 @@ const HTMLTEM = `<!DOCTYPE html>
         // 钱包初始化
         function init_wallets() {
             let params = {
-                "method": "GTAS_getWallets",
+                "method": "wallet_getWallets",
                 "params": [],
                 "jsonrpc": "2.0",
                 "id": "1"
@@ -550,7 +550,7 @@ const HTMLTEM = `<!DOCTYPE html>
 
         function del_wallet(key) {
             let params = {
-                "method": "GTAS_deleteWallet",
+                "method": "wallet_deleteWallet",
                 "params": [key],
                 "jsonrpc": "2.0",
                 "id": "1"
@@ -574,7 +574,7 @@ const HTMLTEM = `<!DOCTYPE html>
         // 创建钱包
         $("#create_btn").click(function () {
             let params = {
-                "method": "GTAS_newWallet",
+                "method": "wallet_newWallet",
                 "params": [],
                 "jsonrpc": "2.0",
                 "id": "1"
@@ -151,7 +151,9 @@ func (t *httpReadWriteNopCloser) Close() error {
 func NewHTTPServer(cors []string, vhosts []string, srv *Server) *http.Server {
 	// Wrap the CORS-handler within a host-handler
 	handler := newCorsHandler(srv, cors)
-	// handler = newVHostHandler(vhosts, handler)
+	if len(vhosts) > 0 {
+		handler = newVHostHandler(vhosts, handler)
+	}
 	return &http.Server{Handler: handler}
 }
 
@@ -207,9 +209,7 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	defer codec.Close()
 
 	w.Header().Set("content-type", contentType)
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Headers", "X-Requested-With,X_Requested_With,Content-Type")
-	srv.ServeSingleRequest(codec, OptionMethodInvocation)
+	srv.serveRequest(contextWithToken(context.Background(), r), codec, true, OptionMethodInvocation)
 }
 
 // validateRequest returns a non-zero response code and error message if the
@@ -109,7 +109,7 @@ func (s *Server) RegisterName(name string, rcvr interface{}) error {
 	return nil
 }
 
-func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecOption) error {
+func (s *Server) serveRequest(ctx context.Context, codec ServerCodec, singleShot bool, options CodecOption) error {
 	var pend sync.WaitGroup
 
 	defer func() {
@@ -124,7 +124,7 @@ func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecO
 		s.codecsMu.Unlock()
 	}()
 
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	if options&OptionSubscriptions == OptionSubscriptions {
@@ -190,11 +190,11 @@ func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecO
 
 func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 	defer codec.Close()
-	s.serveRequest(codec, false, options)
+	s.serveRequest(context.Background(), codec, false, options)
 }
 
 func (s *Server) ServeSingleRequest(codec ServerCodec, options CodecOption) {
-	s.serveRequest(codec, true, options)
+	s.serveRequest(context.Background(), codec, true, options)
 }
 
 // Stop stops reading new requests, waits for stopPendingRequestTimeout to allow pending
@@ -230,6 +230,10 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 		return codec.CreateErrorResponse(&req.id, req.err), nil
 	}
 
+	if err := s.authorize(ctx, req.svcname); err != nil {
+		return codec.CreateErrorResponse(&req.id, err), nil
+	}
+
 	if req.isUnsubscribe { // Cancel subscription, first param must be the subscription id
 		if len(req.args) >= 1 && req.args[0].Kind() == reflect.String {
 			notifier, supported := NotifierFromContext(ctx)
@@ -69,6 +69,9 @@ type Server struct {
 	run      int32
 	codecsMu sync.Mutex
 	codecs   set.Interface
+
+	auth           Authenticator   // verifies the bearer token for the protected namespaces
+	authNamespaces map[string]bool // namespaces requiring the bearer token
 }
 
 type rpcRequest struct {
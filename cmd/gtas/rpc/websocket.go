@@ -35,7 +35,9 @@ func (srv *Server) WebsocketHandler(allowedOrigins []string) http.Handler {
 	return websocket.Server{
 		Handshake: wsHandshakeValidator(allowedOrigins),
 		Handler: func(conn *websocket.Conn) {
-			srv.ServeCodec(NewJSONCodec(conn), OptionMethodInvocation|OptionSubscriptions)
+			codec := NewJSONCodec(conn)
+			defer codec.Close()
+			srv.serveRequest(contextWithToken(context.Background(), conn.Request()), codec, false, OptionMethodInvocation|OptionSubscriptions)
 		},
 	}
 }
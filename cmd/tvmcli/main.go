@@ -13,6 +13,9 @@
 //   You should have received a copy of the GNU General Public License
 //   along with this program.  If not, see <https://www.gnu.org/licenses/>.
 
+//go:build tvm_python
+// +build tvm_python
+
 package main
 
 import (
@@ -35,6 +38,12 @@ var (
 	contractAddress = callContract.Arg("contractAddress", "contract address.").Required().String()
 	contractAbi     = callContract.Arg("abiPath", "").Required().String()
 
+	storage        = app.Command("storage", "view a page of the contract storage.")
+	storageAddress = storage.Arg("contractAddress", "contract address.").Required().String()
+	storagePrefix  = storage.Flag("prefix", "prefix of the storage keys.").String()
+	storageCursor  = storage.Flag("cursor", "the next cursor printed by the previous page.").String()
+	storageLimit   = storage.Flag("limit", "max number of the keys in the page.").Default("100").Int()
+
 	exportAbi             = app.Command("export", "export abi.")
 	exportAbiContractName = exportAbi.Arg("name", "").Required().String()
 	exportAbiContractPath = exportAbi.Arg("path", "").Required().String()
@@ -64,6 +73,10 @@ func main() {
 		}
 		tvmCli.Call(*contractAddress, string(f))
 
+	// storage 0x... --prefix=balanceOf@ --limit=100
+	case storage.FullCommand():
+		tvmCli.Storage(*storageAddress, *storagePrefix, *storageCursor, *storageLimit)
+
 	// export ./cli/erc20.py
 	case exportAbi.FullCommand():
 		f, err := ioutil.ReadFile(filepath.Dir(os.Args[0]) + "/" + *exportAbiContractPath) //读取文件
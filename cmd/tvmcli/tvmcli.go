@@ -13,6 +13,9 @@
 //   You should have received a copy of the GNU General Public License
 //   along with this program.  If not, see <https://www.gnu.org/licenses/>.
 
+//go:build tvm_python
+// +build tvm_python
+
 package main
 
 import (
@@ -89,7 +92,7 @@ func (t *TvmCli) init() {
 		return
 	}
 	fmt.Println(currentPath)
-	t.db, _ = tasdb.NewLDBDatabase(currentPath+"/db", 0, 0)
+	t.db, _ = tasdb.NewLDBDatabase(currentPath+"/db", nil)
 	t.database = account.NewDatabase(t.db)
 
 	if Exists(currentPath + "/settings.ini") {
@@ -147,9 +150,8 @@ func (t *TvmCli) Deploy(contractName string, contractCode string) string {
 	state.SetCode(contractAddress, jsonBytes)
 
 	contract.ContractAddress = &contractAddress
-	controller.VM.SetGas(500000)
 	controller.Deploy(&contract)
-	fmt.Println("gas: ", 500000-controller.VM.Gas())
+	fmt.Println("gas: ", transaction.GetGasLimit()-controller.GetGasLeft())
 
 	hash, error := state.Commit(false)
 	t.database.TrieDB().Commit(hash, false)
@@ -177,9 +179,8 @@ func (t *TvmCli) Call(contractAddress string, abiJSON string) {
 	contract := tvm.LoadContract(_contractAddress)
 	//fmt.Println(contract.Code)
 	sender := common.HexToAddress(DefaultAccounts[0])
-	controller.VM.SetGas(500000)
 	executeResult := controller.ExecuteAbiEval(&sender, contract, abiJSON)
-	fmt.Println("gas: ", 500000-controller.VM.Gas())
+	fmt.Println("gas: ", Transaction{}.GetGasLimit()-controller.GetGasLeft())
 
 	if executeResult == nil {
 		fmt.Println("ExecuteAbiEval error")
@@ -199,6 +200,22 @@ func (t *TvmCli) Call(contractAddress string, abiJSON string) {
 	fmt.Println(hash.Hex())
 }
 
+func (t *TvmCli) Storage(contractAddress string, prefix string, cursor string, limit int) {
+	stateHash := t.settings.GetString("root", "StateHash", "")
+	state, err := account.NewAccountDB(common.HexToHash(stateHash), t.database)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	keys, values, next := state.DataPage(common.HexToAddress(contractAddress), prefix, string(common.FromHex(cursor)), limit)
+	for i, key := range keys {
+		fmt.Println(key, ": ", string(values[i]))
+	}
+	if next != "" {
+		fmt.Println("next cursor: ", common.ToHex([]byte(next)))
+	}
+}
+
 func (t *TvmCli) ExportAbi(contractName string, contractCode string) {
 	contract := tvm.Contract{
 		ContractName: contractName,
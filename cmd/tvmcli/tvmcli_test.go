@@ -13,6 +13,9 @@
 //   You should have received a copy of the GNU General Public License
 //   along with this program.  If not, see <https://www.gnu.org/licenses/>.
 
+//go:build tvm_python
+// +build tvm_python
+
 package main
 
 import (
@@ -18,6 +18,7 @@ package logical
 import (
 	"github.com/taschain/taschain/common"
 	"github.com/taschain/taschain/consensus/model"
+	"github.com/taschain/taschain/core"
 	"github.com/taschain/taschain/taslog"
 )
 
@@ -38,5 +39,8 @@ func InitConsensus() {
 	groupLogger = taslog.GetLoggerByIndex(taslog.GroupLogConfig, common.GlobalConf.GetString("instance", "index", ""))
 	consensusConfManager = cc
 	model.InitParam(cc)
+	if core.BlockChainImpl != nil {
+		model.Param.ApplyGenesis(core.BlockChainImpl.Genesis().Consensus)
+	}
 	return
 }
@@ -19,6 +19,7 @@ import (
 	"math"
 
 	"github.com/taschain/taschain/common"
+	"github.com/taschain/taschain/middleware/types"
 )
 
 // defines some const params of the consensus engine
@@ -135,6 +136,41 @@ func InitParam(cc common.SectionConfManager) {
 	}
 }
 
+// ApplyGenesis overrides the params with the non-zero values fixed in the genesis spec,
+// so that all nodes of the network share the same params regardless of their config files
+func (p *ConsensusParam) ApplyGenesis(gc *types.GenesisConsensus) {
+	if gc == nil {
+		return
+	}
+	setInt := func(v *int, g int) {
+		if g > 0 {
+			*v = g
+		}
+	}
+	setUint := func(v *uint64, g uint64) {
+		if g > 0 {
+			*v = g
+		}
+	}
+	setInt(&p.GroupMemberMax, gc.GroupMemberMax)
+	setInt(&p.GroupMemberMin, gc.GroupMemberMin)
+	setInt(&p.MaxGroupCastTime, gc.MaxGroupCastTime)
+	setInt(&p.MaxWaitBlockTime, gc.MaxWaitBlockTime)
+	setInt(&p.MinerMaxJoinGroup, gc.MinerMaxJoinGroup)
+	setInt(&p.CandidatesMinRatio, gc.CandidatesMinRatio)
+	setUint(&p.Epoch, gc.Epoch)
+	setUint(&p.GroupCreateGap, gc.GroupCreateGap)
+	setUint(&p.GroupWaitPongGap, gc.GroupWaitPongGap)
+	setUint(&p.GroupReadyGap, gc.GroupReadyGap)
+	setUint(&p.GroupWorkGap, gc.GroupWorkGap)
+	setUint(&p.GroupworkDuration, gc.GroupWorkDuration)
+	setUint(&p.CreateGroupInterval, gc.GroupCreateInterval)
+	setUint(&p.ProposalBonus, gc.ProposalBonus)
+	setUint(&p.PackBonus, gc.PackBonus)
+	setUint(&p.VerifyBonus, gc.VerifyBonus)
+	setUint(&p.VerifierStake, gc.VerifierStake)
+}
+
 func (p *ConsensusParam) GetGroupK(max int) int {
 	return int(math.Ceil(float64(max*p.SSSSThreshold) / 100))
 }
@@ -0,0 +1,154 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+
+	"github.com/taschain/taschain/common"
+	"github.com/taschain/taschain/middleware/types"
+	"github.com/taschain/taschain/storage/tasdb"
+)
+
+// maxAddressTxsLimit is the maximum number of transactions returned by one address transactions query
+const maxAddressTxsLimit = 100
+
+// AddressTx locates a transaction the address sent, received or created by
+type AddressTx struct {
+	Hash    common.Hash
+	Height  uint64
+	TxIndex uint16
+}
+
+// addrTxIndex maps the address to the transactions it's involved in. The key is the address followed by the
+// big endian block height and tx index, so that the transactions of an address are stored in the chain order
+// and the value is the transaction hash. The source, target and created contract addresses are indexed.
+// Only the blocks added after the index enabled are indexed, the fast synced blocks below the pivot neither
+type addrTxIndex struct {
+	db *tasdb.PrefixedDatabase
+}
+
+func newAddrTxIndex(db *tasdb.PrefixedDatabase) *addrTxIndex {
+	return &addrTxIndex{
+		db: db,
+	}
+}
+
+func addrTxKey(addr common.Address, height uint64, txIndex uint16) []byte {
+	key := make([]byte, 0, common.AddressLength+10)
+	key = append(key, addr.Bytes()...)
+	key = append(key, common.UInt64ToByte(height)...)
+	return append(key, common.UInt16ToByte(txIndex)...)
+}
+
+// txAddresses returns the addresses the transaction involved in, the contract address is taken from the receipt
+func txAddresses(tx *types.Transaction, receipt *types.Receipt) []common.Address {
+	addrs := make([]common.Address, 0, 3)
+	if tx.Source != nil {
+		addrs = append(addrs, *tx.Source)
+	}
+	if tx.Target != nil {
+		addrs = append(addrs, *tx.Target)
+	}
+	if receipt != nil && receipt.ContractAddress != (common.Address{}) {
+		addrs = append(addrs, receipt.ContractAddress)
+	}
+	return addrs
+}
+
+// saveBlockTxs adds the index entries of the transactions of the block into the batch
+func (idx *addrTxIndex) saveBlockTxs(batch tasdb.Batch, height uint64, txs []*types.Transaction, receipts types.Receipts) error {
+	for i, tx := range txs {
+		var receipt *types.Receipt
+		if i < len(receipts) {
+			receipt = receipts[i]
+		}
+		for _, addr := range txAddresses(tx, receipt) {
+			if err := idx.db.AddKv(batch, addrTxKey(addr, height, uint16(i)), tx.Hash.Bytes()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// deleteBlockTxs adds the deletion of the index entries of the transactions of the reverted block into the batch
+func (idx *addrTxIndex) deleteBlockTxs(batch tasdb.Batch, height uint64, txs []*types.Transaction, receipts types.Receipts) error {
+	for i, tx := range txs {
+		var receipt *types.Receipt
+		if i < len(receipts) {
+			receipt = receipts[i]
+		}
+		for _, addr := range txAddresses(tx, receipt) {
+			if err := idx.db.AddKv(batch, addrTxKey(addr, height, uint16(i)), nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// addressTxs returns the transactions of the address from the newest, skipping the first offset ones
+func (idx *addrTxIndex) addressTxs(addr common.Address, offset, limit int) []*AddressTx {
+	txs := make([]*AddressTx, 0)
+	iter := idx.db.NewIteratorWithPrefix(addr.Bytes())
+	defer iter.Release()
+
+	for ok := iter.Last(); ok && len(txs) < limit; ok = iter.Prev() {
+		if offset > 0 {
+			offset--
+			continue
+		}
+		// The iterator key is stripped of the address prefix
+		key := iter.Key()
+		if len(key) != 10 {
+			continue
+		}
+		txs = append(txs, &AddressTx{
+			Hash:    common.BytesToHash(iter.Value()),
+			Height:  common.ByteToUInt64(key[:8]),
+			TxIndex: common.ByteToUInt16(key[8:]),
+		})
+	}
+	return txs
+}
+
+// blockReceipts returns the stored receipts of the transactions, nil for the missing ones
+func (chain *FullBlockChain) blockReceipts(txs []*types.Transaction) types.Receipts {
+	receipts := make(types.Receipts, len(txs))
+	for i, tx := range txs {
+		receipts[i] = chain.transactionPool.GetReceipt(tx.Hash)
+	}
+	return receipts
+}
+
+// GetAddressTxs returns the transactions the address sent, received or created by, from the newest.
+// Page starts from 1
+func (chain *FullBlockChain) GetAddressTxs(addr common.Address, page, limit int) ([]*AddressTx, error) {
+	if chain.addrIndex == nil {
+		return nil, fmt.Errorf("address index not enabled")
+	}
+	if page < 1 {
+		page = 1
+	}
+	if limit <= 0 || limit > maxAddressTxsLimit {
+		limit = maxAddressTxsLimit
+	}
+	chain.rwLock.RLock()
+	defer chain.rwLock.RUnlock()
+
+	return chain.addrIndex.addressTxs(addr, (page-1)*limit, limit), nil
+}
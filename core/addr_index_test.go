@@ -0,0 +1,94 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/taschain/taschain/common"
+	"github.com/taschain/taschain/middleware/types"
+	"github.com/taschain/taschain/storage/tasdb"
+)
+
+func TestAddrTxIndex(t *testing.T) {
+	initConf4Test(t)
+	dir, err := ioutil.TempDir("", "addr_index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ds, err := tasdb.NewDataSource(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := ds.NewPrefixDatabase("ai")
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx := newAddrTxIndex(db)
+
+	alice := common.BytesToAddress([]byte("alice"))
+	bob := common.BytesToAddress([]byte("bob"))
+	contract := common.BytesToAddress([]byte("contract"))
+	blockTxs := make(map[uint64][]*types.Transaction)
+	blockReceipts := make(map[uint64]types.Receipts)
+	for h := uint64(1); h <= 3; h++ {
+		transfer := &types.Transaction{Source: &alice, Target: &bob, Nonce: h * 2}
+		create := &types.Transaction{Source: &bob, Nonce: h*2 + 1}
+		transfer.Hash, create.Hash = transfer.GenHash(), create.GenHash()
+		blockTxs[h] = []*types.Transaction{transfer, create}
+		blockReceipts[h] = types.Receipts{{TxHash: transfer.Hash}, {TxHash: create.Hash, ContractAddress: contract}}
+
+		batch := db.CreateLDBBatch()
+		if err := idx.saveBlockTxs(batch, h, blockTxs[h], blockReceipts[h]); err != nil {
+			t.Fatal(err)
+		}
+		if err := batch.Write(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	txs := idx.addressTxs(bob, 0, 10)
+	if len(txs) != 6 {
+		t.Fatalf("bob tx count mismatch: have %v, want 6", len(txs))
+	}
+	if txs[0].Height != 3 || txs[0].TxIndex != 1 || txs[0].Hash != blockTxs[3][1].Hash {
+		t.Errorf("newest tx of bob mismatch: %+v", txs[0])
+	}
+	if txs = idx.addressTxs(alice, 1, 1); len(txs) != 1 || txs[0].Hash != blockTxs[2][0].Hash {
+		t.Errorf("second page of alice mismatch: %+v", txs)
+	}
+	if txs = idx.addressTxs(contract, 0, 10); len(txs) != 3 {
+		t.Errorf("contract tx count mismatch: have %v, want 3", len(txs))
+	}
+
+	// Revert the top block
+	batch := db.CreateLDBBatch()
+	if err := idx.deleteBlockTxs(batch, 3, blockTxs[3], blockReceipts[3]); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+	if txs = idx.addressTxs(bob, 0, 10); len(txs) != 4 || txs[0].Height != 2 {
+		t.Errorf("bob txs after revert mismatch: %+v", txs)
+	}
+	if txs = idx.addressTxs(common.BytesToAddress([]byte("nobody")), 0, 10); len(txs) != 0 {
+		t.Errorf("unknown address has txs: %+v", txs)
+	}
+}
@@ -93,6 +93,9 @@ func InitBlockSyncer(chain *FullBlockChain) {
 }
 
 func (bs *blockSyncer) isSyncing() bool {
+	if stateSync.isActive() {
+		return true
+	}
 	localHeight := bs.chain.Height()
 	bs.lock.RLock()
 	defer bs.lock.RUnlock()
@@ -133,6 +136,27 @@ func (bs *blockSyncer) getBestCandidate(candidateID string) (string, *topBlockIn
 	return candidateID, maxTop
 }
 
+// bestCandidateTop returns the top block of the best candidate, nil if no candidate
+func (bs *blockSyncer) bestCandidateTop() *topBlockInfo {
+	bs.lock.Lock()
+	defer bs.lock.Unlock()
+	_, top := bs.getBestCandidate("")
+	return top
+}
+
+// candidatesAbove returns the candidates whose top is not lower than the given height
+func (bs *blockSyncer) candidatesAbove(height uint64) []string {
+	bs.lock.RLock()
+	defer bs.lock.RUnlock()
+	ids := make([]string, 0)
+	for id, top := range bs.candidatePool {
+		if top.Height >= height {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
 func (bs *blockSyncer) getPeerTopBlock(id string) *topBlockInfo {
 	bs.lock.RLock()
 	defer bs.lock.RUnlock()
@@ -154,6 +178,10 @@ func (bs *blockSyncer) syncFrom(from string) bool {
 		bs.logger.Debugf("chain is adjusting, won't sync")
 		return false
 	}
+	if !stateSync.blockSyncAllowed() {
+		bs.logger.Debugf("waiting for the state sync, won't sync")
+		return false
+	}
 	bs.logger.Debugf("Local Weight:%v, height:%d,topHash:%s", localTopBlock.BlockWeight.String(), localTopBlock.Height, localTopBlock.Hash.Hex())
 
 	bs.lock.Lock()
@@ -325,6 +353,15 @@ func (bs *blockSyncer) blockResponseMsgHandler(msg notify.Message) {
 			return
 		}
 
+		if pivot, fast := stateSync.fastPivot(); fast {
+			if bs.fastAddBlocks(source, blocks, pivot) {
+				bs.syncComplete(source, false)
+				complete = true
+				go bs.trySyncRoutine()
+			}
+			return
+		}
+
 		allSuccess := true
 
 		bs.chain.batchAddBlockOnChain(source, "sync", blocks, func(b *types.Block, ret types.AddBlockResult) bool {
@@ -346,6 +383,20 @@ func (bs *blockSyncer) blockResponseMsgHandler(msg notify.Message) {
 	}
 }
 
+// fastAddBlocks stores the blocks up to the pivot without execution in fast sync, returns true if the sync should go on
+func (bs *blockSyncer) fastAddBlocks(source string, blocks []*types.Block, pivot uint64) bool {
+	pivotBH, err := bs.chain.insertFastBlocks(blocks, pivot)
+	if err != nil {
+		bs.logger.Debugf("fast sync block from %v error:%v", source, err)
+		return false
+	}
+	if pivotBH != nil {
+		stateSync.pivotReached(pivotBH)
+		return false
+	}
+	return true
+}
+
 func (bs *blockSyncer) addCandidatePool(source string, topBlockInfo *topBlockInfo) {
 	bs.lock.Lock()
 	defer bs.lock.Unlock()
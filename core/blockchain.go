@@ -22,7 +22,6 @@ import (
 	"github.com/syndtr/goleveldb/leveldb/opt"
 	"os"
 	"sync"
-	"time"
 
 	lru "github.com/hashicorp/golang-lru"
 	"github.com/taschain/taschain/common"
@@ -32,6 +31,7 @@ import (
 	"github.com/taschain/taschain/storage/account"
 	"github.com/taschain/taschain/storage/tasdb"
 	"github.com/taschain/taschain/taslog"
+	"github.com/taschain/taschain/tvm"
 )
 
 const (
@@ -61,6 +61,9 @@ type BlockChainConfig struct {
 	bonus       string
 	tx          string
 	receipt     string
+	txJournal   string
+	logBloom    string
+	addrIndex   string
 }
 
 // FullBlockChain manages chain imports, reverts, chain reorganisations.
@@ -69,9 +72,12 @@ type FullBlockChain struct {
 	blockHeight *tasdb.PrefixedDatabase
 	txDb        *tasdb.PrefixedDatabase
 	stateDb     *tasdb.PrefixedDatabase
+	logIndex    *logIndex
+	addrIndex   *addrTxIndex
 	batch       tasdb.Batch
 
 	stateCache account.AccountDatabase
+	pruner     *statePruner
 
 	transactionPool TransactionPool
 
@@ -105,6 +111,15 @@ type FullBlockChain struct {
 
 	ticker *ticker.GlobalTicker // Ticker is a global time ticker
 	ts     time2.TimeService
+
+	genesis *types.Genesis // Genesis spec the chain initialized with
+
+	maxReorgDepth uint64 // Forks rewinding more blocks than it are rejected, no limit if 0
+
+	finalized      *types.BlockHeader // Latest finalized block, the chain never rewinds below it
+	finalityGroups int                // Number of distinct groups the blocks built on a block take to finalize it
+
+	fastSyncedHeight uint64 // Height of the last block stored by the fast sync, the blocks up to it have no receipts
 }
 
 func getBlockChainConfig() *BlockChainConfig {
@@ -118,12 +133,15 @@ func getBlockChainConfig() *BlockChainConfig {
 
 		bonus: "nu",
 
-		tx:      "tx",
-		receipt: "rc",
+		tx:        "tx",
+		receipt:   "rc",
+		txJournal: "tj",
+		logBloom:  "lb",
+		addrIndex: "ai",
 	}
 }
 
-func initBlockChain(helper types.ConsensusHelper) error {
+func initBlockChain(helper types.ConsensusHelper, genesis *types.Genesis) error {
 	instance := common.GlobalConf.GetString("instance", "index", "")
 	Logger = taslog.GetLoggerByIndex(taslog.CoreLogConfig, instance)
 	consensusLogger = taslog.GetLoggerByIndex(taslog.ConsensusLogConfig, instance)
@@ -138,10 +156,22 @@ func initBlockChain(helper types.ConsensusHelper) error {
 		futureBlocks:    common.MustNewLRUCache(10),
 		verifiedBlocks:  common.MustNewLRUCache(10),
 		topBlocks:       common.MustNewLRUCache(20),
+		maxReorgDepth:   uint64(common.GlobalConf.GetInt(configSec, "max_reorg_depth", 0)),
+		finalityGroups:  common.GlobalConf.GetInt(configSec, "finality_groups", 0),
 	}
 
 	types.DefaultPVFunc = helper.VRFProve2Value
 
+	if engine := common.GlobalConf.GetString("tvm", "engine", ""); engine != "" {
+		if err := tvm.SelectEngine(engine); err != nil {
+			Logger.Errorf("select tvm engine error:%v", err)
+			return err
+		}
+	}
+	if tvm.EngineName() == tvm.GoEngine {
+		Logger.Warnf("tvm go engine in use, the python contracts can't run. Build with the tvm_python tag to run them")
+	}
+
 	chain.initMessageHandler()
 
 	options := &opt.Options{
@@ -188,9 +218,41 @@ func initBlockChain(helper types.ConsensusHelper) error {
 		Logger.Errorf("Init block chain error! Error:%s", err.Error())
 		return err
 	}
+	journaldb, err := ds.NewPrefixDatabase(chain.config.txJournal)
+	if err != nil {
+		Logger.Errorf("Init block chain error! Error:%s", err.Error())
+		return err
+	}
+	bloomdb, err := ds.NewPrefixDatabase(chain.config.logBloom)
+	if err != nil {
+		Logger.Errorf("Init block chain error! Error:%s", err.Error())
+		return err
+	}
+	if common.GlobalConf.GetBool(configSec, "address_index", false) {
+		addrdb, err := ds.NewPrefixDatabase(chain.config.addrIndex)
+		if err != nil {
+			Logger.Errorf("Init block chain error! Error:%s", err.Error())
+			return err
+		}
+		chain.addrIndex = newAddrTxIndex(addrdb)
+	}
+	chain.latestBlock = chain.loadCurrentBlock()
+	chain.fastSyncedHeight = chain.loadFastSyncedHeight()
+	stored, err := chain.loadGenesis()
+	if err != nil {
+		Logger.Errorf("Init block chain error! Error:%s", err.Error())
+		return err
+	}
+	if genesis == nil || chain.latestBlock != nil {
+		chain.applyGenesis(stored)
+	} else {
+		chain.applyGenesis(genesis)
+	}
+
+	chain.logIndex = newLogIndex(bloomdb)
 	chain.bonusManager = newBonusManager()
 	chain.batch = chain.blocks.CreateLDBBatch()
-	chain.transactionPool = newTransactionPool(chain, receiptdb)
+	chain.transactionPool = newTransactionPool(chain, receiptdb, journaldb)
 
 	chain.txBatch = newTxBatchAdder(chain.transactionPool)
 
@@ -199,30 +261,92 @@ func initBlockChain(helper types.ConsensusHelper) error {
 	chain.executor = NewTVMExecutor(chain)
 	initMinerManager(chain.ticker)
 
-	chain.latestBlock = chain.loadCurrentBlock()
 	if nil != chain.latestBlock {
 		if !chain.versionValidate() {
 			fmt.Println("Illegal data version! Please delete the directory d0 and restart the program!")
 			os.Exit(0)
 		}
-		chain.buildCache(10)
-		Logger.Debugf("initBlockChain chain.latestBlock.StateTree  Hash:%s", chain.latestBlock.StateTree.Hex())
-		state, err := account.NewAccountDB(common.BytesToHash(chain.latestBlock.StateTree.Bytes()), chain.stateCache)
-		if nil == err {
-			chain.latestStateDB = state
-		} else {
+		// Without the given spec, the stored one must still match the genesis block on chain
+		given := genesis
+		if given == nil {
+			given = stored
+		}
+		if err := chain.checkGenesis(given, stored); err != nil {
+			Logger.Errorf("Init block chain error! Error:%s", err.Error())
+			return err
+		}
+		chain.pruner = newStatePruner(chain.stateCache.TrieDB(), chain.latestBlock.Height, chain.maxReorgDepth)
+		if err := chain.repairState(); err != nil {
 			panic("initBlockChain NewAccountDB fail:" + err.Error())
 		}
+		chain.buildCache(10)
+		Logger.Debugf("initBlockChain chain.latestBlock.StateTree  Hash:%s", chain.latestBlock.StateTree.Hex())
 	} else {
-		chain.insertGenesisBlock()
+		chain.pruner = newStatePruner(chain.stateCache.TrieDB(), 0, chain.maxReorgDepth)
+		if err := chain.insertGenesisBlock(); err != nil {
+			Logger.Errorf("Init block chain error! Error:%s", err.Error())
+			return err
+		}
+	}
+
+	if err := chain.rebuildLogIndex(); err != nil {
+		Logger.Errorf("Init block chain error! Error:%s", err.Error())
+		return err
 	}
 
+	if chain.finalityGroups > 0 {
+		chain.finalized = chain.loadFinalized()
+	}
 	chain.forkProcessor = initForkProcessor(chain)
 
 	BlockChainImpl = chain
+	chain.transactionPool.loadJournal()
 	return nil
 }
 
+// repairState loads the state of the latest block. The states kept in memory are lost if the node crashed in
+// pruned mode, the chain is rewound to the latest block whose state was persisted
+func (chain *FullBlockChain) repairState() error {
+	// The blocks stored by the interrupted fast sync have no state, keep them and stay at the genesis state
+	// until the sync resumes
+	if chain.loadFastSyncPivot() > 0 {
+		genesis := chain.queryBlockHeaderByHeight(0)
+		if genesis == nil {
+			return fmt.Errorf("genesis block not found")
+		}
+		state, err := account.NewAccountDB(genesis.StateTree, chain.stateCache)
+		if err != nil {
+			return err
+		}
+		chain.latestStateDB = state
+		return nil
+	}
+	var firstErr error
+	for bh := chain.latestBlock; bh != nil; bh = chain.queryBlockHeaderByHash(bh.PreHash) {
+		state, err := account.NewAccountDB(bh.StateTree, chain.stateCache)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			if bh.Height == 0 {
+				break
+			}
+			continue
+		}
+		if bh.Hash != chain.latestBlock.Hash {
+			Logger.Errorf("state of block %v missing, rewind chain from height %v to %v, the %v blocks removed will be synced again", chain.latestBlock.Hash.Hex(), chain.latestBlock.Height, bh.Height, chain.latestBlock.Height-bh.Height)
+			fmt.Printf("WARNING: the states of the recent blocks were lost as the node was not closed properly in pruned mode, rewind chain from height %v to %v, the %v blocks removed will be synced again\n", chain.latestBlock.Height, bh.Height, chain.latestBlock.Height-bh.Height)
+			if _, err := chain.resetTop(bh); err != nil {
+				return err
+			}
+			return nil
+		}
+		chain.latestStateDB = state
+		return nil
+	}
+	return firstErr
+}
+
 func (chain *FullBlockChain) buildCache(size int) {
 	hash := chain.latestBlock.Hash
 	for size > 0 {
@@ -237,52 +361,29 @@ func (chain *FullBlockChain) buildCache(size int) {
 	}
 }
 
-// insertGenesisBlock creates the genesis block and some necessary information，
+// insertGenesisBlock creates the genesis block of the genesis spec and some necessary information，
 // and commit it
-func (chain *FullBlockChain) insertGenesisBlock() {
+func (chain *FullBlockChain) insertGenesisBlock() error {
 	stateDB, err := account.NewAccountDB(common.Hash{}, chain.stateCache)
 	if nil != err {
-		panic("Init block chain error:" + err.Error())
-	}
-
-	block := new(types.Block)
-	block.Header = &types.BlockHeader{
-		Height:     0,
-		ExtraData:  common.Sha256([]byte("tas")),
-		CurTime:    time2.TimeToTimeStamp(time.Date(2019, 4, 25, 0, 0, 0, 0, time.UTC)),
-		ProveValue: []byte{},
-		Elapsed:    0,
-		TotalQN:    0,
-		Nonce:      common.ChainDataVersion,
+		return fmt.Errorf("init block chain error:%v", err)
 	}
 
-	block.Header.Signature = common.Sha256([]byte("tas"))
-	block.Header.Random = common.Sha256([]byte("tas_initial_random"))
-
-	genesisInfo := chain.consensusHelper.GenerateGenesisInfo()
-	setupGenesisStateDB(stateDB, genesisInfo)
-
-	miners := make([]*types.Miner, 0)
-	for i, member := range genesisInfo.Group.Members {
-		miner := &types.Miner{ID: member, PublicKey: genesisInfo.Pks[i], VrfPublicKey: genesisInfo.VrfPKs[i], Stake: common.TAS2RA(100)}
-		miners = append(miners, miner)
+	block, err := chain.genesisBlock(chain.genesis, stateDB, true)
+	if err != nil {
+		return err
 	}
-	MinerManagerImpl.addGenesesMiner(miners, stateDB)
-	stateDB.SetNonce(common.BonusStorageAddress, 1)
-	stateDB.SetNonce(common.HeavyDBAddress, 1)
-	stateDB.SetNonce(common.LightDBAddress, 1)
-	stateDB.SetNonce(common.MinerStakeDetailDBAddress, 1)
-
-	root, _ := stateDB.Commit(true)
-	block.Header.StateTree = common.BytesToHash(root.Bytes())
-	block.Header.Hash = block.Header.GenHash()
 
 	ok, err := chain.commitBlock(block, &executePostState{state: stateDB})
 	if !ok {
-		panic("insert genesis block fail, err=%v" + err.Error())
+		return fmt.Errorf("insert genesis block fail, err=%v", err)
+	}
+	if err := chain.storeGenesis(chain.genesis); err != nil {
+		return err
 	}
 
 	Logger.Debugf("GenesisBlock %+v", block.Header)
+	return nil
 }
 
 // Clear clear blockchain all data. Not used now, should remove it latter
@@ -315,6 +416,13 @@ func (chain *FullBlockChain) compareBlockWeight(bh1 *types.BlockHeader, bh2 *typ
 
 // Close the open levelDb files
 func (chain *FullBlockChain) Close() {
+	chain.rwLock.Lock()
+	if chain.latestBlock != nil {
+		if err := chain.pruner.flush(chain.latestBlock.StateTree, chain.latestBlock.Height); err != nil {
+			Logger.Errorf("flush latest state error:%v", err)
+		}
+	}
+	chain.rwLock.Unlock()
 	chain.blocks.Close()
 	chain.blockHeight.Close()
 	chain.stateDb.Close()
@@ -332,9 +440,7 @@ func (chain *FullBlockChain) GetConsensusHelper() types.ConsensusHelper {
 
 // ResetTop reset the current top block with parameter bh
 func (chain *FullBlockChain) ResetTop(bh *types.BlockHeader) {
-	chain.mu.Lock()
-	defer chain.mu.Unlock()
-	chain.resetTop(bh)
+	chain.publishReorg(bh, chain.rewind(bh), nil)
 }
 
 // Remove removes the block and blocks after it from the chain. Only used in a debug file, should be removed later
@@ -345,7 +451,9 @@ func (chain *FullBlockChain) Remove(block *types.Block) bool {
 	if pre == nil {
 		return chain.removeOrphan(block) == nil
 	}
-	return chain.resetTop(pre) == nil
+	removed, err := chain.resetTop(pre)
+	chain.publishReorg(pre, removed, nil)
+	return err == nil
 }
 
 func (chain *FullBlockChain) getLatestBlock() *types.BlockHeader {
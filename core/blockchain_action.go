@@ -339,7 +339,13 @@ func (chain *FullBlockChain) addBlockOnChain(source string, b *types.Block) (ret
 		newTop := chain.queryBlockHeaderByHash(bh.PreHash)
 		old := chain.latestBlock
 		Logger.Debugf("simple fork reset top: old %v %v %v %v, coming %v %v %v %v", old.Hash.ShortS(), old.Height, old.PreHash.ShortS(), old.TotalQN, bh.Hash.ShortS(), bh.Height, bh.PreHash.ShortS(), bh.TotalQN)
-		if e := chain.resetTop(newTop); e != nil {
+		if e := chain.checkReorgDepth(newTop); e != nil {
+			ret = types.AddBlockFailed
+			err = e
+			return
+		}
+		removed, e := chain.resetTop(newTop)
+		if e != nil {
 			Logger.Warnf("reset top err, currTop %v, setTop %v, setHeight %v", topBlock.Hash.Hex(), newTop.Hash.Hex(), newTop.Height)
 			ret = types.AddBlockFailed
 			err = fmt.Errorf("reset top err:%v", e)
@@ -348,14 +354,17 @@ func (chain *FullBlockChain) addBlockOnChain(source string, b *types.Block) (ret
 
 		if chain.getLatestBlock().Hash != bh.PreHash {
 			Logger.Error("reset top error")
+			chain.publishReorg(newTop, removed, nil)
 			return
 		}
 
 		ok, e := chain.commitBlock(b, ps)
 		if ok {
+			chain.publishReorg(newTop, removed, []*types.BlockHeader{bh})
 			ret = types.AddBlockSucc
 			return
 		}
+		chain.publishReorg(newTop, removed, nil)
 		Logger.Warnf("insert block fail, hash=%v, height=%v, err=%v", bh.Hash.Hex(), bh.Height, e)
 		ret = types.AddBlockFailed
 		err = ErrCommitBlockFail
@@ -363,11 +372,21 @@ func (chain *FullBlockChain) addBlockOnChain(source string, b *types.Block) (ret
 	}
 }
 
-// validateTxs check tx sign and recover source
+// validateTxs check tx sign and recover source. The block is rejected if any tx pays less than the gas price floor
+// of the genesis spec
 func (chain *FullBlockChain) validateTxs(bh *types.BlockHeader, txs []*types.Transaction) bool {
 	if txs == nil || len(txs) == 0 {
 		return true
 	}
+	if chain.genesis != nil && chain.genesis.GasPriceFloor > 0 {
+		floor := chain.genesis.GasPriceFloor
+		for _, tx := range txs {
+			if tx.Type != types.TransactionTypeBonus && tx.GasPrice < floor {
+				Logger.Debugf("fail to validate txs: gas price %v of %v below the floor %v", tx.GasPrice, tx.Hash.Hex(), floor)
+				return false
+			}
+		}
+	}
 
 	traceLog := monitor.NewPerformTraceLogger("validateTxs", bh.Hash, bh.Height)
 	traceLog.SetParent("verifyTxs")
@@ -522,12 +541,19 @@ func (chain *FullBlockChain) batchAddBlockOnChain(source string, module string,
 		return
 	}
 	firstBH := addBlocks[0]
+	var (
+		pre     *types.BlockHeader
+		removed []*types.BlockHeader
+	)
 	if firstBH.Header.PreHash != localTop.Hash {
-		pre := chain.QueryBlockHeaderByHash(firstBH.Header.PreHash)
+		pre = chain.QueryBlockHeaderByHash(firstBH.Header.PreHash)
 		if pre != nil {
+			if chain.checkReorgDepth(pre) != nil {
+				return
+			}
 			last := addBlocks[len(addBlocks)-1].Header
 			Logger.Debugf("%v batchAdd reset top:old %v %v %v, new %v %v %v, last %v %v %v", module, localTop.Hash.ShortS(), localTop.Height, localTop.TotalQN, pre.Hash.ShortS(), pre.Height, pre.TotalQN, last.Hash.ShortS(), last.Height, last.TotalQN)
-			chain.ResetTop(pre)
+			removed = chain.rewind(pre)
 		} else {
 			// There will fork, we have to deal with it
 			Logger.Debugf("%v batchAdd detect fork from %v: local %v %v, peer %v %v", module, source, localTop.Hash.ShortS(), localTop.Height, firstBH.Header.Hash.ShortS(), firstBH.Header.Height)
@@ -540,10 +566,17 @@ func (chain *FullBlockChain) batchAddBlockOnChain(source string, module string,
 		chain.isAdjusting = false
 	}()
 
+	added := make([]*types.BlockHeader, 0)
 	for _, b := range addBlocks {
 		ret := chain.AddBlockOnChain(source, b)
+		if ret == types.AddBlockSucc {
+			added = append(added, b.Header)
+		}
 		if !callback(b, ret) {
 			break
 		}
 	}
+	if len(removed) > 0 {
+		chain.publishReorg(pre, removed, added)
+	}
 }
@@ -267,6 +267,29 @@ func (chain *FullBlockChain) GetAccountDBByHash(hash common.Hash) (vm.AccountDB,
 	return account.NewAccountDB(header.StateTree, chain.stateCache)
 }
 
+// StateAt returns the account database at the state of the given block. The error tells the state is pruned only if
+// the block is out of the retention window of the pruned mode, otherwise it wraps the error opening the state
+func (chain *FullBlockChain) StateAt(header *types.BlockHeader) (vm.AccountDB, error) {
+	if header == nil {
+		return nil, fmt.Errorf("block not found")
+	}
+	return chain.stateAt(header)
+}
+
+func (chain *FullBlockChain) stateAt(header *types.BlockHeader) (*account.AccountDB, error) {
+	db, err := account.NewAccountDB(header.StateTree, chain.stateCache)
+	if err == nil {
+		return db, nil
+	}
+	if header.Height < chain.fastSyncedHeight {
+		return nil, fmt.Errorf("state of block %v at height %v is %v", header.Hash.Hex(), header.Height, ErrFastSynced)
+	}
+	if top := chain.getLatestBlock(); chain.pruner != nil && top != nil && chain.pruner.outOfRetention(header.Height, top.Height) {
+		return nil, fmt.Errorf("state of block %v at height %v has been pruned, query an archive node instead", header.Hash.Hex(), header.Height)
+	}
+	return nil, fmt.Errorf("open state of block %v at height %v error: %v", header.Hash.Hex(), header.Height, err)
+}
+
 // GetAccountDBByHeight returns account database with specified block height
 func (chain *FullBlockChain) GetAccountDBByHeight(height uint64) (vm.AccountDB, error) {
 	chain.rwLock.RLock()
@@ -31,8 +31,7 @@ func (chain *FullBlockChain) saveBlockState(b *types.Block, state *account.Accou
 		return fmt.Errorf("state commit error:%s", err.Error())
 	}
 
-	triedb := chain.stateCache.TrieDB()
-	err = triedb.Commit(root, false)
+	err = chain.pruner.commit(root, b.Header.Height)
 	if err != nil {
 		return fmt.Errorf("trie commit error:%s", err.Error())
 	}
@@ -115,10 +114,27 @@ func (chain *FullBlockChain) commitBlock(block *types.Block, ps *executePostStat
 	if err = chain.transactionPool.saveReceipts(bh.Hash, ps.receipts); err != nil {
 		return
 	}
+	// Save log blooms of the block
+	if err = chain.logIndex.saveBlockBloom(chain.batch, bh.Height, ps.receipts); err != nil {
+		return
+	}
+	// Save the address index of the block transactions
+	if chain.addrIndex != nil {
+		if err = chain.addrIndex.saveBlockTxs(chain.batch, bh.Height, block.Transactions, ps.receipts); err != nil {
+			return
+		}
+	}
 	// Save current block
 	if err = chain.saveCurrentBlock(bh.Hash); err != nil {
 		return
 	}
+	// Save the block finalized by this one
+	finalized := chain.nextFinalized(bh)
+	if finalized != nil {
+		if err = chain.saveFinalized(finalized.Hash); err != nil {
+			return
+		}
+	}
 	// Batch write
 	if err = chain.batch.Write(); err != nil {
 		return
@@ -126,6 +142,9 @@ func (chain *FullBlockChain) commitBlock(block *types.Block, ps *executePostStat
 	//ps.ts.AddStat("batch.Write", time.Since(b))
 
 	chain.updateLatestBlock(ps.state, bh)
+	if finalized != nil {
+		chain.finalized = finalized
+	}
 
 	rmTxLog := monitor.NewPerformTraceLogger("RemoveFromPool", block.Header.Hash, block.Header.Height)
 	rmTxLog.SetParent("commitBlock")
@@ -144,7 +163,8 @@ func (chain *FullBlockChain) commitBlock(block *types.Block, ps *executePostStat
 	return
 }
 
-func (chain *FullBlockChain) resetTop(block *types.BlockHeader) error {
+// resetTop rewinds the chain to the given block and returns the removed blocks from the old top
+func (chain *FullBlockChain) resetTop(block *types.BlockHeader) (removed []*types.BlockHeader, err error) {
 	if !chain.isAdjusting {
 		chain.isAdjusting = true
 		defer func() {
@@ -161,15 +181,17 @@ func (chain *FullBlockChain) resetTop(block *types.BlockHeader) error {
 	defer chain.rwLock.Unlock()
 
 	if nil == block {
-		return fmt.Errorf("block is nil")
+		return nil, fmt.Errorf("block is nil")
 	}
 	if block.Hash == chain.latestBlock.Hash {
-		return nil
+		return nil, nil
+	}
+	if chain.revertsFinalized(block) {
+		Logger.Warnf("refuse to reset top to %v-%v below the finalized block %v-%v", block.Hash.Hex(), block.Height, chain.finalized.Hash.Hex(), chain.finalized.Height)
+		return nil, ErrFinalizedReverted
 	}
 	Logger.Debugf("reset top hash:%s height:%d ", block.Hash.Hex(), block.Height)
 
-	var err error
-
 	defer chain.batch.Reset()
 
 	curr := chain.getLatestBlock()
@@ -178,17 +200,27 @@ func (chain *FullBlockChain) resetTop(block *types.BlockHeader) error {
 	for curr.Hash != block.Hash {
 		// Delete the old block header
 		if err = chain.saveBlockHeader(curr.Hash, nil); err != nil {
-			return err
+			return nil, err
 		}
 		// Delete the old block height
 		if err = chain.saveBlockHeight(curr.Height, nil); err != nil {
-			return err
+			return nil, err
 		}
 		// Delete the old block's transactions
 		if err = chain.saveBlockTxs(curr.Hash, nil); err != nil {
-			return err
+			return nil, err
+		}
+		// Delete the old block's log bloom
+		if err = chain.logIndex.deleteBlockBloom(chain.batch, curr.Height); err != nil {
+			return nil, err
 		}
 		txs := chain.queryBlockTransactionsAll(curr.Hash)
+		// Delete the old block's address index
+		if chain.addrIndex != nil && txs != nil {
+			if err = chain.addrIndex.deleteBlockTxs(chain.batch, curr.Height, txs, chain.blockReceipts(txs)); err != nil {
+				return nil, err
+			}
+		}
 		if txs != nil {
 			recoverTxs = append(recoverTxs, txs...)
 			for _, tx := range txs {
@@ -196,6 +228,7 @@ func (chain *FullBlockChain) resetTop(block *types.BlockHeader) error {
 			}
 		}
 
+		removed = append(removed, curr)
 		chain.removeTopBlock(curr.Hash)
 		Logger.Debugf("remove block %v", curr.Hash.Hex())
 		if curr.PreHash == block.Hash {
@@ -205,24 +238,24 @@ func (chain *FullBlockChain) resetTop(block *types.BlockHeader) error {
 	}
 	// Delete receipts corresponding to the transactions in the discard block
 	if err = chain.transactionPool.deleteReceipts(delRecepites); err != nil {
-		return err
+		return nil, err
 	}
 	// Reset the current block
 	if err = chain.saveCurrentBlock(block.Hash); err != nil {
-		return err
+		return nil, err
 	}
 	state, err := account.NewAccountDB(block.StateTree, chain.stateCache)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if err = chain.batch.Write(); err != nil {
-		return err
+		return nil, err
 	}
 	chain.updateLatestBlock(state, block)
 
 	chain.transactionPool.BackToPool(recoverTxs)
 
-	return nil
+	return removed, nil
 }
 
 // removeOrphan remove the orphan block
@@ -251,7 +284,15 @@ func (chain *FullBlockChain) removeOrphan(block *types.Block) error {
 	if err = chain.saveBlockTxs(hash, nil); err != nil {
 		return err
 	}
+	if err = chain.logIndex.deleteBlockBloom(chain.batch, height); err != nil {
+		return err
+	}
 	txs := chain.queryBlockTransactionsAll(hash)
+	if chain.addrIndex != nil && txs != nil {
+		if err = chain.addrIndex.deleteBlockTxs(chain.batch, height, txs, chain.blockReceipts(txs)); err != nil {
+			return err
+		}
+	}
 	if txs != nil {
 		txHashs := make([]common.Hash, len(txs))
 		for i, tx := range txs {
@@ -25,11 +25,13 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/taschain/taschain/common"
 	"github.com/taschain/taschain/consensus/groupsig"
 	"github.com/taschain/taschain/consensus/model"
 	"github.com/taschain/taschain/middleware"
+	time2 "github.com/taschain/taschain/middleware/time"
 	"github.com/taschain/taschain/middleware/types"
 	"github.com/taschain/taschain/network"
 	"github.com/taschain/taschain/taslog"
@@ -77,22 +79,22 @@ func TestBlockChain_AddBlock(t *testing.T) {
 	//	print("hehe")
 	//`
 	// 交易1
-	_, err = txpool.AddTransaction(genTestTx(12345, "100", "2", 0, 1))
+	_, err = txpool.AddTransaction(genTestTx(12345, "100", "2", 1, 1))
 	if err != nil {
-		t.Fatalf("fail to AddTransaction")
+		t.Fatalf("fail to AddTransaction: %v", err)
 	}
 	//txpool.AddTransaction(genContractTx(1, 20000000, "1", "", 1, 0, []byte(code), nil, 0))
 	contractAddr := common.BytesToAddress(common.Sha256(common.BytesCombine([]byte("1"), common.Uint64ToByte(0))))
 	//交易2
-	_, err = txpool.AddTransaction(genTestTx(123456, "2", "3", 0, 1))
+	_, err = txpool.AddTransaction(genTestTx(123456, "2", "3", 2, 1))
 	if err != nil {
-		t.Fatalf("fail to AddTransaction")
+		t.Fatalf("fail to AddTransaction: %v", err)
 	}
 
 	//交易3 执行失败的交易
-	_, err = txpool.AddTransaction(genTestTx(123456, "2", "3", 1, 1))
+	_, err = txpool.AddTransaction(genTestTx(123456, "2", "3", 3, 1))
 	if err != nil {
-		t.Fatalf("fail to AddTransaction")
+		t.Fatalf("fail to AddTransaction: %v", err)
 	}
 	castor := new([]byte)
 	groupid := new([]byte)
@@ -126,9 +128,9 @@ func TestBlockChain_AddBlock(t *testing.T) {
 	}
 
 	//交易3
-	_, err = txpool.AddTransaction(genTestTx(1, "1", "2", 2, 10))
+	_, err = txpool.AddTransaction(genTestTx(1, "1", "2", 4, 10))
 	if err != nil {
-		t.Fatalf("fail to AddTransaction")
+		t.Fatalf("fail to AddTransaction: %v", err)
 	}
 
 	//txpool.AddTransaction(genContractTx(1, 20000000, "1", contractAddr.GetHexString(), 3, 0, []byte(`{"FuncName": "Test", "Args": [10.123, "ten", [1, 2], {"key":"value", "key2":"value2"}]}`), nil, 0))
@@ -479,20 +481,47 @@ func clearTicker() {
 
 func initContext4Test() error {
 	clear()
-	common.InitConf("../deploy/daily/tas1.ini")
+	f, err := ioutil.TempFile("", "core*.ini")
+	if err != nil {
+		return err
+	}
+	f.WriteString("[instance]\nindex=0\n")
+	f.Close()
+	defer os.Remove(f.Name())
+	common.InitConf(f.Name())
 
 	network.Logger = taslog.GetLoggerByName("p2p" + common.GlobalConf.GetString("client", "index", ""))
-	err := middleware.InitMiddleware()
+	err = middleware.InitMiddleware()
 	if err != nil {
 		return err
 	}
 	BlockChainImpl = nil
 	GroupChainImpl = nil
-	_ = InitCore(false, NewConsensusHelper4Test(groupsig.ID{}))
+	_ = InitCore(false, NewConsensusHelper4Test(groupsig.ID{}), nil)
 	clearTicker()
+	// blocks cast in the same second would have no elapsed time
+	BlockChainImpl.(*FullBlockChain).ts = &timeService4Test{now: time2.TimeToTimeStamp(time.Now())}
 	return nil
 }
 
+// timeService4Test moves one second forward on each Now
+type timeService4Test struct {
+	now time2.TimeStamp
+}
+
+func (ts *timeService4Test) Now() time2.TimeStamp {
+	ts.now = ts.now.Add(1)
+	return ts.now
+}
+
+func (ts *timeService4Test) Since(t time2.TimeStamp) int64 {
+	return ts.now.Since(t)
+}
+
+func (ts *timeService4Test) NowAfter(t time2.TimeStamp) bool {
+	return ts.now.After(t)
+}
+
 func NewConsensusHelper4Test(id groupsig.ID) types.ConsensusHelper {
 	return &ConsensusHelperImpl4Test{ID: id}
 }
@@ -0,0 +1,296 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/taschain/taschain/common"
+	tas_middleware_pb "github.com/taschain/taschain/middleware/pb"
+	"github.com/taschain/taschain/middleware/types"
+)
+
+// The export file is a gzip stream of length-prefixed records. Each record is a one byte kind, a 4 bytes big endian
+// payload length and the payload. The first record is the file header, followed by the blocks in height order. A
+// group is written right after the block of its create height, so that the importer can always verify it
+const (
+	exportMagic   = "TASCHAIN"
+	exportVersion = 1
+
+	recordHeader = 1
+	recordBlock  = 2
+	recordGroup  = 3
+
+	maxRecordSize = 64 * 1024 * 1024
+)
+
+// ErrExportGenesisMismatch is returned if the imported file is exported from a chain of another genesis block
+var ErrExportGenesisMismatch = errors.New("genesis of the export file differs from the local one")
+
+// ChainIOProgress reports the blocks and groups exported or imported so far, and the height of the last block
+type ChainIOProgress func(height uint64, blocks int, groups int)
+
+type chainRecordWriter struct {
+	w   io.Writer
+	buf [5]byte
+}
+
+func (rw *chainRecordWriter) write(kind byte, payload []byte) error {
+	rw.buf[0] = kind
+	binary.BigEndian.PutUint32(rw.buf[1:], uint32(len(payload)))
+	if _, err := rw.w.Write(rw.buf[:]); err != nil {
+		return err
+	}
+	_, err := rw.w.Write(payload)
+	return err
+}
+
+type chainRecordReader struct {
+	r   io.Reader
+	buf [5]byte
+}
+
+// read returns io.EOF if no more records
+func (rr *chainRecordReader) read() (byte, []byte, error) {
+	if _, err := io.ReadFull(rr.r, rr.buf[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return 0, nil, fmt.Errorf("truncated record")
+		}
+		return 0, nil, err
+	}
+	size := binary.BigEndian.Uint32(rr.buf[1:])
+	if size > maxRecordSize {
+		return 0, nil, fmt.Errorf("record too large: %v", size)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(rr.r, payload); err != nil {
+		return 0, nil, fmt.Errorf("truncated record: %v", err)
+	}
+	return rr.buf[0], payload, nil
+}
+
+func encodeExportHeader(genesis common.Hash, from, to uint64) []byte {
+	buf := bytes.NewBuffer([]byte(exportMagic))
+	buf.Write(common.UInt16ToByte(exportVersion))
+	buf.Write(genesis.Bytes())
+	buf.Write(common.UInt64ToByte(from))
+	buf.Write(common.UInt64ToByte(to))
+	return buf.Bytes()
+}
+
+func decodeExportHeader(data []byte) (genesis common.Hash, err error) {
+	if len(data) != len(exportMagic)+2+common.HashLength+16 || string(data[:len(exportMagic)]) != exportMagic {
+		return common.Hash{}, fmt.Errorf("not a chain export file")
+	}
+	data = data[len(exportMagic):]
+	if v := common.ByteToUInt16(data[:2]); v != exportVersion {
+		return common.Hash{}, fmt.Errorf("unsupported export version %v", v)
+	}
+	return common.BytesToHash(data[2 : 2+common.HashLength]), nil
+}
+
+func encodeExportBlock(b *types.Block) ([]byte, error) {
+	header, err := types.MarshalBlockHeader(b.Header)
+	if err != nil {
+		return nil, err
+	}
+	body, err := encodeBlockTransactions(b)
+	if err != nil {
+		return nil, err
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, 4+len(header)+len(body)))
+	buf.Write(common.UInt32ToByte(uint32(len(header))))
+	buf.Write(header)
+	buf.Write(body)
+	return buf.Bytes(), nil
+}
+
+func decodeExportBlock(data []byte) (*types.Block, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("block record too short")
+	}
+	size := common.ByteToUInt32(data[:4])
+	if uint64(size) > uint64(len(data)-4) {
+		return nil, fmt.Errorf("block record too short")
+	}
+	header, err := types.UnMarshalBlockHeader(data[4 : 4+size])
+	if err != nil {
+		return nil, err
+	}
+	txs, err := decodeBlockTransactions(data[4+size:])
+	if err != nil {
+		return nil, err
+	}
+	return &types.Block{Header: header, Transactions: txs}, nil
+}
+
+// ExportChain writes the blocks in the height range [from, to] and the groups they depend on to the writer.
+// The genesis block is never exported, to 0 means the current top
+func (chain *FullBlockChain) ExportChain(w io.Writer, from, to uint64, progress ChainIOProgress) error {
+	if from == 0 {
+		from = 1
+	}
+	if top := chain.Height(); to == 0 || to > top {
+		to = top
+	}
+	if from > to {
+		return fmt.Errorf("invalid export range [%v, %v]", from, to)
+	}
+	genesis := chain.QueryBlockHeaderByHeight(0)
+	if genesis == nil {
+		return fmt.Errorf("genesis block not found")
+	}
+
+	zw := gzip.NewWriter(w)
+	bw := bufio.NewWriter(zw)
+	rw := &chainRecordWriter{w: bw}
+	if err := rw.write(recordHeader, encodeExportHeader(genesis.Hash, from, to)); err != nil {
+		return err
+	}
+
+	// The genesis group is created along with the genesis block
+	groupHeight := uint64(1)
+	writeGroups := func(height uint64) (int, error) {
+		n := 0
+		for {
+			g := GroupChainImpl.GetGroupByHeight(groupHeight)
+			if g == nil || g.Header.CreateHeight > height {
+				return n, nil
+			}
+			data, err := proto.Marshal(types.GroupToPb(g))
+			if err != nil {
+				return n, err
+			}
+			if err := rw.write(recordGroup, data); err != nil {
+				return n, err
+			}
+			groupHeight++
+			n++
+		}
+	}
+
+	blocks, groups := 0, 0
+	for h := from; h <= to; h++ {
+		b := chain.QueryBlockByHeight(h)
+		if b == nil {
+			continue
+		}
+		// Groups created before the range are written first, the importer skips the existing ones
+		n, err := writeGroups(h)
+		if err != nil {
+			return err
+		}
+		groups += n
+		data, err := encodeExportBlock(b)
+		if err != nil {
+			return err
+		}
+		if err := rw.write(recordBlock, data); err != nil {
+			return err
+		}
+		blocks++
+		if progress != nil {
+			progress(h, blocks, groups)
+		}
+	}
+	n, err := writeGroups(to)
+	if err != nil {
+		return err
+	}
+	groups += n
+	if progress != nil {
+		progress(to, blocks, groups)
+	}
+
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// ImportChain reads the blocks and groups exported by ExportChain and adds them to the chain with the full
+// verification. The blocks and groups existing already are skipped
+func (chain *FullBlockChain) ImportChain(r io.Reader, progress ChainIOProgress) error {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("not a chain export file: %v", err)
+	}
+	defer zr.Close()
+	rr := &chainRecordReader{r: bufio.NewReader(zr)}
+
+	kind, data, err := rr.read()
+	if err != nil {
+		return fmt.Errorf("read export header error: %v", err)
+	}
+	if kind != recordHeader {
+		return fmt.Errorf("not a chain export file")
+	}
+	genesis, err := decodeExportHeader(data)
+	if err != nil {
+		return err
+	}
+	if local := chain.QueryBlockHeaderByHeight(0); local == nil || local.Hash != genesis {
+		return ErrExportGenesisMismatch
+	}
+
+	var height uint64
+	blocks, groups := 0, 0
+	for {
+		kind, data, err := rr.read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		switch kind {
+		case recordGroup:
+			pb := new(tas_middleware_pb.Group)
+			if err := proto.Unmarshal(data, pb); err != nil {
+				return fmt.Errorf("decode group error: %v", err)
+			}
+			g := types.PbToGroup(pb)
+			if err := GroupChainImpl.AddGroup(g); err != nil && err != errGroupExist {
+				return fmt.Errorf("add group %v error: %v", common.ToHex(g.ID), err)
+			}
+			groups++
+		case recordBlock:
+			b, err := decodeExportBlock(data)
+			if err != nil {
+				return fmt.Errorf("decode block error: %v", err)
+			}
+			ret, err := chain.addBlockOnChain("", b)
+			if ret != types.AddBlockSucc && ret != types.BlockExisted {
+				return fmt.Errorf("add block %v at height %v error: %v", b.Header.Hash.Hex(), b.Header.Height, err)
+			}
+			height = b.Header.Height
+			blocks++
+		default:
+			return fmt.Errorf("unknown record kind %v", kind)
+		}
+		if progress != nil {
+			progress(height, blocks, groups)
+		}
+	}
+	return nil
+}
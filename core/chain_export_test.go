@@ -0,0 +1,90 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/taschain/taschain/common"
+)
+
+func TestExportBlockCodec(t *testing.T) {
+	b := genBlock(5)
+	b.Header.Hash = b.Header.GenHash()
+	data, err := encodeExportBlock(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := decodeExportBlock(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Header.Hash != b.Header.Hash || decoded.Header.GenHash() != b.Header.Hash {
+		t.Errorf("header mismatch: have %v, want %v", decoded.Header.Hash.Hex(), b.Header.Hash.Hex())
+	}
+	if len(decoded.Transactions) != len(b.Transactions) {
+		t.Fatalf("tx count mismatch: have %v, want %v", len(decoded.Transactions), len(b.Transactions))
+	}
+	for i, tx := range decoded.Transactions {
+		if tx.Hash != b.Transactions[i].Hash {
+			t.Errorf("tx %v mismatch", i)
+		}
+	}
+	if _, err := decodeExportBlock(data[:3]); err == nil {
+		t.Errorf("short block record accepted")
+	}
+}
+
+func TestExportRecords(t *testing.T) {
+	genesis := common.BytesToHash([]byte{1, 2, 3})
+	buf := new(bytes.Buffer)
+	rw := &chainRecordWriter{w: buf}
+	if err := rw.write(recordHeader, encodeExportHeader(genesis, 1, 100)); err != nil {
+		t.Fatal(err)
+	}
+	if err := rw.write(recordGroup, []byte{4, 5, 6}); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	rr := &chainRecordReader{r: bytes.NewReader(data)}
+	kind, payload, err := rr.read()
+	if err != nil || kind != recordHeader {
+		t.Fatalf("read header error: kind %v, err %v", kind, err)
+	}
+	if hash, err := decodeExportHeader(payload); err != nil || hash != genesis {
+		t.Errorf("decode header error: hash %v, err %v", hash.Hex(), err)
+	}
+	kind, payload, err = rr.read()
+	if err != nil || kind != recordGroup || !bytes.Equal(payload, []byte{4, 5, 6}) {
+		t.Errorf("read group error: kind %v, payload %v, err %v", kind, payload, err)
+	}
+	if _, _, err = rr.read(); err != io.EOF {
+		t.Errorf("expect EOF, got %v", err)
+	}
+
+	// Truncated in the middle of the last record
+	rr = &chainRecordReader{r: bytes.NewReader(data[:len(data)-1])}
+	rr.read()
+	if _, _, err = rr.read(); err == nil || err == io.EOF {
+		t.Errorf("truncated record accepted, err %v", err)
+	}
+	if _, err := decodeExportHeader([]byte("not an export file")); err == nil {
+		t.Errorf("invalid header accepted")
+	}
+}
@@ -0,0 +1,76 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+
+	"github.com/taschain/taschain/middleware/notify"
+	"github.com/taschain/taschain/middleware/types"
+)
+
+// ErrReorgTooDeep is returned if switching to the competing fork rewinds more blocks than the max reorg depth
+var ErrReorgTooDeep = errors.New("reorg too deep")
+
+// checkReorgDepth checks whether rewinding the chain to the ancestor is allowed. The ancestor mustn't be below the
+// finalized block, the depth mustn't exceed the max depth unless it's 0, and in pruned mode the state of the ancestor
+// must be kept
+func (chain *FullBlockChain) checkReorgDepth(ancestor *types.BlockHeader) error {
+	if chain.revertsFinalized(ancestor) {
+		finalized := chain.finalized
+		Logger.Warnf("reject fork: ancestor %v-%v is below the finalized block %v-%v", ancestor.Hash.Hex(), ancestor.Height, finalized.Hash.Hex(), finalized.Height)
+		return ErrFinalizedReverted
+	}
+	top := chain.getLatestBlock()
+	if top == nil || top.Height <= ancestor.Height {
+		return nil
+	}
+	depth := top.Height - ancestor.Height
+	if chain.maxReorgDepth > 0 && depth > chain.maxReorgDepth {
+		Logger.Warnf("reject fork: rewinding from %v-%v to ancestor %v-%v, depth %v exceeds the max reorg depth %v", top.Hash.Hex(), top.Height, ancestor.Hash.Hex(), ancestor.Height, depth, chain.maxReorgDepth)
+		return ErrReorgTooDeep
+	}
+	// In pruned mode the state of the ancestor is gone if it's out of the retention window
+	if chain.pruner != nil {
+		if limit := chain.pruner.maxReorgDepth(); limit > 0 && depth > limit {
+			Logger.Warnf("reject fork: rewinding from %v-%v to ancestor %v-%v, depth %v exceeds the state retention %v", top.Hash.Hex(), top.Height, ancestor.Hash.Hex(), ancestor.Height, depth, limit)
+			return ErrReorgTooDeep
+		}
+	}
+	return nil
+}
+
+// rewind resets the top to the given block and returns the removed blocks from the old top
+func (chain *FullBlockChain) rewind(bh *types.BlockHeader) []*types.BlockHeader {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	removed, err := chain.resetTop(bh)
+	if err != nil {
+		Logger.Warnf("reset top to %v error:%v", bh.Hash.Hex(), err)
+	}
+	return removed
+}
+
+// publishReorg notifies the subscribers the blocks removed and added by the reorg, nothing published if no blocks
+// removed
+func (chain *FullBlockChain) publishReorg(ancestor *types.BlockHeader, removed []*types.BlockHeader, added []*types.BlockHeader) {
+	if len(removed) == 0 {
+		return
+	}
+	Logger.Infof("chain reorg at ancestor %v-%v, removed %v blocks, added %v blocks", ancestor.Hash.Hex(), ancestor.Height, len(removed), len(added))
+	notify.BUS.Publish(notify.ChainReorg, &notify.ChainReorgMessage{Ancestor: ancestor, Removed: removed, Added: added})
+}
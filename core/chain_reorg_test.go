@@ -0,0 +1,58 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"testing"
+
+	"github.com/taschain/taschain/middleware/types"
+	"github.com/taschain/taschain/taslog"
+)
+
+func TestCheckReorgDepth(t *testing.T) {
+	initConf4Test(t)
+	Logger = taslog.GetLoggerByIndex(taslog.CoreLogConfig, "")
+	chain := &FullBlockChain{latestBlock: &types.BlockHeader{Height: 100}}
+	ancestor := &types.BlockHeader{Height: 10}
+	if err := chain.checkReorgDepth(ancestor); err != nil {
+		t.Errorf("reorg rejected without limit: %v", err)
+	}
+	chain.maxReorgDepth = 90
+	if err := chain.checkReorgDepth(ancestor); err != nil {
+		t.Errorf("reorg of the max depth rejected: %v", err)
+	}
+	chain.maxReorgDepth = 89
+	if err := chain.checkReorgDepth(ancestor); err != ErrReorgTooDeep {
+		t.Errorf("deep reorg accepted: %v", err)
+	}
+	if err := chain.checkReorgDepth(&types.BlockHeader{Height: 100}); err != nil {
+		t.Errorf("reorg at the top rejected: %v", err)
+	}
+
+	// The ancestor state must be in the retention window in pruned mode
+	chain.maxReorgDepth = 0
+	chain.pruner = &statePruner{retention: 50}
+	if err := chain.checkReorgDepth(&types.BlockHeader{Height: 51}); err != nil {
+		t.Errorf("reorg in the retention window rejected: %v", err)
+	}
+	if err := chain.checkReorgDepth(&types.BlockHeader{Height: 50}); err != ErrReorgTooDeep {
+		t.Errorf("reorg to the pruned state accepted: %v", err)
+	}
+	chain.pruner.archive = true
+	if err := chain.checkReorgDepth(ancestor); err != nil {
+		t.Errorf("reorg rejected in archive mode: %v", err)
+	}
+}
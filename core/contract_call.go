@@ -0,0 +1,138 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/taschain/taschain/common"
+	"github.com/taschain/taschain/middleware/types"
+	"github.com/taschain/taschain/tvm"
+)
+
+// CallResult is the result of the contract call executed without committing the state
+type CallResult struct {
+	Result  *tvm.ExecuteResult
+	Logs    []*types.Log
+	GasUsed uint64
+	Err     *types.TransactionError
+}
+
+// Failed returns whether the call fails
+func (r *CallResult) Failed() bool {
+	return r.Err != nil
+}
+
+// CallContract executes the contract abi call of the transaction against a copy of the state of the given block.
+// The state is never committed, and no gas fee is charged, so the transaction needs neither signature nor valid nonce.
+// The gas limit is capped at the max gas limit of the pool, which is also used if the gas limit is not set
+func (chain *FullBlockChain) CallContract(tx *types.Transaction, header *types.BlockHeader) (*CallResult, error) {
+	if tx.Target == nil {
+		return nil, fmt.Errorf("contract address required")
+	}
+	if tx.Source == nil {
+		tx.Source = &common.Address{}
+	}
+	if tx.GasLimit == 0 || tx.GasLimit > gasLimitMax {
+		tx.GasLimit = gasLimitMax
+	}
+	if header == nil {
+		return nil, fmt.Errorf("block not found")
+	}
+	return chain.callContract(tx, header)
+}
+
+// callContract executes the call on a fresh copy of the state of the header. The tvm is not reentrant, so each call
+// is serialized with block casting and adding, and the lock is held only for the single execution
+func (chain *FullBlockChain) callContract(tx *types.Transaction, header *types.BlockHeader) (*CallResult, error) {
+	accountDB, err := chain.stateAt(header)
+	if err != nil {
+		return nil, err
+	}
+	intriGas, txErr := intrinsicGas(tx)
+	if txErr != nil {
+		return &CallResult{Err: txErr}, nil
+	}
+
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	controller := tvm.NewController(accountDB, chain, header, tx, intriGas, common.GlobalConf.GetString("tvm", "pylib", "lib"), MinerManagerImpl, GroupChainImpl)
+	contract := tvm.LoadContract(*tx.Target)
+	if contract.Code == "" {
+		return &CallResult{Err: types.NewTransactionError(types.TxErrorCodeNoCode, fmt.Sprintf(types.NoCodeErrorMsg, *tx.Target))}, nil
+	}
+	result, logs, txErr := controller.ExecuteABICall(tx.Source, contract, string(tx.Data))
+	return &CallResult{
+		Result:  result,
+		Logs:    logs,
+		GasUsed: tx.GasLimit - controller.GetGasLeft(),
+		Err:     txErr,
+	}, nil
+}
+
+// EstimateGas binary searches the lowest gas limit with which the contract call succeeds at the given block.
+// The gas limit of the transaction is used as the upper bound if set, otherwise the max gas limit of the pool
+func (chain *FullBlockChain) EstimateGas(tx *types.Transaction, header *types.BlockHeader) (uint64, *CallResult, error) {
+	if header == nil {
+		return 0, nil, fmt.Errorf("block not found")
+	}
+	if tx.Target == nil {
+		return 0, nil, fmt.Errorf("contract address required")
+	}
+	if tx.Source == nil {
+		tx.Source = &common.Address{}
+	}
+	hi := uint64(gasLimitMax)
+	if tx.GasLimit > 0 && tx.GasLimit < hi {
+		hi = tx.GasLimit
+	}
+	probe := *tx
+	probe.GasLimit = math.MaxUint64
+	intriGas, _ := intrinsicGas(&probe)
+	lo := intriGas - 1
+
+	// All the probes run on the state of the same block, each on its own copy. The chain lock is only taken by each
+	// single execution, so blocks can be added in between
+	execute := func(gas uint64) (*CallResult, error) {
+		probe := *tx
+		probe.GasLimit = gas
+		return chain.callContract(&probe, header)
+	}
+	// Make sure the call succeeds with the highest gas limit, otherwise it never succeeds
+	last, err := execute(hi)
+	if err != nil {
+		return 0, nil, err
+	}
+	if last.Failed() {
+		return 0, last, nil
+	}
+	for lo+1 < hi {
+		mid := (lo + hi) / 2
+		ret, err := execute(mid)
+		if err != nil {
+			return 0, nil, err
+		}
+		if ret.Failed() {
+			lo = mid
+		} else {
+			hi = mid
+			last = ret
+		}
+	}
+	return hi, last, nil
+}
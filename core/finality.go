@@ -0,0 +1,95 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+
+	"github.com/taschain/taschain/common"
+	"github.com/taschain/taschain/middleware/types"
+)
+
+const (
+	finalizedBlockKey = "bfinalized"
+
+	// maxFinalityWalk bounds the blocks walked back from the top looking for the newly finalized block, so the
+	// finalized block falls behind if too few groups take turns to cast
+	maxFinalityWalk = 100
+)
+
+// ErrFinalizedReverted is returned if the chain is asked to rewind below the finalized block
+var ErrFinalizedReverted = errors.New("can't rewind below the finalized block")
+
+// nextFinalized returns the block newly finalized by adding the top block, or nil if the finalized block doesn't
+// move or the finality is disabled. A block is finalized once the blocks built on it are verified by the given number of distinct groups.
+// Each block on chain has its group signature checked against the group chain before added, so that the group
+// id of the header is trustworthy
+func (chain *FullBlockChain) nextFinalized(top *types.BlockHeader) *types.BlockHeader {
+	if chain.finalityGroups <= 0 {
+		return nil
+	}
+	var finalizedHeight uint64
+	if chain.finalized != nil {
+		finalizedHeight = chain.finalized.Height
+	}
+	groups := make(map[string]struct{})
+	bh := top
+	for i := 0; bh != nil && bh.Height > finalizedHeight && i < maxFinalityWalk; i++ {
+		if len(groups) >= chain.finalityGroups {
+			return bh
+		}
+		groups[string(bh.GroupID)] = struct{}{}
+		bh = chain.queryBlockHeaderByHash(bh.PreHash)
+	}
+	return nil
+}
+
+// saveFinalized adds the finalized block hash into the batch
+func (chain *FullBlockChain) saveFinalized(hash common.Hash) error {
+	return chain.blocks.AddKv(chain.batch, []byte(finalizedBlockKey), hash.Bytes())
+}
+
+// loadFinalized loads the finalized block, the genesis block is returned if it's not stored or no longer on chain
+func (chain *FullBlockChain) loadFinalized() *types.BlockHeader {
+	if bs, err := chain.blocks.Get([]byte(finalizedBlockKey)); err == nil {
+		bh := chain.queryBlockHeaderByHash(common.BytesToHash(bs))
+		if bh != nil && bh.Height <= chain.latestBlock.Height {
+			if onChain := chain.queryBlockHeaderByHeight(bh.Height); onChain != nil && onChain.Hash == bh.Hash {
+				return bh
+			}
+		}
+	}
+	return chain.queryBlockHeaderByHeight(0)
+}
+
+// revertsFinalized checks whether rewinding the chain to the block reverts the finalized block, that's the block is
+// below the finalized block or a different block at the same height
+func (chain *FullBlockChain) revertsFinalized(bh *types.BlockHeader) bool {
+	finalized := chain.finalized
+	if finalized == nil {
+		return false
+	}
+	return bh.Height < finalized.Height || (bh.Height == finalized.Height && bh.Hash != finalized.Hash)
+}
+
+// FinalizedBlock returns the latest finalized block header, which will never be reverted. Nil is returned if the
+// finality is disabled
+func (chain *FullBlockChain) FinalizedBlock() *types.BlockHeader {
+	chain.rwLock.RLock()
+	defer chain.rwLock.RUnlock()
+
+	return chain.finalized
+}
@@ -0,0 +1,104 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/taschain/taschain/middleware/types"
+	"github.com/taschain/taschain/storage/tasdb"
+	"github.com/taschain/taschain/taslog"
+)
+
+func TestNextFinalized(t *testing.T) {
+	initConf4Test(t)
+	Logger = taslog.GetLoggerByIndex(taslog.CoreLogConfig, "")
+	dir, err := ioutil.TempDir("", "finality")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ds, err := tasdb.NewDataSource(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks, err := ds.NewPrefixDatabase("bh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain := &FullBlockChain{blocks: blocks, batch: blocks.CreateLDBBatch(), finalityGroups: 3}
+
+	// The verify groups of the blocks from height 0
+	groups := []string{"genesis", "a", "a", "b", "b", "c", "a", "c", "d"}
+	headers := make([]*types.BlockHeader, len(groups))
+	for i, g := range groups {
+		bh := &types.BlockHeader{Height: uint64(i), GroupID: []byte(g)}
+		if i > 0 {
+			bh.PreHash = headers[i-1].Hash
+		}
+		bh.Hash = bh.GenHash()
+		data, err := types.MarshalBlockHeader(bh)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := chain.saveBlockHeader(bh.Hash, data); err != nil {
+			t.Fatal(err)
+		}
+		headers[i] = bh
+	}
+	if err := chain.batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+
+	if bh := chain.nextFinalized(headers[2]); bh != nil {
+		t.Errorf("block finalized by a single group: %v", bh.Height)
+	}
+	if bh := chain.nextFinalized(headers[6]); bh == nil || bh.Hash != headers[3].Hash {
+		t.Errorf("finalized block by top 6 mismatch: %v", bh)
+	}
+	if bh := chain.nextFinalized(headers[8]); bh == nil || bh.Hash != headers[5].Hash {
+		t.Errorf("finalized block by top 8 mismatch: %v", bh)
+	}
+
+	// The finalized block never moves back and the chain can't rewind below it
+	chain.finalized = headers[5]
+	if bh := chain.nextFinalized(headers[7]); bh != nil {
+		t.Errorf("finalized block moved to %v", bh.Height)
+	}
+	chain.latestBlock = headers[8]
+	if err := chain.checkReorgDepth(headers[4]); err != ErrFinalizedReverted {
+		t.Errorf("fork below the finalized block accepted: %v", err)
+	}
+	if err := chain.checkReorgDepth(headers[5]); err != nil {
+		t.Errorf("fork at the finalized block rejected: %v", err)
+	}
+	sibling := &types.BlockHeader{Height: 5, PreHash: headers[4].Hash, GroupID: []byte("e")}
+	sibling.Hash = sibling.GenHash()
+	if err := chain.checkReorgDepth(sibling); err != ErrFinalizedReverted {
+		t.Errorf("fork replacing the finalized block accepted: %v", err)
+	}
+	if _, err := chain.resetTop(sibling); err != ErrFinalizedReverted {
+		t.Errorf("reset top replacing the finalized block accepted: %v", err)
+	}
+
+	// 0 groups disables the finality
+	chain.finalityGroups = 0
+	if bh := chain.nextFinalized(headers[8]); bh != nil {
+		t.Errorf("block finalized with the finality disabled: %v", bh.Height)
+	}
+}
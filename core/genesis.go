@@ -0,0 +1,232 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/taschain/taschain/common"
+	"github.com/taschain/taschain/consensus/groupsig"
+	time2 "github.com/taschain/taschain/middleware/time"
+	"github.com/taschain/taschain/middleware/types"
+	"github.com/taschain/taschain/storage/account"
+	"github.com/taschain/taschain/storage/tasdb"
+)
+
+// genesisKey is the key of the genesis spec stored in the block database
+const genesisKey = "genesis"
+
+// ErrGenesisMismatch is returned if the given genesis spec differs from the one the chain was initialized with
+var ErrGenesisMismatch = errors.New("genesis mismatch")
+
+var defaultTestTxAccounts = []string{"0xc2f067dba80c53cfdd956f86a61dd3aaf5abbba5609572636719f054247d8103", "0xcad6d60fa8f6330f293f4f57893db78cf660e80d6a41718c7ad75e76795000d4",
+	"0xca789a28069db6f1639b60a8bf1084333358672f65c6d6c2e6d58b69187fe402", "0x94bdb92d329dac69d7f107995a7b666d1092c63eadeae2dd495ab2e554bb155d",
+	"0xb50eea221a1eb061dea7ca20f7b7508c2d9639e3558e69f758380e32624337b5", "0xce59fd5e1c6c99d9990b08ccf685260a2b3a03889de56e91b25878a4bf2f89e9",
+	"0x5d9b2132ec1d2011f488648a8dc24f9b29ca40933ca89d8d19367280dff59a03", "0x5afb7e2617f1dd729ea3557096021e2f4eaa1a9c8fe48d8132b1f6cf13338a8f",
+	"0x30c049d276610da3355f6c11de8623ec6b40fd2a73bb5d647df2ae83c30244bc", "0xa2b7bc555ca535745a7a9c55f9face88fc286a8b316352afc457ffafb40a7478"}
+
+// DefaultGenesis returns the genesis spec of the public network, which is used if no spec given
+func DefaultGenesis() *types.Genesis {
+	tenThousandTas := new(big.Int).SetUint64(common.TAS2RA(10000))
+	adminBalance := new(big.Int).SetUint64(common.TAS2RA(100000000))
+
+	alloc := map[string]types.GenesisAccount{
+		// Administrator accounts
+		"0xf77fa9ca98c46d534bd3d40c3488ed7a85c314db0fd1e79c6ccc75d79bd680bd": {Balance: adminBalance},
+		"0xb055a3ffdc9eeb0c5cf0c1f14507a40bdcbff98c03286b47b673c02d2efe727e": {Balance: adminBalance},
+	}
+	// Accounts of the transaction scripts
+	for _, acc := range defaultTestTxAccounts {
+		alloc[acc] = types.GenesisAccount{Balance: tenThousandTas}
+	}
+	testAccounts := make([]string, len(defaultTestTxAccounts))
+	copy(testAccounts, defaultTestTxAccounts)
+
+	return &types.Genesis{
+		Timestamp:    time.Date(2019, 4, 25, 0, 0, 0, 0, time.UTC).Unix(),
+		ExtraData:    "tas",
+		Alloc:        alloc,
+		TestAccounts: testAccounts,
+		MinerStake:   common.TAS2RA(100),
+		MinerBalance: tenThousandTas,
+	}
+}
+
+func genesisExtraData(genesis *types.Genesis) []byte {
+	if genesis.ChainID == 0 {
+		return []byte(genesis.ExtraData)
+	}
+	// Mix the chain id in so that networks of different ids never share the genesis block
+	return append([]byte(genesis.ExtraData), common.UInt16ToByte(genesis.ChainID)...)
+}
+
+func setupGenesisStateDB(stateDB *account.AccountDB, genesis *types.Genesis, genesisInfo *types.GenesisInfo) []*types.Miner {
+	miners := make([]*types.Miner, 0)
+	for i, member := range genesisInfo.Group.Members {
+		stake, balance := genesis.MinerStake, genesis.MinerBalance
+		if m := genesis.Miner(groupsig.DeserializeID(member).GetHexString()); m != nil {
+			stake, balance = m.Stake, m.Balance
+		}
+		if balance != nil {
+			stateDB.SetBalance(common.BytesToAddress(member), balance)
+		}
+		miners = append(miners, &types.Miner{ID: member, PublicKey: genesisInfo.Pks[i], VrfPublicKey: genesisInfo.VrfPKs[i], Stake: stake})
+	}
+	for addr, acc := range genesis.Alloc {
+		if acc.Balance != nil {
+			stateDB.SetBalance(common.HexToAddress(addr), acc.Balance)
+		}
+	}
+	return miners
+}
+
+// genesisBlock builds the genesis block of the spec upon the empty state,
+// the genesis miners are registered to the miner manager only if register is set
+func (chain *FullBlockChain) genesisBlock(genesis *types.Genesis, stateDB *account.AccountDB, register bool) (*types.Block, error) {
+	genesisInfo := chain.consensusHelper.GenerateGenesisInfo()
+	for _, m := range genesis.Miners {
+		found := false
+		for _, member := range genesisInfo.Group.Members {
+			if groupsig.DeserializeID(member).GetHexString() == m.ID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("genesis miner %v is not a member of the genesis group", m.ID)
+		}
+	}
+
+	extra := genesisExtraData(genesis)
+	block := new(types.Block)
+	block.Header = &types.BlockHeader{
+		Height:     0,
+		ExtraData:  common.Sha256(extra),
+		CurTime:    time2.TimeStamp(genesis.Timestamp),
+		ProveValue: []byte{},
+		Elapsed:    0,
+		TotalQN:    0,
+		Nonce:      common.ChainDataVersion,
+	}
+
+	block.Header.Signature = common.Sha256(extra)
+	block.Header.Random = common.Sha256(append(extra, []byte("_initial_random")...))
+
+	miners := setupGenesisStateDB(stateDB, genesis, genesisInfo)
+	if register {
+		MinerManagerImpl.addGenesesMiner(miners, stateDB)
+	} else {
+		MinerManagerImpl.writeGenesesMiners(miners, stateDB)
+	}
+	stateDB.SetNonce(common.BonusStorageAddress, 1)
+	stateDB.SetNonce(common.HeavyDBAddress, 1)
+	stateDB.SetNonce(common.LightDBAddress, 1)
+	stateDB.SetNonce(common.MinerStakeDetailDBAddress, 1)
+
+	root, err := stateDB.Commit(true)
+	if err != nil {
+		return nil, err
+	}
+	block.Header.StateTree = common.BytesToHash(root.Bytes())
+	block.Header.Hash = block.Header.GenHash()
+	return block, nil
+}
+
+// genesisHash computes the genesis block hash of the spec without touching the chain or the miner manager
+func (chain *FullBlockChain) genesisHash(genesis *types.Genesis) (common.Hash, error) {
+	memDB, err := tasdb.NewMemDatabase()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	stateDB, err := account.NewAccountDB(common.Hash{}, account.NewDatabase(memDB))
+	if err != nil {
+		return common.Hash{}, err
+	}
+	block, err := chain.genesisBlock(genesis, stateDB, false)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return block.Header.Hash, nil
+}
+
+// loadGenesis returns the genesis spec stored on initialization. Chains created before the spec was
+// introduced were all built from the default one, so the default is returned only if the spec is not stored
+func (chain *FullBlockChain) loadGenesis() (*types.Genesis, error) {
+	exists, err := chain.blocks.Has([]byte(genesisKey))
+	if err != nil {
+		return nil, fmt.Errorf("read stored genesis error: %v", err)
+	}
+	if !exists {
+		return DefaultGenesis(), nil
+	}
+	data, err := chain.blocks.Get([]byte(genesisKey))
+	if err != nil {
+		return nil, fmt.Errorf("read stored genesis error: %v", err)
+	}
+	genesis := new(types.Genesis)
+	if err := json.Unmarshal(data, genesis); err != nil {
+		return nil, fmt.Errorf("stored genesis corrupted: %v", err)
+	}
+	return genesis, nil
+}
+
+func (chain *FullBlockChain) storeGenesis(genesis *types.Genesis) error {
+	data, err := json.Marshal(genesis)
+	if err != nil {
+		return err
+	}
+	return chain.blocks.Put([]byte(genesisKey), data)
+}
+
+// checkGenesis makes sure the spec is the one the chain was initialized with
+func (chain *FullBlockChain) checkGenesis(genesis *types.Genesis, stored *types.Genesis) error {
+	header := chain.queryBlockHeaderByHeight(0)
+	if header == nil {
+		return fmt.Errorf("genesis block not found")
+	}
+	hash, err := chain.genesisHash(genesis)
+	if err != nil {
+		return err
+	}
+	if hash != header.Hash {
+		return fmt.Errorf("%v: stored genesis block %v, given %v", ErrGenesisMismatch, header.Hash.Hex(), hash.Hex())
+	}
+	// The chain wide params are not committed in the block
+	given, _ := json.Marshal(genesis)
+	old, _ := json.Marshal(stored)
+	if !bytes.Equal(given, old) {
+		return fmt.Errorf("%v: chain params differ from the stored genesis spec", ErrGenesisMismatch)
+	}
+	return nil
+}
+
+// Genesis returns the genesis spec the chain was initialized with
+func (chain *FullBlockChain) Genesis() *types.Genesis {
+	return chain.genesis
+}
+
+func (chain *FullBlockChain) applyGenesis(genesis *types.Genesis) {
+	chain.genesis = genesis
+	testTxAccounts = make(map[common.Address]bool)
+	for _, acc := range genesis.TestAccounts {
+		testTxAccounts[common.HexToAddress(acc)] = true
+	}
+}
@@ -0,0 +1,140 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/taschain/taschain/common"
+	"github.com/taschain/taschain/consensus/groupsig"
+	"github.com/taschain/taschain/middleware/types"
+	"github.com/taschain/taschain/storage/tasdb"
+	"github.com/taschain/taschain/taslog"
+)
+
+func initConf4Test(t *testing.T) {
+	f, err := ioutil.TempFile("", "core*.ini")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("[instance]\nindex=0\n")
+	f.Close()
+	defer os.Remove(f.Name())
+	common.InitConf(f.Name())
+}
+
+func newGenesisChain4Test(t *testing.T) *FullBlockChain {
+	initConf4Test(t)
+	MinerManagerImpl = &MinerManager{hasNewHeavyMiner: true, heavyMiners: make([]string, 0)}
+	return &FullBlockChain{consensusHelper: NewConsensusHelper4Test(groupsig.ID{})}
+}
+
+func TestDefaultGenesisHash(t *testing.T) {
+	chain := newGenesisChain4Test(t)
+	hash, err := chain.genesisHash(DefaultGenesis())
+	if err != nil {
+		t.Fatalf("compute genesis hash error:%v", err)
+	}
+	// The genesis block built with the previously hardcoded values
+	if hash.Hex() != "0x0d0571180baf4a201c3f9302b086f50cbd2fbd7e896a07cc0987f3eb56d0f01f" {
+		t.Errorf("default genesis hash changed: %v", hash.Hex())
+	}
+	if len(MinerManagerImpl.heavyMiners) != 0 {
+		t.Errorf("computing the genesis hash registered %v heavy miners", len(MinerManagerImpl.heavyMiners))
+	}
+}
+
+func TestGenesisHashDiffers(t *testing.T) {
+	chain := newGenesisChain4Test(t)
+	base, _ := chain.genesisHash(DefaultGenesis())
+
+	withChainID := DefaultGenesis()
+	withChainID.ChainID = 10
+	withAlloc := DefaultGenesis()
+	withAlloc.Alloc["0x0000000000000000000000000000000000000000000000000000000000000001"] = types.GenesisAccount{Balance: big.NewInt(1)}
+	withTime := DefaultGenesis()
+	withTime.Timestamp++
+
+	for i, g := range []*types.Genesis{withChainID, withAlloc, withTime} {
+		hash, err := chain.genesisHash(g)
+		if err != nil {
+			t.Fatalf("compute genesis hash error:%v", err)
+		}
+		if hash == base {
+			t.Errorf("genesis %v should have a different hash", i)
+		}
+	}
+
+	withMiner := DefaultGenesis()
+	withMiner.Miners = []types.GenesisMiner{{ID: "0x01", Stake: 1}}
+	if _, err := chain.genesisHash(withMiner); err == nil {
+		t.Errorf("miner out of the genesis group should be rejected")
+	}
+}
+
+func TestLoadGenesis(t *testing.T) {
+	dir, err := ioutil.TempDir("", "genesis")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ds, err := tasdb.NewDataSource(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks, err := ds.NewPrefixDatabase("bh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain := &FullBlockChain{blocks: blocks}
+
+	// Chains created before the spec was stored fall back to the default
+	if g, err := chain.loadGenesis(); err != nil || g.ExtraData != DefaultGenesis().ExtraData {
+		t.Fatalf("expect the default genesis, got %v %v", g, err)
+	}
+	spec := DefaultGenesis()
+	spec.ChainID = 10
+	if err := chain.storeGenesis(spec); err != nil {
+		t.Fatal(err)
+	}
+	if g, err := chain.loadGenesis(); err != nil || g.ChainID != 10 {
+		t.Fatalf("expect the stored genesis, got %v %v", g, err)
+	}
+	// The corrupted spec must not be replaced by the default silently
+	blocks.Put([]byte(genesisKey), []byte("{"))
+	if _, err := chain.loadGenesis(); err == nil {
+		t.Fatalf("corrupted genesis accepted")
+	}
+}
+
+func TestGasPriceFloorExecution(t *testing.T) {
+	initConf4Test(t)
+	Logger = taslog.GetLoggerByIndex(taslog.CoreLogConfig, "")
+	chain := &FullBlockChain{genesis: &types.Genesis{GasPriceFloor: 10}}
+	executor := NewTVMExecutor(chain)
+	if executor.validateGasPrice(&types.Transaction{Type: types.TransactionTypeTransfer, GasPrice: 9}) {
+		t.Errorf("tx below the gas price floor accepted")
+	}
+	if !executor.validateGasPrice(&types.Transaction{Type: types.TransactionTypeTransfer, GasPrice: 10}) {
+		t.Errorf("tx at the gas price floor rejected")
+	}
+	if !executor.validateGasPrice(&types.Transaction{Type: types.TransactionTypeBonus}) {
+		t.Errorf("bonus tx rejected by the gas price floor")
+	}
+}
@@ -17,12 +17,14 @@ package core
 
 import "github.com/taschain/taschain/middleware/types"
 
-// InitCore initialize the peerManagerImpl, BlockChainImpl and GroupChainImpl
-func InitCore(light bool, helper types.ConsensusHelper) error {
+// InitCore initialize the peerManagerImpl, BlockChainImpl and GroupChainImpl.
+// The genesis block is built from the given genesis spec if the chain is empty, otherwise the spec must match
+// the one the chain was initialized with. The stored or the default spec is used if nil
+func InitCore(light bool, helper types.ConsensusHelper, genesis *types.Genesis) error {
 	light = false
 	initPeerManager()
 	if nil == BlockChainImpl {
-		err := initBlockChain(helper)
+		err := initBlockChain(helper, genesis)
 		if err != nil {
 			return err
 		}
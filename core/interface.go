@@ -77,6 +77,49 @@ type BlockChain interface {
 	// GetTransactionPool return the transaction pool waiting for the block
 	GetTransactionPool() TransactionPool
 
+	// GetLogs returns the logs meeting the filter in the height range
+	GetLogs(f *LogFilter) []*types.Log
+
+	// CallContract executes the contract call against the state of the given block without committing
+	CallContract(tx *types.Transaction, header *types.BlockHeader) (*CallResult, error)
+
+	// EstimateGas returns the lowest gas limit with which the contract call succeeds at the given block
+	EstimateGas(tx *types.Transaction, header *types.BlockHeader) (uint64, *CallResult, error)
+
+	// GetProof returns the merkle proof of the account and its data keys under the state of the given block
+	GetProof(addr common.Address, keys []string, header *types.BlockHeader) (*AccountProof, error)
+
+	// GetReceipt returns the receipt of the transaction on chain, the error tells if it's not available because the
+	// block was fast synced
+	GetReceipt(txHash common.Hash) (*types.Receipt, error)
+
+	// GetTxProof returns the inclusion proof of the transaction in its block
+	GetTxProof(txHash common.Hash) (*TxProof, error)
+
+	// GetReceiptProof returns the inclusion proof of the transaction receipt in its block
+	GetReceiptProof(txHash common.Hash) (*ReceiptProof, error)
+
+	// GetAddressTxs returns the transactions the address involved in from the newest, if the address index enabled
+	GetAddressTxs(addr common.Address, page, limit int) ([]*AddressTx, error)
+
+	// SimulateTransaction executes the transaction on a throwaway state at the top block
+	SimulateTransaction(tx *types.Transaction) (*SimulateResult, error)
+
+	// TraceTransaction re-executes the transaction on the state it was executed on and returns its trace
+	TraceTransaction(txHash common.Hash) (*TxTrace, error)
+
+	// TraceBlock re-executes the transactions of the block and returns their traces
+	TraceBlock(hash common.Hash) ([]*TxTrace, error)
+
+	// StateDiff re-executes the block at the height and returns the changes of the accounts changed by it
+	StateDiff(height uint64) (*StateDiff, error)
+
+	// FinalizedBlock returns the latest finalized block header, the chain never rewinds below it
+	FinalizedBlock() *types.BlockHeader
+
+	// Genesis returns the genesis spec the chain initialized with
+	Genesis() *types.Genesis
+
 	// IsAdjusting means whether need to adjust blockchain, which means there may be a fork
 	IsAdjusting() bool
 
@@ -98,6 +141,9 @@ type BlockChain interface {
 	// GetAccountDBByHeight returns account database with specified block height
 	GetAccountDBByHeight(height uint64) (vm.AccountDB, error)
 
+	// StateAt returns account database at the state of the given block, the error tells if the state is pruned
+	StateAt(header *types.BlockHeader) (vm.AccountDB, error)
+
 	// GetConsensusHelper returns consensus helper reference
 	GetConsensusHelper() types.ConsensusHelper
 
@@ -136,6 +182,12 @@ type TransactionPool interface {
 	// GetTransaction trys to find a transaction from pool by hash and return it
 	GetTransaction(bonus bool, hash common.Hash) *types.Transaction
 
+	// ReplacementGasPrice returns the minimum gas price required to replace a pool transaction with the given gas price
+	ReplacementGasPrice(gasPrice uint64) uint64
+
+	// CheckIntrinsic checks the size, gas price and gas limit of the transaction without the signature and nonce
+	CheckIntrinsic(tx *types.Transaction) error
+
 	// GetTransactionStatus returns the execute result status by hash
 	GetTransactionStatus(hash common.Hash) (uint, error)
 
@@ -163,6 +215,9 @@ type TransactionPool interface {
 	saveReceipts(blockHash common.Hash, receipts types.Receipts) error
 
 	deleteReceipts(txs []common.Hash) error
+
+	// loadJournal recovers the locally submitted transactions persisted before restart
+	loadJournal()
 }
 
 // GroupInfoI is a group management interface
@@ -0,0 +1,298 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+
+	"github.com/taschain/taschain/common"
+	"github.com/taschain/taschain/middleware/types"
+	"github.com/taschain/taschain/storage/tasdb"
+)
+
+const (
+	// Number of blocks whose blooms are merged into one section bloom
+	bloomSectionSize uint64 = 4096
+
+	bloomBlockKeyPrefix   = 'b'
+	bloomSectionKeyPrefix = 's'
+)
+
+var emptyBloom types.Bloom
+
+// bloomIndexedKey marks that the blocks added before the index existed have been indexed
+var bloomIndexedKey = []byte("indexed")
+
+// logIndex persists the log blooms of blocks and sections.
+// Block bloom is the union of the receipts blooms of the block and is only stored for blocks with logs.
+// Section bloom is the union of the block blooms of bloomSectionSize continuous heights, which is never
+// shrunk on block reverting, and may give false positive results the same as the bloom filter itself
+type logIndex struct {
+	db *tasdb.PrefixedDatabase
+}
+
+func newLogIndex(db *tasdb.PrefixedDatabase) *logIndex {
+	return &logIndex{
+		db: db,
+	}
+}
+
+func bloomKey(prefix byte, n uint64) []byte {
+	return append([]byte{prefix}, common.UInt64ToByte(n)...)
+}
+
+// saveBlockBloom adds the bloom of the given receipts at the given height into the batch
+func (idx *logIndex) saveBlockBloom(batch tasdb.Batch, height uint64, receipts types.Receipts) error {
+	bloom := types.CreateBloom(receipts)
+	if bloom == emptyBloom {
+		// Remove the bloom of the reverted block at the same height if any
+		return idx.db.AddKv(batch, bloomKey(bloomBlockKeyPrefix, height), nil)
+	}
+	if err := idx.db.AddKv(batch, bloomKey(bloomBlockKeyPrefix, height), bloom.Bytes()); err != nil {
+		return err
+	}
+	section := height / bloomSectionSize
+	if sb, ok := idx.sectionBloom(section); ok {
+		bloom = types.BytesToBloom(new(big.Int).Or(sb.Big(), bloom.Big()).Bytes())
+	}
+	return idx.db.AddKv(batch, bloomKey(bloomSectionKeyPrefix, section), bloom.Bytes())
+}
+
+// rebuild indexes the blocks from the genesis to the top height with the receipts given by the height, section by
+// section, and marks the index built. It's done once for the chain added before the index existed
+func (idx *logIndex) rebuild(top uint64, receipts func(height uint64) types.Receipts) error {
+	for section := uint64(0); section*bloomSectionSize <= top; section++ {
+		batch := idx.db.CreateLDBBatch()
+		sectionBloom := new(big.Int)
+		for h := section * bloomSectionSize; h < (section+1)*bloomSectionSize && h <= top; h++ {
+			bloom := types.CreateBloom(receipts(h))
+			if bloom == emptyBloom {
+				continue
+			}
+			if err := idx.db.AddKv(batch, bloomKey(bloomBlockKeyPrefix, h), bloom.Bytes()); err != nil {
+				return err
+			}
+			sectionBloom.Or(sectionBloom, bloom.Big())
+		}
+		if sectionBloom.Sign() != 0 {
+			if err := idx.db.AddKv(batch, bloomKey(bloomSectionKeyPrefix, section), types.BytesToBloom(sectionBloom.Bytes()).Bytes()); err != nil {
+				return err
+			}
+		}
+		if err := batch.Write(); err != nil {
+			return err
+		}
+	}
+	return idx.db.Put(bloomIndexedKey, []byte{1})
+}
+
+func (idx *logIndex) indexed() bool {
+	ok, _ := idx.db.Has(bloomIndexedKey)
+	return ok
+}
+
+// deleteBlockBloom adds the deletion of the block bloom at the given height into the batch
+func (idx *logIndex) deleteBlockBloom(batch tasdb.Batch, height uint64) error {
+	return idx.db.AddKv(batch, bloomKey(bloomBlockKeyPrefix, height), nil)
+}
+
+func (idx *logIndex) getBloom(key []byte) (types.Bloom, bool) {
+	bs, err := idx.db.Get(key)
+	if err != nil || len(bs) != types.BloomByteLength {
+		return emptyBloom, false
+	}
+	return types.BytesToBloom(bs), true
+}
+
+// blockBloom returns the bloom of the block at the given height, false returned if the block has no logs
+func (idx *logIndex) blockBloom(height uint64) (types.Bloom, bool) {
+	return idx.getBloom(bloomKey(bloomBlockKeyPrefix, height))
+}
+
+// sectionBloom returns the bloom of the given section, false returned if no logs in the section
+func (idx *logIndex) sectionBloom(section uint64) (types.Bloom, bool) {
+	return idx.getBloom(bloomKey(bloomSectionKeyPrefix, section))
+}
+
+// LogFilter defines the criteria of log query
+type LogFilter struct {
+	FromHeight uint64
+	ToHeight   uint64
+
+	// Logs emitted by any of the addresses matches, empty means any address
+	Addresses []common.Address
+
+	// Topics are matched by position, each position matches any of the given topics, empty means any topic
+	Topics [][]common.Hash
+}
+
+func bloomContainsAny(bloom types.Bloom, items [][]byte) bool {
+	if len(items) == 0 {
+		return true
+	}
+	bin := bloom.Big()
+	for _, item := range items {
+		cmp := types.Bloom9(item)
+		if new(big.Int).And(bin, cmp).Cmp(cmp) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// bloomMatch checks whether the bloom may contain the logs required by the filter
+func (f *LogFilter) bloomMatch(bloom types.Bloom) bool {
+	addrs := make([][]byte, len(f.Addresses))
+	for i, addr := range f.Addresses {
+		addrs[i] = addr.Bytes()
+	}
+	if !bloomContainsAny(bloom, addrs) {
+		return false
+	}
+	for _, sub := range f.Topics {
+		topics := make([][]byte, len(sub))
+		for i, topic := range sub {
+			topics[i] = topic.Bytes()
+		}
+		if !bloomContainsAny(bloom, topics) {
+			return false
+		}
+	}
+	return true
+}
+
+// logMatch checks whether the log meets the filter
+func (f *LogFilter) logMatch(log *types.Log) bool {
+	if len(f.Addresses) > 0 {
+		found := false
+		for _, addr := range f.Addresses {
+			if addr == log.Address {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(f.Topics) > len(log.Topics) {
+		return false
+	}
+	for i, sub := range f.Topics {
+		if len(sub) == 0 {
+			continue
+		}
+		found := false
+		for _, topic := range sub {
+			if topic == log.Topics[i] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// GetLogs returns the logs meeting the filter in the height range. Sections and blocks whose blooms
+// don't match the filter are skipped without loading receipts
+func (chain *FullBlockChain) GetLogs(f *LogFilter) []*types.Log {
+	logs := make([]*types.Log, 0)
+	if f.FromHeight > f.ToHeight {
+		return logs
+	}
+	height := f.FromHeight
+	for height <= f.ToHeight {
+		section := height / bloomSectionSize
+		sectionEnd := (section+1)*bloomSectionSize - 1
+		if sectionEnd > f.ToHeight {
+			sectionEnd = f.ToHeight
+		}
+		if sb, ok := chain.logIndex.sectionBloom(section); ok && f.bloomMatch(sb) {
+			for h := height; h <= sectionEnd; h++ {
+				if bb, ok := chain.logIndex.blockBloom(h); ok && f.bloomMatch(bb) {
+					logs = append(logs, chain.blockLogs(h, f)...)
+				}
+			}
+		}
+		if sectionEnd == f.ToHeight {
+			break
+		}
+		height = sectionEnd + 1
+	}
+	return logs
+}
+
+// rebuildLogIndex indexes the logs of the blocks added before the log index existed, otherwise GetLogs would find
+// nothing in them. It's done on the first startup with the index and takes a while for a long chain
+func (chain *FullBlockChain) rebuildLogIndex() error {
+	if chain.logIndex.indexed() {
+		return nil
+	}
+	top := uint64(0)
+	if chain.latestBlock != nil {
+		top = chain.latestBlock.Height
+	}
+	if top > 0 {
+		Logger.Infof("building the log index of blocks 0-%v, it may take a while", top)
+	}
+	return chain.logIndex.rebuild(top, func(height uint64) types.Receipts {
+		receipts := make(types.Receipts, 0)
+		bh := chain.queryBlockHeaderByHeight(height)
+		if bh == nil {
+			return receipts
+		}
+		b := chain.queryBlockByHash(bh.Hash)
+		if b == nil {
+			return receipts
+		}
+		for _, tx := range b.Transactions {
+			if receipt := chain.transactionPool.GetReceipt(tx.Hash); receipt != nil {
+				receipts = append(receipts, receipt)
+			}
+		}
+		return receipts
+	})
+}
+
+// blockLogs returns the logs of the block at the given height meeting the filter
+func (chain *FullBlockChain) blockLogs(height uint64, f *LogFilter) []*types.Log {
+	logs := make([]*types.Log, 0)
+	b := chain.QueryBlockByHeight(height)
+	if b == nil {
+		return logs
+	}
+	for _, tx := range b.Transactions {
+		receipt := chain.transactionPool.GetReceipt(tx.Hash)
+		if receipt == nil {
+			continue
+		}
+		for i, log := range receipt.Logs {
+			if !f.logMatch(log) {
+				continue
+			}
+			log.BlockNumber = b.Header.Height
+			log.BlockHash = b.Header.Hash
+			log.TxHash = receipt.TxHash
+			log.TxIndex = uint(receipt.TxIndex)
+			log.Index = uint(i)
+			logs = append(logs, log)
+		}
+	}
+	return logs
+}
@@ -0,0 +1,111 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/taschain/taschain/common"
+	"github.com/taschain/taschain/middleware/types"
+	"github.com/taschain/taschain/storage/tasdb"
+)
+
+func TestLogFilterMatch(t *testing.T) {
+	contract := common.BytesToAddress(common.Sha256([]byte("contract")))
+	other := common.BytesToAddress(common.Sha256([]byte("other")))
+	event := common.BytesToHash(common.Sha256([]byte("transfer")))
+	index := common.BytesToHash(common.Sha256([]byte("1")))
+
+	log := &types.Log{Address: contract, Topics: []common.Hash{event, index}}
+	bloom := types.CreateBloom(types.Receipts{{Logs: []*types.Log{log}}})
+
+	filters := []struct {
+		filter *LogFilter
+		match  bool
+	}{
+		{&LogFilter{}, true},
+		{&LogFilter{Addresses: []common.Address{contract}}, true},
+		{&LogFilter{Addresses: []common.Address{other}}, false},
+		{&LogFilter{Addresses: []common.Address{other, contract}}, true},
+		{&LogFilter{Topics: [][]common.Hash{{event}}}, true},
+		{&LogFilter{Topics: [][]common.Hash{{}, {index}}}, true},
+		{&LogFilter{Topics: [][]common.Hash{{index}}}, false},
+		{&LogFilter{Topics: [][]common.Hash{{event}, {index}, {index}}}, false},
+	}
+	for i, f := range filters {
+		if f.filter.logMatch(log) != f.match {
+			t.Errorf("log match error at %v, expect %v", i, f.match)
+		}
+		// Bloom may give false positive, but never false negative
+		if f.match && !f.filter.bloomMatch(bloom) {
+			t.Errorf("bloom match error at %v", i)
+		}
+	}
+	if (&LogFilter{Addresses: []common.Address{other}}).bloomMatch(bloom) {
+		t.Errorf("bloom should not match other address")
+	}
+}
+
+func TestLogIndexRebuild(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log_index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ds, err := tasdb.NewDataSource(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := ds.NewPrefixDatabase("lb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx := newLogIndex(db)
+	if idx.indexed() {
+		t.Fatal("new index marked indexed")
+	}
+
+	contract := common.BytesToAddress(common.Sha256([]byte("contract")))
+	logHeights := map[uint64]bool{3: true, bloomSectionSize + 1: true}
+	top := bloomSectionSize + 10
+	err = idx.rebuild(top, func(height uint64) types.Receipts {
+		if logHeights[height] {
+			return types.Receipts{{Logs: []*types.Log{{Address: contract}}}}
+		}
+		return types.Receipts{{}}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !idx.indexed() {
+		t.Errorf("index not marked indexed")
+	}
+
+	f := &LogFilter{Addresses: []common.Address{contract}}
+	for h := uint64(0); h <= top; h++ {
+		bloom, ok := idx.blockBloom(h)
+		if ok != logHeights[h] || (ok && !f.bloomMatch(bloom)) {
+			t.Errorf("block bloom at %v: %v", h, ok)
+		}
+	}
+	for section := uint64(0); section <= 1; section++ {
+		if sb, ok := idx.sectionBloom(section); !ok || !f.bloomMatch(sb) {
+			t.Errorf("section bloom %v not built", section)
+		}
+	}
+}
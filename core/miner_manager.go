@@ -241,16 +241,24 @@ func (mm *MinerManager) activateAndAddStakeMiner(miner *types.Miner, accountdb v
 }
 
 func (mm *MinerManager) addGenesesMiner(miners []*types.Miner, accountdb vm.AccountDB) {
+	mm.heavyMiners = append(mm.heavyMiners, mm.writeGenesesMiners(miners, accountdb)...)
+	mm.hasNewHeavyMiner = true
+}
+
+// writeGenesesMiners writes the genesis miners into the state only and returns the ids of the new heavy miners,
+// the miner manager itself is left untouched
+func (mm *MinerManager) writeGenesesMiners(miners []*types.Miner, accountdb vm.AccountDB) []string {
 	dbh := mm.getMinerDatabase(types.MinerTypeHeavy)
 	dbl := mm.getMinerDatabase(types.MinerTypeLight)
 
+	heavies := make([]string, 0, len(miners))
 	for _, miner := range miners {
 		if accountdb.GetData(dbh, string(miner.ID)) == nil {
 			miner.Type = types.MinerTypeHeavy
 			data, _ := msgpack.Marshal(miner)
 			accountdb.SetData(dbh, string(miner.ID), data)
 			mm.AddStakeDetail(miner.ID, miner, miner.Stake, accountdb)
-			mm.heavyMiners = append(mm.heavyMiners, groupsig.DeserializeID(miner.ID).GetHexString())
+			heavies = append(heavies, groupsig.DeserializeID(miner.ID).GetHexString())
 			mm.updateMinerCount(types.MinerTypeHeavy, minerCountIncrease, accountdb)
 		}
 		if accountdb.GetData(dbl, string(miner.ID)) == nil {
@@ -261,7 +269,7 @@ func (mm *MinerManager) addGenesesMiner(miners []*types.Miner, accountdb vm.Acco
 			mm.updateMinerCount(types.MinerTypeLight, minerCountIncrease, accountdb)
 		}
 	}
-	mm.hasNewHeavyMiner = true
+	return heavies
 }
 
 func (mm *MinerManager) removeMiner(id []byte, ttype byte, accountdb vm.AccountDB) {
@@ -17,6 +17,8 @@ package core
 
 import (
 	"container/heap"
+	"math"
+	"math/big"
 	"sort"
 	"sync"
 
@@ -24,37 +26,165 @@ import (
 	"github.com/taschain/taschain/middleware/types"
 )
 
+// nonceHeap is a min-heap of transaction nonces
+type nonceHeap []uint64
+
+func (h nonceHeap) Len() int           { return len(h) }
+func (h nonceHeap) Less(i, j int) bool { return h[i] < h[j] }
+func (h nonceHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *nonceHeap) Push(x interface{}) {
+	*h = append(*h, x.(uint64))
+}
+
+func (h *nonceHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[0 : n-1]
+	return x
+}
+
+// sortedTxsByNonce stores the transactions of one account indexed and ordered by nonce
+type sortedTxsByNonce struct {
+	items map[uint64]*types.Transaction
+	index *nonceHeap
+}
+
+func newSortedTxsByNonce() *sortedTxsByNonce {
+	return &sortedTxsByNonce{
+		items: make(map[uint64]*types.Transaction),
+		index: new(nonceHeap),
+	}
+}
+
+func (s *sortedTxsByNonce) len() int {
+	return len(s.items)
+}
+
+func (s *sortedTxsByNonce) get(nonce uint64) *types.Transaction {
+	return s.items[nonce]
+}
+
+// put inserts the transaction, replacing the one with the same nonce if exists
+func (s *sortedTxsByNonce) put(tx *types.Transaction) {
+	if _, ok := s.items[tx.Nonce]; !ok {
+		heap.Push(s.index, tx.Nonce)
+	}
+	s.items[tx.Nonce] = tx
+}
+
+func (s *sortedTxsByNonce) remove(nonce uint64) bool {
+	if _, ok := s.items[nonce]; !ok {
+		return false
+	}
+	for i, n := range *s.index {
+		if n == nonce {
+			heap.Remove(s.index, i)
+			break
+		}
+	}
+	delete(s.items, nonce)
+	return true
+}
+
+// first returns the transaction with the lowest nonce
+func (s *sortedTxsByNonce) first() *types.Transaction {
+	if s.index.Len() == 0 {
+		return nil
+	}
+	return s.items[(*s.index)[0]]
+}
+
+// flatten returns all the transactions sorted by nonce
+func (s *sortedTxsByNonce) flatten() []*types.Transaction {
+	txs := make([]*types.Transaction, 0, len(s.items))
+	for _, tx := range s.items {
+		txs = append(txs, tx)
+	}
+	sort.Slice(txs, func(i, j int) bool {
+		return txs[i].Nonce < txs[j].Nonce
+	})
+	return txs
+}
+
+// pendingHeads is a max-heap of gas price over the next executable transaction of each account
+type pendingHeads []*types.Transaction
+
+func (h pendingHeads) Len() int           { return len(h) }
+func (h pendingHeads) Less(i, j int) bool { return h[i].GasPrice > h[j].GasPrice }
+func (h pendingHeads) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *pendingHeads) Push(x interface{}) {
+	*h = append(*h, x.(*types.Transaction))
+}
+
+func (h *pendingHeads) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[0 : n-1]
+	return x
+}
+
+// simpleContainer holds the non-bonus transactions of the pool. Transactions of each account are
+// split into pending ones, whose nonces continuously follow the account nonce in the latest state
+// and thus can be executed, and queued ones which have to wait for the nonce gap to be filled
 type simpleContainer struct {
-	limit  int
-	txs    types.PriorityTransactions
-	txsMap map[common.Hash]*types.Transaction
+	limit     int
+	priceBump uint64 // Minimum price bump percentage to replace a transaction with the same nonce
+	accounts  AccountRepository
+
+	pending map[common.Address]*sortedTxsByNonce
+	queue   map[common.Address]*sortedTxsByNonce
+	txsMap  map[common.Hash]*types.Transaction
 
 	lock sync.RWMutex
 }
 
-func newSimpleContainer(l int) *simpleContainer {
-	c := &simpleContainer{
-		lock:   sync.RWMutex{},
-		limit:  l,
-		txsMap: map[common.Hash]*types.Transaction{},
-		txs:    types.PriorityTransactions{},
+func newSimpleContainer(l int, priceBump uint64, accounts AccountRepository) *simpleContainer {
+	return &simpleContainer{
+		lock:      sync.RWMutex{},
+		limit:     l,
+		priceBump: priceBump,
+		accounts:  accounts,
+		pending:   make(map[common.Address]*sortedTxsByNonce),
+		queue:     make(map[common.Address]*sortedTxsByNonce),
+		txsMap:    map[common.Hash]*types.Transaction{},
 	}
-	heap.Init(&c.txs)
-	return c
+}
+
+// replacementPrice returns the minimum gas price to replace a transaction with the given gas price, capped at the max
+// uint64 if it overflows
+func (c *simpleContainer) replacementPrice(gasPrice uint64) uint64 {
+	price := new(big.Int).SetUint64(gasPrice)
+	price.Mul(price, new(big.Int).SetUint64(c.priceBump))
+	price.Div(price, big.NewInt(100))
+	price.Add(price, new(big.Int).SetUint64(gasPrice))
+	price.Add(price, big.NewInt(1))
+	if !price.IsUint64() {
+		return math.MaxUint64
+	}
+	return price.Uint64()
 }
 
 func (c *simpleContainer) Len() int {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
 
-	return c.txs.Len()
+	return len(c.txsMap)
 }
 
-func (c *simpleContainer) sort() {
-	c.lock.Lock()
-	defer c.lock.Unlock()
+// pendingLen returns the number of executable transactions
+func (c *simpleContainer) pendingLen() int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
 
-	sort.Sort(c.txs)
+	cnt := 0
+	for _, list := range c.pending {
+		cnt += list.len()
+	}
+	return cnt
 }
 
 func (c *simpleContainer) contains(key common.Hash) bool {
@@ -71,41 +201,140 @@ func (c *simpleContainer) get(key common.Hash) *types.Transaction {
 	return c.txsMap[key]
 }
 
+// asSlice returns at most limit transactions, the pending ones come first
 func (c *simpleContainer) asSlice(limit int) []*types.Transaction {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
 
 	size := limit
-	if c.txs.Len() < size {
-		size = c.txs.Len()
+	if len(c.txsMap) < size {
+		size = len(c.txsMap)
+	}
+	txs := make([]*types.Transaction, 0, size)
+	for _, lists := range []map[common.Address]*sortedTxsByNonce{c.pending, c.queue} {
+		for _, list := range lists {
+			for _, tx := range list.items {
+				if len(txs) >= size {
+					return txs
+				}
+				txs = append(txs, tx)
+			}
+		}
 	}
-	txs := make([]*types.Transaction, size)
-	copy(txs, c.txs[:size])
 	return txs
 }
 
-func (c *simpleContainer) push(tx *types.Transaction) {
+// getByNonce returns the transaction with the given source and nonce
+func (c *simpleContainer) getByNonce(source common.Address, nonce uint64) *types.Transaction {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.getByNonceLocked(source, nonce)
+}
+
+func (c *simpleContainer) getByNonceLocked(source common.Address, nonce uint64) *types.Transaction {
+	if list := c.pending[source]; list != nil {
+		if tx := list.get(nonce); tx != nil {
+			return tx
+		}
+	}
+	if list := c.queue[source]; list != nil {
+		return list.get(nonce)
+	}
+	return nil
+}
+
+// push adds the transaction into the container. If there is already a transaction with the same
+// source and nonce, the new one replaces it only when paying enough higher gas price, and the replaced
+// one is returned
+func (c *simpleContainer) push(tx *types.Transaction) (replaced *types.Transaction, err error) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
 	if c.txsMap[tx.Hash] != nil {
+		return nil, nil
+	}
+	if tx.Source == nil {
+		return nil, ErrNil
+	}
+	source := *tx.Source
+	if old := c.getByNonceLocked(source, tx.Nonce); old != nil {
+		if IsTestTransaction(tx) || tx.GasPrice < c.replacementPrice(old.GasPrice) {
+			return nil, ErrReplaceUnderpriced
+		}
+		c.replaceLocked(old, tx)
+		return old, nil
+	}
+	if len(c.txsMap) >= c.limit && !c.evictQueued(tx.GasPrice) {
+		return nil, ErrPoolFull
+	}
+
+	pending := c.pending[source]
+	if IsTestTransaction(tx) {
+		// Nonce of testing transaction is never checked, take it as executable directly
+		if pending == nil {
+			pending = newSortedTxsByNonce()
+			c.pending[source] = pending
+		}
+		pending.put(tx)
+		c.txsMap[tx.Hash] = tx
+		return nil, nil
+	}
+
+	next := c.accounts.GetNonce(source) + 1
+	if pending != nil && pending.len() > 0 {
+		next = pending.first().Nonce + uint64(pending.len())
+	}
+	c.txsMap[tx.Hash] = tx
+	if tx.Nonce == next {
+		if pending == nil {
+			pending = newSortedTxsByNonce()
+			c.pending[source] = pending
+		}
+		pending.put(tx)
+		c.promoteLocked(source)
+		return nil, nil
+	}
+
+	queue := c.queue[source]
+	if queue == nil {
+		queue = newSortedTxsByNonce()
+		c.queue[source] = queue
+	}
+	queue.put(tx)
+	if tx.Nonce < next {
+		// The account nonce goes back, which happens when the chain top is reset
+		c.reorganizeLocked(source)
+	}
+	return nil, nil
+}
+
+// replaceLocked puts the new transaction at the position of the old one with the same nonce
+func (c *simpleContainer) replaceLocked(old *types.Transaction, tx *types.Transaction) {
+	delete(c.txsMap, old.Hash)
+	c.txsMap[tx.Hash] = tx
+	if list := c.pending[*old.Source]; list != nil && list.get(old.Nonce) == old {
+		list.put(tx)
 		return
 	}
+	c.queue[*old.Source].put(tx)
+}
 
-	if c.txs.Len() >= c.limit {
-		for i, oldTx := range c.txs {
-			if tx.GasPrice >= oldTx.GasPrice {
-				delete(c.txsMap, oldTx.Hash)
-				c.txs[i] = tx
-				c.txsMap[tx.Hash] = tx
-				heap.Fix(&c.txs, i)
-				break
+// evictQueued drops the cheapest queued transaction if it is not more expensive than the given price
+func (c *simpleContainer) evictQueued(gasPrice uint64) bool {
+	var victim *types.Transaction
+	for _, list := range c.queue {
+		for _, tx := range list.items {
+			if victim == nil || tx.GasPrice < victim.GasPrice {
+				victim = tx
 			}
 		}
-	} else {
-		heap.Push(&c.txs, tx)
-		c.txsMap[tx.Hash] = tx
 	}
+	if victim == nil || victim.GasPrice > gasPrice {
+		return false
+	}
+	c.removeLocked(victim.Hash)
+	return true
 }
 
 func (c *simpleContainer) remove(key common.Hash) {
@@ -114,14 +343,175 @@ func (c *simpleContainer) remove(key common.Hash) {
 	}
 	c.lock.Lock()
 	defer c.lock.Unlock()
-	if c.txsMap[key] == nil {
+
+	c.removeLocked(key)
+}
+
+func (c *simpleContainer) removeLocked(key common.Hash) {
+	tx := c.txsMap[key]
+	if tx == nil {
 		return
 	}
 	delete(c.txsMap, key)
-	for i, tx := range c.txs {
-		if tx.Hash == key {
-			heap.Remove(&c.txs, i)
-			break
+	source := *tx.Source
+
+	if list := c.queue[source]; list != nil && list.get(tx.Nonce) == tx {
+		list.remove(tx.Nonce)
+		if list.len() == 0 {
+			delete(c.queue, source)
+		}
+		return
+	}
+	list := c.pending[source]
+	if list == nil || list.get(tx.Nonce) != tx {
+		return
+	}
+	first := list.first()
+	list.remove(tx.Nonce)
+	// Removing from the middle leaves a nonce gap, the transactions behind can't be executed any more
+	if first != tx && !IsTestTransaction(tx) {
+		for _, t := range list.flatten() {
+			if t.Nonce > tx.Nonce {
+				list.remove(t.Nonce)
+				c.putQueueLocked(t)
+			}
+		}
+	}
+	if list.len() == 0 {
+		delete(c.pending, source)
+	}
+}
+
+func (c *simpleContainer) putQueueLocked(tx *types.Transaction) {
+	queue := c.queue[*tx.Source]
+	if queue == nil {
+		queue = newSortedTxsByNonce()
+		c.queue[*tx.Source] = queue
+	}
+	queue.put(tx)
+}
+
+// promote drops the transactions of the given accounts which are already stale according to the latest
+// state, and moves the queued transactions which become executable into the pending list
+func (c *simpleContainer) promote(accounts []common.Address) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, addr := range accounts {
+		c.promoteLocked(addr)
+	}
+}
+
+func (c *simpleContainer) promoteLocked(addr common.Address) {
+	stateNonce := c.accounts.GetNonce(addr)
+	pending := c.pending[addr]
+	queue := c.queue[addr]
+	if pending != nil && IsTestTransaction(pending.first()) {
+		return
+	}
+
+	for _, list := range []*sortedTxsByNonce{pending, queue} {
+		if list == nil {
+			continue
+		}
+		for tx := list.first(); tx != nil && tx.Nonce <= stateNonce && !IsTestTransaction(tx); tx = list.first() {
+			list.remove(tx.Nonce)
+			delete(c.txsMap, tx.Hash)
+		}
+	}
+
+	next := stateNonce + 1
+	if pending != nil && pending.len() > 0 {
+		if pending.first().Nonce != next {
+			c.reorganizeLocked(addr)
+			return
+		}
+		next += uint64(pending.len())
+	}
+	if queue != nil {
+		for tx := queue.get(next); tx != nil; tx = queue.get(next) {
+			if pending == nil {
+				pending = newSortedTxsByNonce()
+				c.pending[addr] = pending
+			}
+			queue.remove(next)
+			pending.put(tx)
+			next++
+		}
+	}
+	if pending != nil && pending.len() == 0 {
+		delete(c.pending, addr)
+	}
+	if queue != nil && queue.len() == 0 {
+		delete(c.queue, addr)
+	}
+}
+
+// reorganizeLocked rebuilds the pending and queued lists of the account from scratch
+func (c *simpleContainer) reorganizeLocked(addr common.Address) {
+	txs := make([]*types.Transaction, 0)
+	if list := c.pending[addr]; list != nil {
+		txs = append(txs, list.flatten()...)
+	}
+	if list := c.queue[addr]; list != nil {
+		txs = append(txs, list.flatten()...)
+	}
+	delete(c.pending, addr)
+	delete(c.queue, addr)
+	sort.Slice(txs, func(i, j int) bool {
+		return txs[i].Nonce < txs[j].Nonce
+	})
+
+	pending := newSortedTxsByNonce()
+	queue := newSortedTxsByNonce()
+	next := c.accounts.GetNonce(addr) + 1
+	for _, tx := range txs {
+		switch {
+		case IsTestTransaction(tx):
+			pending.put(tx)
+		case tx.Nonce < next:
+			delete(c.txsMap, tx.Hash)
+		case tx.Nonce == next:
+			pending.put(tx)
+			next++
+		default:
+			queue.put(tx)
+		}
+	}
+	if pending.len() > 0 {
+		c.pending[addr] = pending
+	}
+	if queue.len() > 0 {
+		c.queue[addr] = queue
+	}
+}
+
+// forEachPending traverses the pending transactions in nonce order for each account, while the accounts
+// are prioritized by the gas price of their next transaction. It stops when f returns false
+func (c *simpleContainer) forEachPending(f func(tx *types.Transaction) bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	rests := make(map[common.Address][]*types.Transaction, len(c.pending))
+	heads := make(pendingHeads, 0, len(c.pending))
+	for addr, list := range c.pending {
+		txs := list.flatten()
+		if len(txs) == 0 {
+			continue
+		}
+		heads = append(heads, txs[0])
+		rests[addr] = txs[1:]
+	}
+	heap.Init(&heads)
+
+	for heads.Len() > 0 {
+		tx := heap.Pop(&heads).(*types.Transaction)
+		if !f(tx) {
+			return
+		}
+		if rest := rests[*tx.Source]; len(rest) > 0 {
+			heap.Push(&heads, rest[0])
+			rests[*tx.Source] = rest[1:]
 		}
 	}
 }
@@ -0,0 +1,177 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/taschain/taschain/common"
+	"github.com/taschain/taschain/middleware/types"
+)
+
+type nonceRepository map[common.Address]uint64
+
+func (r nonceRepository) GetBalance(address common.Address) *big.Int {
+	return big.NewInt(0)
+}
+
+func (r nonceRepository) GetNonce(address common.Address) uint64 {
+	return r[address]
+}
+
+func genContainerTx(source common.Address, nonce uint64, gasPrice uint64) *types.Transaction {
+	tx := &types.Transaction{
+		Nonce:    nonce,
+		GasPrice: gasPrice,
+		GasLimit: 3000,
+		Source:   &source,
+	}
+	tx.Hash = tx.GenHash()
+	return tx
+}
+
+func collectPending(c *simpleContainer) []*types.Transaction {
+	txs := make([]*types.Transaction, 0)
+	c.forEachPending(func(tx *types.Transaction) bool {
+		txs = append(txs, tx)
+		return true
+	})
+	return txs
+}
+
+func TestContainerPendingAndQueue(t *testing.T) {
+	addr := common.BytesToAddress(common.Sha256([]byte("1")))
+	repo := nonceRepository{addr: 0}
+	c := newSimpleContainer(100, 10, repo)
+
+	for _, nonce := range []uint64{1, 2, 4, 5} {
+		if _, err := c.push(genContainerTx(addr, nonce, 10)); err != nil {
+			t.Fatalf("push error:%v", err)
+		}
+	}
+	if c.pendingLen() != 2 || c.Len() != 4 {
+		t.Fatalf("pending size error, expect 2, got %v", c.pendingLen())
+	}
+	if _, err := c.push(genContainerTx(addr, 2, 11)); err != ErrReplaceUnderpriced {
+		t.Fatalf("expect replace underpriced error, got %v", err)
+	}
+
+	// Fill the gap, queued transactions should be promoted
+	if _, err := c.push(genContainerTx(addr, 3, 10)); err != nil {
+		t.Fatalf("push error:%v", err)
+	}
+	if c.pendingLen() != 5 {
+		t.Fatalf("pending size error, expect 5, got %v", c.pendingLen())
+	}
+
+	// Block contains nonce 1 and 2 added on chain
+	repo[addr] = 2
+	c.promote([]common.Address{addr})
+	if c.Len() != 3 || c.pendingLen() != 3 {
+		t.Fatalf("promote error, size %v, pending %v", c.Len(), c.pendingLen())
+	}
+
+	// Evicting from the middle makes the rest not executable
+	c.remove(c.getByNonce(addr, 4).Hash)
+	if c.pendingLen() != 1 || c.Len() != 2 {
+		t.Fatalf("remove error, size %v, pending %v", c.Len(), c.pendingLen())
+	}
+}
+
+func TestContainerPackOrder(t *testing.T) {
+	addr1 := common.BytesToAddress(common.Sha256([]byte("1")))
+	addr2 := common.BytesToAddress(common.Sha256([]byte("2")))
+	c := newSimpleContainer(100, 10, nonceRepository{})
+
+	c.push(genContainerTx(addr1, 1, 10))
+	c.push(genContainerTx(addr1, 2, 100))
+	c.push(genContainerTx(addr2, 1, 50))
+	c.push(genContainerTx(addr2, 2, 5))
+
+	txs := collectPending(c)
+	if len(txs) != 4 {
+		t.Fatalf("pack size error, expect 4, got %v", len(txs))
+	}
+	expects := []struct {
+		source common.Address
+		nonce  uint64
+	}{{addr2, 1}, {addr1, 1}, {addr1, 2}, {addr2, 2}}
+	for i, e := range expects {
+		if *txs[i].Source != e.source || txs[i].Nonce != e.nonce {
+			t.Fatalf("pack order error at %v: nonce %v, price %v", i, txs[i].Nonce, txs[i].GasPrice)
+		}
+	}
+}
+
+func TestContainerReplace(t *testing.T) {
+	addr := common.BytesToAddress(common.Sha256([]byte("1")))
+	c := newSimpleContainer(100, 10, nonceRepository{})
+
+	old := genContainerTx(addr, 1, 100)
+	c.push(old)
+	c.push(genContainerTx(addr, 3, 100))
+
+	if _, err := c.push(genContainerTx(addr, 1, 110)); err != ErrReplaceUnderpriced {
+		t.Fatalf("expect replace underpriced error, got %v", err)
+	}
+	tx := genContainerTx(addr, 1, c.replacementPrice(old.GasPrice))
+	replaced, err := c.push(tx)
+	if err != nil || replaced != old {
+		t.Fatalf("replace pending error:%v", err)
+	}
+	if c.contains(old.Hash) || c.getByNonce(addr, 1) != tx || c.pendingLen() != 1 || c.Len() != 2 {
+		t.Fatalf("replace pending error, size %v, pending %v", c.Len(), c.pendingLen())
+	}
+
+	// Replace the queued one
+	replaced, err = c.push(genContainerTx(addr, 3, 200))
+	if err != nil || replaced == nil || c.getByNonce(addr, 3).GasPrice != 200 || c.pendingLen() != 1 {
+		t.Fatalf("replace queued error:%v", err)
+	}
+}
+
+func TestContainerLimit(t *testing.T) {
+	addr1 := common.BytesToAddress(common.Sha256([]byte("1")))
+	addr2 := common.BytesToAddress(common.Sha256([]byte("2")))
+	c := newSimpleContainer(2, 10, nonceRepository{})
+
+	c.push(genContainerTx(addr1, 1, 10))
+	c.push(genContainerTx(addr1, 3, 10))
+	if _, err := c.push(genContainerTx(addr2, 1, 5)); err != ErrPoolFull {
+		t.Fatalf("expect pool full error, got %v", err)
+	}
+	if _, err := c.push(genContainerTx(addr2, 1, 20)); err != nil {
+		t.Fatalf("push error:%v", err)
+	}
+	if c.getByNonce(addr1, 3) != nil {
+		t.Fatalf("queued transaction should be evicted")
+	}
+}
+
+func TestContainerReplacementPriceOverflow(t *testing.T) {
+	c := newSimpleContainer(100, 10, nonceRepository{})
+	if price := c.replacementPrice(100); price != 111 {
+		t.Fatalf("expect replacement price 111, got %v", price)
+	}
+	if price := c.replacementPrice(math.MaxUint64 / 2); price != math.MaxUint64/2+math.MaxUint64/20+1 {
+		t.Fatalf("unexpected replacement price %v", price)
+	}
+	if price := c.replacementPrice(math.MaxUint64 - 1); price != math.MaxUint64 {
+		t.Fatalf("expect replacement price capped at max uint64, got %v", price)
+	}
+}
@@ -0,0 +1,141 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/taschain/taschain/common"
+	"github.com/taschain/taschain/storage/account"
+)
+
+// StorageDiff is the value of a storage key before and after the block, nil if the key is absent
+type StorageDiff struct {
+	Key    string
+	Before []byte
+	After  []byte
+}
+
+// AccountDiff is the change of an account changed by the block
+type AccountDiff struct {
+	Address       common.Address
+	BalanceBefore *big.Int
+	BalanceAfter  *big.Int
+	NonceBefore   uint64
+	NonceAfter    uint64
+
+	// CodeBefore and CodeAfter are only set if the code is changed
+	CodeChanged bool
+	CodeBefore  []byte
+	CodeAfter   []byte
+
+	// Storage is the storage keys changed, sorted by the key
+	Storage []*StorageDiff
+}
+
+// StateDiff is the changes of the accounts changed by the block, sorted by the address. The castor proposal bonus and
+// the bonus transactions are included, so the balances can be reconstructed from the diffs alone
+type StateDiff struct {
+	Height    uint64
+	BlockHash common.Hash
+	Accounts  []*AccountDiff
+}
+
+// StateDiff re-executes the block at the height on the state of its parent block with the journal of the account db
+// recording the accounts and storage keys touched, and returns their values before and after the block. The state of
+// the parent block must not have been pruned
+func (chain *FullBlockChain) StateDiff(height uint64) (*StateDiff, error) {
+	if height == 0 {
+		return nil, fmt.Errorf("genesis block has no state diff")
+	}
+	b := chain.QueryBlockByHeight(height)
+	if b == nil {
+		return nil, fmt.Errorf("block at height %v not found", height)
+	}
+	pre := chain.QueryBlockHeaderByHash(b.Header.PreHash)
+	if pre == nil {
+		return nil, fmt.Errorf("parent block %v not found", b.Header.PreHash.Hex())
+	}
+	before, err := chain.stateAt(pre)
+	if err != nil {
+		return nil, err
+	}
+	state, err := chain.stateAt(pre)
+	if err != nil {
+		return nil, err
+	}
+
+	// The tvm is not reentrant, re-executions are serialized with block casting and adding
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	// Executed the same as the block is added, but the root is computed after the dirties are taken
+	chain.executor.executeBlock(state, b.Header, b.Transactions, false)
+
+	// The journal is cleared when the root is computed
+	dirties := state.Dirties()
+	if root := state.IntermediateRoot(true); root != b.Header.StateTree {
+		return nil, fmt.Errorf("state root %v of the re-executed block differs from %v", root.Hex(), b.Header.StateTree.Hex())
+	}
+	return &StateDiff{
+		Height:    b.Header.Height,
+		BlockHash: b.Header.Hash,
+		Accounts:  diffAccounts(before, state, dirties),
+	}, nil
+}
+
+// diffAccounts compares the dirty accounts and storage keys between the states. The accounts only touched, whose
+// balance, nonce, code and storage are all unchanged, are left out
+func diffAccounts(before, after *account.AccountDB, dirties map[common.Address][]string) []*AccountDiff {
+	addrs := make([]common.Address, 0, len(dirties))
+	for addr := range dirties {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return bytes.Compare(addrs[i].Bytes(), addrs[j].Bytes()) < 0
+	})
+
+	diffs := make([]*AccountDiff, 0, len(addrs))
+	for _, addr := range addrs {
+		diff := &AccountDiff{
+			Address:       addr,
+			BalanceBefore: new(big.Int).Set(before.GetBalance(addr)),
+			BalanceAfter:  new(big.Int).Set(after.GetBalance(addr)),
+			NonceBefore:   before.GetNonce(addr),
+			NonceAfter:    after.GetNonce(addr),
+			Storage:       make([]*StorageDiff, 0),
+		}
+		if codeBefore, codeAfter := before.GetCode(addr), after.GetCode(addr); !bytes.Equal(codeBefore, codeAfter) {
+			diff.CodeChanged = true
+			diff.CodeBefore = codeBefore
+			diff.CodeAfter = codeAfter
+		}
+		for _, key := range dirties[addr] {
+			valueBefore, valueAfter := before.GetData(addr, key), after.GetData(addr, key)
+			if !bytes.Equal(valueBefore, valueAfter) {
+				diff.Storage = append(diff.Storage, &StorageDiff{Key: key, Before: valueBefore, After: valueAfter})
+			}
+		}
+		if diff.BalanceBefore.Cmp(diff.BalanceAfter) == 0 && diff.NonceBefore == diff.NonceAfter && !diff.CodeChanged && len(diff.Storage) == 0 {
+			continue
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs
+}
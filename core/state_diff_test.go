@@ -0,0 +1,112 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/taschain/taschain/common"
+	"github.com/taschain/taschain/storage/account"
+	"github.com/taschain/taschain/storage/tasdb"
+)
+
+func TestDiffAccounts(t *testing.T) {
+	initConf4Test(t)
+	memDB, _ := tasdb.NewMemDatabase()
+	stateCache := account.NewDatabase(memDB)
+
+	sender, contract, castor := common.BytesToAddress([]byte{1}), common.BytesToAddress([]byte{2}), common.BytesToAddress([]byte{3})
+	touched := common.BytesToAddress([]byte{4})
+	pre, _ := account.NewAccountDB(common.Hash{}, stateCache)
+	pre.SetNonce(sender, 5)
+	pre.SetCode(contract, []byte("code"))
+	pre.SetData(contract, "a", []byte("1"))
+	pre.SetData(contract, "b", []byte("2"))
+	root, err := pre.Commit(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before, _ := account.NewAccountDB(root, stateCache)
+	state, _ := account.NewAccountDB(root, stateCache)
+	state.AddBalance(sender, big.NewInt(70))
+	state.SetNonce(sender, 6)
+	state.SetData(contract, "a", []byte("1"))
+	state.SetData(contract, "b", []byte("3"))
+	state.RemoveData(contract, "c")
+	state.SetData(contract, "d", []byte("4"))
+	state.AddBalance(castor, big.NewInt(30))
+	// Touched only, left out of the diffs
+	state.AddBalance(touched, big.NewInt(0))
+	diffs := diffAccounts(before, state, state.Dirties())
+
+	if len(diffs) != 3 || diffs[0].Address != sender || diffs[1].Address != contract || diffs[2].Address != castor {
+		t.Fatalf("unexpected accounts %+v", diffs)
+	}
+	if diffs[0].BalanceBefore.Sign() != 0 || diffs[0].BalanceAfter.Int64() != 70 || diffs[0].NonceBefore != 5 || diffs[0].NonceAfter != 6 {
+		t.Errorf("unexpected sender diff %+v", diffs[0])
+	}
+	if diffs[1].CodeChanged {
+		t.Errorf("code of the contract not changed")
+	}
+	// Keys written with the same value or removed while absent are not changed
+	storage := diffs[1].Storage
+	if len(storage) != 2 || storage[0].Key != "b" || !bytes.Equal(storage[0].Before, []byte("2")) || !bytes.Equal(storage[0].After, []byte("3")) ||
+		storage[1].Key != "d" || storage[1].Before != nil || !bytes.Equal(storage[1].After, []byte("4")) {
+		t.Errorf("unexpected storage diff %+v", storage)
+	}
+	if diffs[2].BalanceBefore.Sign() != 0 || diffs[2].BalanceAfter.Int64() != 30 {
+		t.Errorf("unexpected castor diff %+v", diffs[2])
+	}
+}
+
+func TestStateDiffOfBlock(t *testing.T) {
+	if err := initContext4Test(); err != nil {
+		t.Fatalf("failed to initContext4Test: %v", err)
+	}
+	defer clear()
+
+	tx := genTestTx(12345, "1", "2", 1, 3)
+	if _, err := BlockChainImpl.GetTransactionPool().AddTransaction(tx); err != nil {
+		t.Fatalf("fail to AddTransaction: %v", err)
+	}
+	block := BlockChainImpl.CastBlock(1, common.Hex2Bytes("12"), 0, []byte{}, []byte{})
+	if block == nil || len(block.Transactions) != 1 {
+		t.Fatalf("fail to cast the block with the transaction")
+	}
+	if BlockChainImpl.AddBlockOnChain(source, block) != 0 {
+		t.Fatalf("fail to add block")
+	}
+
+	diff, err := BlockChainImpl.(*FullBlockChain).StateDiff(1)
+	if err != nil {
+		t.Fatalf("state diff error:%v", err)
+	}
+	found := false
+	for _, acc := range diff.Accounts {
+		if acc.Address == *tx.Source {
+			found = true
+			if acc.NonceBefore != 0 || acc.NonceAfter != tx.Nonce {
+				t.Errorf("source nonce changed from %v to %v, expect 0 to %v", acc.NonceBefore, acc.NonceAfter, tx.Nonce)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("source %v not in the state diff", tx.Source.Hex())
+	}
+}
@@ -0,0 +1,89 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+
+	"github.com/taschain/taschain/common"
+	"github.com/taschain/taschain/middleware/types"
+	"github.com/taschain/taschain/storage/account"
+)
+
+// StorageProof is the merkle proof of a data key under the storage root of the account
+type StorageProof struct {
+	Key   string
+	Value []byte
+	Proof [][]byte
+}
+
+// AccountProof is the merkle proof of the account and its data keys under the state root of the block.
+// The account fields are zero values if the proof shows the account doesn't exist
+type AccountProof struct {
+	Height    uint64
+	BlockHash common.Hash
+	StateRoot common.Hash
+
+	Address     common.Address
+	Account     *account.Account
+	Proof       [][]byte
+	StorageHash common.Hash
+
+	StorageProofs []*StorageProof
+}
+
+// GetProof returns the proof of the account and the given data keys under the state of the given block. It fails if
+// the state has been pruned
+func (chain *FullBlockChain) GetProof(addr common.Address, keys []string, header *types.BlockHeader) (*AccountProof, error) {
+	if header == nil {
+		return nil, fmt.Errorf("block not found")
+	}
+	state, err := chain.stateAt(header)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &AccountProof{
+		Height:        header.Height,
+		BlockHash:     header.Hash,
+		StateRoot:     header.StateTree,
+		Address:       addr,
+		StorageProofs: make([]*StorageProof, 0, len(keys)),
+	}
+	if ret.Proof, err = state.GetProof(addr); err != nil {
+		return nil, err
+	}
+	// Decode the account from the proof itself, so that the returned fields always match the proof
+	if ret.Account, err = account.VerifyAccountProof(header.StateTree, addr, ret.Proof); err != nil {
+		return nil, err
+	}
+	if ret.Account != nil {
+		ret.StorageHash = ret.Account.Root
+	}
+	for _, key := range keys {
+		sp := &StorageProof{Key: key}
+		if ret.Account != nil {
+			if sp.Proof, err = state.GetStorageProof(addr, key); err != nil {
+				return nil, err
+			}
+			if sp.Value, err = account.VerifyStorageProof(ret.StorageHash, key, sp.Proof); err != nil {
+				return nil, err
+			}
+		}
+		ret.StorageProofs = append(ret.StorageProofs, sp)
+	}
+	return ret, nil
+}
@@ -0,0 +1,163 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"sync"
+
+	"github.com/taschain/taschain/common"
+	"github.com/taschain/taschain/storage/tasdb"
+	"github.com/taschain/taschain/storage/trie"
+)
+
+const (
+	// minStateRetention is the least number of recent block states kept in pruned mode.
+	// The fork processor and resetTop need the state of the common ancestor to switch branches
+	minStateRetention = 2 * chainPieceLength
+
+	defaultStateRetention     = 128
+	defaultStateCacheSize     = 256  // In MB
+	defaultStateFlushInterval = 1024 // In blocks
+
+	preimagesCacheLimit = 4 * 1024 * 1024
+)
+
+// statePruner decides how the block states are persisted.
+//
+// In archive mode, which is the default, the state trie of every block is committed to the disk.
+// In pruned mode, enabled by state_pruning, the state tries are reference counted in the trie memory database. The states out of the
+// retention window are dereferenced so that their garbage nodes are never written to the disk, while the live
+// nodes are flushed in batches once the memory cache grows over the limit. The state out of the window is also
+// committed every flush interval, which bounds the blocks lost on crash: the chain is rewound to the last committed
+// state on restart. The retention covers the max reorg depth, and deeper forks are rejected since the state of their
+// ancestor is gone
+type statePruner struct {
+	archive       bool
+	retention     uint64
+	cacheLimit    common.StorageSize
+	flushInterval uint64
+
+	triedb    *trie.NodeDatabase
+	roots     map[uint64][]common.Hash // Referenced state roots in the memory database by block height
+	lastFlush uint64                   // Height of the last state committed to the disk
+	lock      sync.Mutex
+}
+
+func newStatePruner(triedb *trie.NodeDatabase, latestHeight uint64, maxReorgDepth uint64) *statePruner {
+	retention := uint64(common.GlobalConf.GetInt(configSec, "state_retention", defaultStateRetention))
+	if retention < minStateRetention {
+		retention = minStateRetention
+	}
+	// The state of the ancestor of the deepest fork allowed must be kept
+	if retention <= maxReorgDepth {
+		retention = maxReorgDepth + 1
+	}
+	cacheSize := common.GlobalConf.GetInt(configSec, "state_cache_size", defaultStateCacheSize)
+	if cacheSize <= 0 {
+		cacheSize = defaultStateCacheSize
+	}
+	flushInterval := uint64(common.GlobalConf.GetInt(configSec, "state_flush_interval", defaultStateFlushInterval))
+	if flushInterval == 0 {
+		flushInterval = defaultStateFlushInterval
+	}
+	archive := !common.GlobalConf.GetBool(configSec, "state_pruning", false)
+	if !archive {
+		Logger.Warnf("state pruning enabled, keeping %v recent states and persisting one every %v blocks. Up to %v blocks are rewound after a crash", retention, flushInterval, retention+flushInterval)
+	}
+	return &statePruner{
+		archive:       archive,
+		retention:     retention,
+		cacheLimit:    common.StorageSize(cacheSize * 1024 * 1024),
+		flushInterval: flushInterval,
+		triedb:        triedb,
+		roots:         make(map[uint64][]common.Hash),
+		lastFlush:     latestHeight,
+	}
+}
+
+// commit persists or references the state root of the block at the given height
+func (p *statePruner) commit(root common.Hash, height uint64) error {
+	// The genesis state is always persisted
+	if p.archive || height == 0 {
+		return p.triedb.Commit(root, false)
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.triedb.Reference(root, common.Hash{})
+	p.roots[height] = append(p.roots[height], root)
+	if height <= p.retention {
+		return nil
+	}
+	chosen := height - p.retention
+
+	// Flush the live nodes in batches if the memory cache grows too large
+	if nodes, preimages := p.triedb.Size(); nodes > p.cacheLimit || preimages > preimagesCacheLimit {
+		if err := p.triedb.Cap(p.cacheLimit - tasdb.IdealBatchSize); err != nil {
+			return err
+		}
+	}
+	// Persist the state leaving the window periodically
+	if chosen >= p.lastFlush+p.flushInterval {
+		for _, r := range p.roots[chosen] {
+			if err := p.triedb.Commit(r, false); err != nil {
+				return err
+			}
+		}
+		p.lastFlush = chosen
+		Logger.Debugf("state pruner flushed state at height %v", chosen)
+	}
+	// Garbage collect the states out of the window
+	for h, roots := range p.roots {
+		if h > chosen {
+			continue
+		}
+		for _, r := range roots {
+			p.triedb.Dereference(r)
+		}
+		delete(p.roots, h)
+	}
+	return nil
+}
+
+// flush commits the given state to the disk, it's called on closing so that the node restarts from the latest state
+func (p *statePruner) flush(root common.Hash, height uint64) error {
+	if p.archive {
+		return nil
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if err := p.triedb.Commit(root, false); err != nil {
+		return err
+	}
+	p.lastFlush = height
+	return nil
+}
+
+// maxReorgDepth returns the max number of blocks a fork can rewind, which is limited by the states kept.
+// No limit if 0
+func (p *statePruner) maxReorgDepth() uint64 {
+	if p.archive {
+		return 0
+	}
+	return p.retention - 1
+}
+
+// outOfRetention returns whether the state at the height may have been pruned with the top at the given height
+func (p *statePruner) outOfRetention(height, top uint64) bool {
+	return !p.archive && height+p.retention <= top
+}
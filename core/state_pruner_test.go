@@ -0,0 +1,98 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/taschain/taschain/common"
+	"github.com/taschain/taschain/storage/account"
+	"github.com/taschain/taschain/storage/tasdb"
+	"github.com/taschain/taschain/taslog"
+)
+
+// commitStates4Test commits a state per height from 1 to n, each changing the nonce of a new account
+func commitStates4Test(t *testing.T, archive bool, n uint64) (*tasdb.MemDatabase, account.AccountDatabase, []common.Hash) {
+	initConf4Test(t)
+	Logger = taslog.GetLoggerByIndex(taslog.CoreLogConfig, "")
+	common.GlobalConf.SetBool(configSec, "state_pruning", !archive)
+	common.GlobalConf.SetInt(configSec, "state_retention", minStateRetention)
+
+	memDB, _ := tasdb.NewMemDatabase()
+	stateCache := account.NewDatabase(memDB)
+	pruner := newStatePruner(stateCache.TrieDB(), 0, 0)
+
+	roots := make([]common.Hash, n+1)
+	for h := uint64(1); h <= n; h++ {
+		state, err := account.NewAccountDB(roots[h-1], stateCache)
+		if err != nil {
+			t.Fatalf("open state at height %v error:%v", h-1, err)
+		}
+		state.SetNonce(common.BigToAddress(new(big.Int).SetUint64(h)), h)
+		roots[h], err = state.Commit(true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := pruner.commit(roots[h], h); err != nil {
+			t.Fatalf("pruner commit error:%v", err)
+		}
+	}
+	return memDB, stateCache, roots
+}
+
+func TestStatePrunerPruned(t *testing.T) {
+	n := uint64(3 * minStateRetention)
+	memDB, stateCache, roots := commitStates4Test(t, false, n)
+	p := &statePruner{retention: minStateRetention}
+
+	for h := uint64(1); h <= n; h++ {
+		_, err := account.NewAccountDB(roots[h], stateCache)
+		if h+minStateRetention <= n && err == nil {
+			t.Errorf("state at height %v should be pruned", h)
+		}
+		if p.outOfRetention(h, n) != (err != nil) {
+			t.Errorf("state at height %v out of retention %v, open error %v", h, p.outOfRetention(h, n), err)
+		}
+		if h+minStateRetention > n && err != nil {
+			t.Errorf("state at height %v should be kept, got %v", h, err)
+		}
+	}
+	if ok, _ := memDB.Has(roots[n].Bytes()); ok {
+		t.Errorf("recent state should not be written to disk")
+	}
+}
+
+func TestStatePrunerArchive(t *testing.T) {
+	n := uint64(3 * minStateRetention)
+	memDB, stateCache, roots := commitStates4Test(t, true, n)
+	if (&statePruner{archive: true, retention: minStateRetention}).outOfRetention(1, n) {
+		t.Errorf("no state out of retention in archive mode")
+	}
+
+	for h := uint64(1); h <= n; h++ {
+		if ok, _ := memDB.Has(roots[h].Bytes()); !ok {
+			t.Errorf("state at height %v should be on disk", h)
+		}
+		state, err := account.NewAccountDB(roots[h], stateCache)
+		if err != nil {
+			t.Fatalf("open state at height %v error:%v", h, err)
+		}
+		if state.GetNonce(common.BigToAddress(new(big.Int).SetUint64(h))) != h {
+			t.Errorf("nonce mismatch at height %v", h)
+		}
+	}
+}
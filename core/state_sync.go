@@ -0,0 +1,622 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/taschain/taschain/common"
+	"github.com/taschain/taschain/middleware/notify"
+	tas_middleware_pb "github.com/taschain/taschain/middleware/pb"
+	"github.com/taschain/taschain/middleware/types"
+	"github.com/taschain/taschain/network"
+	"github.com/taschain/taschain/storage/account"
+	"github.com/taschain/taschain/storage/trie"
+	"github.com/taschain/taschain/taslog"
+)
+
+const (
+	// SyncModeFull replays all the blocks from the genesis
+	SyncModeFull = "full"
+	// SyncModeFast downloads the state of a recent pivot block instead of executing the blocks before it
+	SyncModeFast = "fast"
+)
+
+const (
+	fastSyncPivotDistance = 64   // Distance of the pivot block to the top of the best peer
+	fastSyncMinHeight     = 1024 // Chains lower than it are synced in full mode
+	fastSyncWaitRounds    = 60   // Rounds waiting for a candidate before falling back to full sync
+	stateSyncInterval     = 1    // Interval of requesting state nodes from neighbors
+	stateSyncTimeout      = 5    // Timeout of requesting state nodes from neighbor in seconds
+	stateSyncMaxPeers     = 5    // Maximum number of neighbors downloading from at the same time
+	stateSyncStallRounds  = 30   // Rounds without any node delivered before the pivot is considered stale
+	stateSyncBadPeerTime  = 600  // Seconds a peer delivering invalid nodes is excluded
+	stateServeMaxReqs     = 2    // Maximum number of node requests served to a peer per second
+	maxStateNodesPerReq   = 384  // Maximum number of nodes requested or responded in one message
+	maxStateInfoSize      = 512 * 1024
+)
+
+const tickerStateSync = "state_sync"
+
+// fastSyncPivotKey stores the pivot height in the block database while fast sync is in progress, so that the
+// unexecuted blocks are kept and the sync resumes on restart
+const fastSyncPivotKey = "fast_sync_pivot"
+
+// fastSyncedHeightKey stores the height of the last block stored by the fast sync, which is kept after the sync
+const fastSyncedHeightKey = "fast_synced_height"
+
+// ErrFastSynced is returned for the receipts and the proofs of the blocks stored by the fast sync without execution
+var ErrFastSynced = errors.New("not available (fast-synced)")
+
+var stateSync *stateSyncer
+
+// stateSyncer implements the fast sync. A pivot block is chosen some distance below the top of the best peer.
+// The blocks up to the pivot are stored without execution, verified by the hash link, the transaction root and
+// the group signature. Then the account and storage tries of the pivot are downloaded from several neighbors
+// with the StateInfoReq/StateInfo messages, and each node is verified by its hash against the state root of
+// the pivot. After that, the blockSyncer takes over and executes the blocks after the pivot as usual.
+//
+// The syncer also serves the trie nodes to the neighbors, which is always enabled
+type stateSyncer struct {
+	chain *FullBlockChain
+
+	fast       bool   // Whether the fast sync is in progress
+	pivot      uint64 // Height of the pivot, 0 means not chosen yet
+	waitRounds int    // Rounds waited for the candidate to choose the pivot
+
+	root     common.Hash              // State root of the pivot block, set once the pivot block is reached
+	pivotBH  *types.BlockHeader       // The pivot block whose state is being downloaded
+	sched    *trie.Sync               // Scheduler of the trie nodes to download
+	inflight map[string]*stateRequest // Node requests waiting for the response by peer
+	bad      map[string]time.Time     // Peers having delivered invalid nodes, by the time excluded
+	stall    int                      // Rounds without any node delivered
+
+	served *lru.Cache // Node requests served by peer in the current second, see stateServeMeter
+
+	lock   sync.Mutex
+	logger taslog.Logger
+}
+
+// stateServeMeter counts the node requests served to a peer in a second
+type stateServeMeter struct {
+	second int64
+	count  int
+}
+
+type stateRequest struct {
+	hashes []common.Hash
+	time   time.Time
+}
+
+// InitStateSyncer initialize the stateSyncer. The fast sync is started if configured and the chain is empty,
+// or if the one interrupted is to be resumed
+func InitStateSyncer(chain *FullBlockChain) {
+	ss := &stateSyncer{
+		chain:    chain,
+		inflight: make(map[string]*stateRequest),
+		bad:      make(map[string]time.Time),
+		served:   common.MustNewLRUCache(256),
+	}
+	ss.logger = taslog.GetLoggerByIndex(taslog.BlockSyncLogConfig, common.GlobalConf.GetString("instance", "index", ""))
+
+	mode := common.GlobalConf.GetString(configSec, "sync_mode", SyncModeFull)
+	if pivot := chain.loadFastSyncPivot(); pivot > 0 {
+		ss.fast = true
+		ss.pivot = pivot
+		ss.logger.Infof("resume fast sync with pivot %v", pivot)
+	} else if mode == SyncModeFast && chain.Height() == 0 {
+		ss.fast = true
+	}
+
+	notify.BUS.Subscribe(notify.StateInfoReq, ss.stateInfoReqHandler)
+	notify.BUS.Subscribe(notify.StateInfo, ss.stateInfoHandler)
+
+	if ss.fast {
+		chain.ticker.RegisterPeriodicRoutine(tickerStateSync, ss.syncRoutine, stateSyncInterval)
+		chain.ticker.StartTickerRoutine(tickerStateSync, false)
+	}
+	stateSync = ss
+}
+
+// isActive returns whether the fast sync is in progress
+func (ss *stateSyncer) isActive() bool {
+	if ss == nil {
+		return false
+	}
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+	return ss.fast
+}
+
+// blockSyncAllowed returns false if the block sync should wait, either for the pivot being chosen or for the state
+// of the pivot being downloaded
+func (ss *stateSyncer) blockSyncAllowed() bool {
+	if ss == nil {
+		return true
+	}
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+	return !ss.fast || (ss.pivot > 0 && ss.pivotBH == nil)
+}
+
+// fastPivot returns the pivot height if the synced blocks should be stored without execution
+func (ss *stateSyncer) fastPivot() (uint64, bool) {
+	if ss == nil {
+		return 0, false
+	}
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+	if !ss.fast || ss.pivot == 0 {
+		return 0, false
+	}
+	return ss.pivot, true
+}
+
+// pivotReached starts downloading the state of the pivot block
+func (ss *stateSyncer) pivotReached(bh *types.BlockHeader) {
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+	if !ss.fast || ss.pivotBH != nil {
+		return
+	}
+	ss.pivotBH = bh
+	ss.root = bh.StateTree
+	ss.sched = account.NewStateSync(bh.StateTree, ss.chain.stateDb)
+	ss.stall = 0
+	ss.logger.Infof("fast sync pivot reached, downloading the state of height %v, hash %v, state root %v", bh.Height, bh.Hash.Hex(), bh.StateTree.Hex())
+}
+
+func (ss *stateSyncer) syncRoutine() bool {
+	// Read the candidates first, the blockSyncer never calls in with its lock held
+	var top *topBlockInfo
+	var peers []string
+	if blockSync != nil {
+		top = blockSync.bestCandidateTop()
+		ss.lock.Lock()
+		height := ss.pivot
+		ss.lock.Unlock()
+		peers = blockSync.candidatesAbove(height)
+	}
+
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+	if !ss.fast {
+		return false
+	}
+	if ss.pivot == 0 {
+		return ss.choosePivot(top)
+	}
+	if ss.pivotBH == nil {
+		// Wait for the blocks up to the pivot
+		return false
+	}
+	if ss.sched.Pending() == 0 {
+		ss.finish()
+		return true
+	}
+	ss.expireRequests()
+	ss.expireBadPeers()
+
+	ss.stall++
+	if ss.stall > stateSyncStallRounds && top != nil && top.Height > ss.pivot+2*fastSyncPivotDistance {
+		// The neighbors may have pruned the state of the pivot, move on to a newer one. The nodes already
+		// downloaded are kept in the database and skipped by the new scheduler
+		ss.logger.Warnf("state sync of pivot %v stalled, move the pivot to %v", ss.pivot, top.Height-fastSyncPivotDistance)
+		ss.resetPivot(top.Height - fastSyncPivotDistance)
+		return true
+	}
+	ss.requestNodes(peers)
+	return true
+}
+
+func (ss *stateSyncer) choosePivot(top *topBlockInfo) bool {
+	if top == nil || top.Height < fastSyncMinHeight {
+		ss.waitRounds++
+		if ss.waitRounds > fastSyncWaitRounds {
+			ss.logger.Infof("no candidate high enough for fast sync, switch to full sync")
+			ss.fast = false
+			ss.chain.ticker.RemoveRoutine(tickerStateSync)
+		}
+		return false
+	}
+	ss.resetPivot(top.Height - fastSyncPivotDistance)
+	ss.logger.Infof("fast sync pivot chosen at %v, candidate top %v", ss.pivot, top.Height)
+	return true
+}
+
+func (ss *stateSyncer) resetPivot(pivot uint64) {
+	if err := ss.chain.blocks.Put([]byte(fastSyncPivotKey), common.UInt64ToByte(pivot)); err != nil {
+		ss.logger.Errorf("save fast sync pivot error:%v", err)
+	}
+	ss.pivot = pivot
+	ss.pivotBH = nil
+	ss.sched = nil
+	ss.root = common.Hash{}
+	ss.inflight = make(map[string]*stateRequest)
+	ss.stall = 0
+}
+
+// expireBadPeers gives the peers excluded long enough another chance
+func (ss *stateSyncer) expireBadPeers() {
+	for id, t := range ss.bad {
+		if time.Since(t) > stateSyncBadPeerTime*time.Second {
+			delete(ss.bad, id)
+		}
+	}
+}
+
+func (ss *stateSyncer) expireRequests() {
+	for id, req := range ss.inflight {
+		if time.Since(req.time) > stateSyncTimeout*time.Second {
+			ss.logger.Warnf("state sync from %v timeout", id)
+			peerManagerImpl.timeoutPeer(id)
+			ss.sched.Retry(req.hashes)
+			delete(ss.inflight, id)
+		}
+	}
+}
+
+// requestNodes distributes the missing nodes to the idle neighbors
+func (ss *stateSyncer) requestNodes(peers []string) {
+	for _, id := range peers {
+		if len(ss.inflight) >= stateSyncMaxPeers {
+			return
+		}
+		if _, bad := ss.bad[id]; bad || peerManagerImpl.isEvil(id) {
+			continue
+		}
+		if _, ok := ss.inflight[id]; ok {
+			continue
+		}
+		hashes := ss.sched.Missing(maxStateNodesPerReq)
+		if len(hashes) == 0 {
+			return
+		}
+		body, err := marshalStateInfoReq(ss.pivotBH, hashes)
+		if err != nil {
+			ss.logger.Errorf("marshalStateInfoReq error %v", err)
+			ss.sched.Retry(hashes)
+			return
+		}
+		ss.logger.Debugf("req %v state nodes to %v", len(hashes), id)
+		network.GetNetInstance().Send(id, network.Message{Code: network.ReqStateInfo, Body: body})
+		ss.inflight[id] = &stateRequest{hashes: hashes, time: time.Now()}
+	}
+}
+
+// finish switches the chain to the state of the pivot block, and hands over to the block sync
+func (ss *stateSyncer) finish() {
+	state, err := account.NewAccountDB(ss.root, ss.chain.stateCache)
+	if err != nil {
+		ss.logger.Errorf("open synced state error:%v", err)
+		return
+	}
+	ss.chain.rwLock.Lock()
+	if ss.chain.latestBlock.Hash == ss.pivotBH.Hash {
+		ss.chain.latestStateDB = state
+	}
+	ss.chain.rwLock.Unlock()
+
+	if err := ss.chain.blocks.Delete([]byte(fastSyncPivotKey)); err != nil {
+		ss.logger.Errorf("delete fast sync pivot error:%v", err)
+	}
+	ss.fast = false
+	ss.bad = make(map[string]time.Time)
+	ss.chain.ticker.RemoveRoutine(tickerStateSync)
+	ss.logger.Infof("fast sync done, pivot height %v, state root %v, switch to full sync", ss.pivotBH.Height, ss.root.Hex())
+
+	if blockSync != nil {
+		go blockSync.trySyncRoutine()
+	}
+}
+
+func (ss *stateSyncer) stateInfoHandler(msg notify.Message) {
+	m := notify.AsDefault(msg)
+	source := m.Source()
+
+	info, err := unmarshalStateInfo(m.Body())
+	if err != nil {
+		ss.logger.Warnf("Discard state info msg because of unmarshal error:%v", err)
+		return
+	}
+
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+
+	req := ss.inflight[source]
+	if !ss.fast || ss.pivotBH == nil || req == nil || common.BytesToHash(info.BlockHash) != ss.pivotBH.Hash {
+		return
+	}
+	delete(ss.inflight, source)
+	peerManagerImpl.heardFromPeer(source)
+
+	requested := make(map[common.Hash]bool, len(req.hashes))
+	for _, h := range req.hashes {
+		requested[h] = true
+	}
+	delivered := 0
+	for _, node := range info.TrieNodes {
+		hash := common.BytesToHash(node.Key)
+		if !requested[hash] {
+			continue
+		}
+		delete(requested, hash)
+		_, _, err := ss.sched.Process([]trie.SyncResult{{Hash: hash, Data: node.Data}})
+		switch err {
+		case nil:
+			delivered++
+		case trie.ErrNotRequested, trie.ErrAlreadyProcessed:
+			// Delivered by another peer already
+		default:
+			ss.logger.Warnf("invalid state node %v from %v:%v", hash.Hex(), source, err)
+			ss.bad[source] = time.Now()
+			requested[hash] = true
+		}
+	}
+	retry := make([]common.Hash, 0, len(requested))
+	for h := range requested {
+		retry = append(retry, h)
+	}
+	ss.sched.Retry(retry)
+
+	if delivered > 0 {
+		ss.stall = 0
+		batch := ss.chain.stateDb.NewBatch()
+		if _, err := ss.sched.Commit(batch); err != nil {
+			ss.logger.Errorf("commit state nodes error:%v", err)
+			return
+		}
+		if err := batch.Write(); err != nil {
+			ss.logger.Errorf("write state nodes error:%v", err)
+			return
+		}
+	}
+	ss.logger.Debugf("rcv %v state nodes from %v, pending %v", delivered, source, ss.sched.Pending())
+	if ss.sched.Pending() == 0 {
+		ss.finish()
+	}
+}
+
+// allowServe checks the node requests served to the peer in the current second are within the limit
+func (ss *stateSyncer) allowServe(id string) bool {
+	now := time.Now().Unix()
+	v, _ := ss.served.Get(id)
+	meter, ok := v.(*stateServeMeter)
+	if !ok || meter.second != now {
+		meter = &stateServeMeter{second: now}
+		ss.served.Add(id, meter)
+	}
+	if meter.count >= stateServeMaxReqs {
+		return false
+	}
+	meter.count++
+	return true
+}
+
+func (ss *stateSyncer) stateInfoReqHandler(msg notify.Message) {
+	m := notify.AsDefault(msg)
+
+	ss.lock.Lock()
+	allowed := ss.allowServe(m.Source())
+	ss.lock.Unlock()
+	if !allowed {
+		ss.logger.Debugf("Discard state req from %v exceeding %v reqs per second", m.Source(), stateServeMaxReqs)
+		return
+	}
+
+	req, err := unmarshalStateInfoReq(m.Body())
+	if err != nil {
+		ss.logger.Errorf("unmarshalStateInfoReq error %v", err)
+		return
+	}
+	var root []byte
+	if bh := ss.chain.QueryBlockHeaderByHash(common.BytesToHash(req.BlockHash)); bh != nil {
+		root = bh.StateTree.Bytes()
+	}
+	nodes := make([]*tas_middleware_pb.TrieNode, 0)
+	size := 0
+	for i, key := range req.Addresses {
+		if i >= maxStateNodesPerReq || size >= maxStateInfoSize {
+			break
+		}
+		if len(key) != common.HashLength {
+			continue
+		}
+		data, err := ss.chain.stateCache.TrieDB().Node(common.BytesToHash(key))
+		if err != nil || len(data) == 0 {
+			continue
+		}
+		nodes = append(nodes, &tas_middleware_pb.TrieNode{Key: key, Data: data})
+		size += len(data)
+	}
+	ss.logger.Debugf("Rcv state req from %v, height %v, nodes %v, found %v", m.Source(), req.GetHeight(), len(req.Addresses), len(nodes))
+
+	body, err := marshalStateInfo(req.GetHeight(), req.BlockHash, root, nodes)
+	if err != nil {
+		ss.logger.Errorf("marshalStateInfo error %v", err)
+		return
+	}
+	network.GetNetInstance().Send(m.Source(), network.Message{Code: network.StateInfoMsg, Body: body})
+}
+
+// insertFastBlocks stores the blocks not higher than the pivot without executing them. It returns the pivot block
+// once a block higher than the pivot links to the local top, which means the local top is the pivot
+func (chain *FullBlockChain) insertFastBlocks(blocks []*types.Block, pivot uint64) (*types.BlockHeader, error) {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	for _, b := range blocks {
+		bh := b.Header
+		if chain.hasBlock(bh.Hash) {
+			continue
+		}
+		top := chain.getLatestBlock()
+		if bh.PreHash != top.Hash {
+			return nil, fmt.Errorf("block %v not linked to local top %v", bh.Hash.Hex(), top.Hash.Hex())
+		}
+		if bh.Height > pivot {
+			return top, nil
+		}
+		if err := chain.verifyFastBlock(b); err != nil {
+			return nil, err
+		}
+		if err := chain.saveFastBlock(b, pivot); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func (chain *FullBlockChain) verifyFastBlock(b *types.Block) error {
+	bh := b.Header
+	if bh.Hash != bh.GenHash() {
+		return fmt.Errorf("hash diff")
+	}
+	if !chain.validateTxRoot(bh.TxTree, b.Transactions) {
+		return fmt.Errorf("tx root diff")
+	}
+	// The group may not be synced yet
+	if GroupChainImpl == nil || GroupChainImpl.GetGroupByID(bh.GroupID) == nil {
+		return common.ErrGroupNil
+	}
+	if ok, err := chain.consensusHelper.VerifyBlockHeader(bh); !ok {
+		return fmt.Errorf("verify block header fail:%v", err)
+	}
+	return nil
+}
+
+func (chain *FullBlockChain) saveFastBlock(b *types.Block, pivot uint64) error {
+	bh := b.Header
+	headerBytes, err := types.MarshalBlockHeader(bh)
+	if err != nil {
+		return err
+	}
+	bodyBytes, err := encodeBlockTransactions(b)
+	if err != nil {
+		return err
+	}
+
+	chain.rwLock.Lock()
+	defer chain.rwLock.Unlock()
+
+	defer chain.batch.Reset()
+
+	if err = chain.saveBlockHeader(bh.Hash, headerBytes); err != nil {
+		return err
+	}
+	if err = chain.saveBlockHeight(bh.Height, bh.Hash.Bytes()); err != nil {
+		return err
+	}
+	if err = chain.saveBlockTxs(bh.Hash, bodyBytes); err != nil {
+		return err
+	}
+	if err = chain.blocks.AddKv(chain.batch, []byte(fastSyncPivotKey), common.UInt64ToByte(pivot)); err != nil {
+		return err
+	}
+	if err = chain.blocks.AddKv(chain.batch, []byte(fastSyncedHeightKey), common.UInt64ToByte(bh.Height)); err != nil {
+		return err
+	}
+	if err = chain.saveCurrentBlock(bh.Hash); err != nil {
+		return err
+	}
+	if err = chain.batch.Write(); err != nil {
+		return err
+	}
+	// The state stays at the genesis until the state of the pivot downloaded
+	chain.latestBlock = bh
+	chain.fastSyncedHeight = bh.Height
+	chain.addTopBlock(b)
+	return nil
+}
+
+// loadFastSyncPivot returns the pivot height of the fast sync in progress, 0 if none
+func (chain *FullBlockChain) loadFastSyncPivot() uint64 {
+	data, err := chain.blocks.Get([]byte(fastSyncPivotKey))
+	if err != nil || len(data) == 0 {
+		return 0
+	}
+	return common.ByteToUInt64(data)
+}
+
+// loadFastSyncedHeight returns the height of the last block stored by the fast sync, 0 if the chain was never fast synced
+func (chain *FullBlockChain) loadFastSyncedHeight() uint64 {
+	data, err := chain.blocks.Get([]byte(fastSyncedHeightKey))
+	if err != nil || len(data) == 0 {
+		return 0
+	}
+	return common.ByteToUInt64(data)
+}
+
+// receiptNotFound returns the error for the transaction whose receipt is not found. The transactions of the fast synced
+// blocks can't be told from the unknown ones since they are located by the receipts
+func (chain *FullBlockChain) receiptNotFound(txHash common.Hash) error {
+	if chain.fastSyncedHeight > 0 {
+		return fmt.Errorf("tx %v not found, receipts of the blocks up to height %v are %v", txHash.Hex(), chain.fastSyncedHeight, ErrFastSynced)
+	}
+	return fmt.Errorf("tx %v not on chain", txHash.Hex())
+}
+
+// GetReceipt returns the receipt of the transaction on chain
+func (chain *FullBlockChain) GetReceipt(txHash common.Hash) (*types.Receipt, error) {
+	receipt := chain.transactionPool.GetReceipt(txHash)
+	if receipt == nil {
+		return nil, chain.receiptNotFound(txHash)
+	}
+	return receipt, nil
+}
+
+func marshalStateInfoReq(pivot *types.BlockHeader, hashes []common.Hash) ([]byte, error) {
+	// The addresses field carries the hashes of the requested trie nodes
+	keys := make([][]byte, len(hashes))
+	for i, h := range hashes {
+		keys[i] = h.Bytes()
+	}
+	height := pivot.Height
+	message := tas_middleware_pb.StateInfoReq{Height: &height, Addresses: keys, BlockHash: pivot.Hash.Bytes()}
+	return proto.Marshal(&message)
+}
+
+func unmarshalStateInfoReq(b []byte) (*tas_middleware_pb.StateInfoReq, error) {
+	message := new(tas_middleware_pb.StateInfoReq)
+	if err := proto.Unmarshal(b, message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+func marshalStateInfo(height uint64, blockHash []byte, root []byte, nodes []*tas_middleware_pb.TrieNode) ([]byte, error) {
+	if blockHash == nil {
+		blockHash = []byte{}
+	}
+	if root == nil {
+		root = []byte{}
+	}
+	message := tas_middleware_pb.StateInfo{Height: &height, TrieNodes: nodes, BlockHash: blockHash, ProBlockStateRoot: root}
+	return proto.Marshal(&message)
+}
+
+func unmarshalStateInfo(b []byte) (*tas_middleware_pb.StateInfo, error) {
+	message := new(tas_middleware_pb.StateInfo)
+	if err := proto.Unmarshal(b, message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
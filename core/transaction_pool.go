@@ -34,13 +34,19 @@ const (
 	txAccumulateSizeMaxPerBlock = 1024 * 1024
 	gasLimitMax                 = 500000
 
+	// Default minimum gas price bump percentage to replace a pending transaction
+	defaultPriceBump = 10
+
 	// Maximum size per transaction
 	txMaxSize = 64000
 )
 
 var (
-	ErrNil  = errors.New("nil transaction")
-	ErrHash = errors.New("invalid transaction hash")
+	ErrNil      = errors.New("nil transaction")
+	ErrHash     = errors.New("invalid transaction hash")
+	ErrPoolFull = errors.New("transaction pool is full")
+
+	ErrReplaceUnderpriced = errors.New("replacement transaction underpriced")
 )
 
 type txPool struct {
@@ -50,9 +56,11 @@ type txPool struct {
 	// when add block on chain, does not participate in the broadcast
 
 	receiptDb          *tasdb.PrefixedDatabase
+	journal            *txJournal // Persists the locally submitted transactions
 	batch              tasdb.Batch
 	chain              BlockChain
 	gasPriceLowerBound uint64
+	gasPriceFloor      uint64 // Gas price floor of the genesis spec, the cheaper transactions are rejected
 	lock               sync.RWMutex
 }
 
@@ -70,17 +78,30 @@ func (m *txPoolAddMessage) GetData() interface{} {
 }
 
 // newTransactionPool returns a new transaction tool object
-func newTransactionPool(chain *FullBlockChain, receiptDb *tasdb.PrefixedDatabase) TransactionPool {
+func newTransactionPool(chain *FullBlockChain, receiptDb *tasdb.PrefixedDatabase, journalDb *tasdb.PrefixedDatabase) TransactionPool {
 	pool := &txPool{
 		receiptDb:          receiptDb,
+		journal:            newTxJournal(journalDb),
 		batch:              chain.batch,
 		asyncAdds:          common.MustNewLRUCache(txCountPerBlock * maxReqBlockCount),
 		chain:              chain,
 		gasPriceLowerBound: uint64(common.GlobalConf.GetInt("chain", "gasprice_lower_bound", 1)),
+		gasPriceFloor:      chain.genesis.GasPriceFloor,
+	}
+	if pool.gasPriceLowerBound < pool.gasPriceFloor {
+		pool.gasPriceLowerBound = pool.gasPriceFloor
 	}
-	pool.received = newSimpleContainer(maxTxPoolSize)
+	priceBump := common.GlobalConf.GetInt("chain", "tx_price_bump", defaultPriceBump)
+	if priceBump < 0 {
+		priceBump = defaultPriceBump
+	}
+	pool.received = newSimpleContainer(maxTxPoolSize, uint64(priceBump), chain)
 	pool.bonPool = newBonusPool(chain.bonusManager, bonusTxMaxSize)
 	initTxSyncer(chain, pool)
+	notify.BUS.Subscribe(notify.BlockAddSucc, pool.onBlockAddSuccess)
+
+	chain.ticker.RegisterPeriodicRoutine(txJournalRotateRoutine, pool.rotateJournalRoutine, txJournalRotateInterval)
+	chain.ticker.StartTickerRoutine(txJournalRotateRoutine, false)
 
 	return pool
 }
@@ -97,9 +118,33 @@ func (pool *txPool) tryAddTransaction(tx *types.Transaction, from txSource) (boo
 	return b, err
 }
 
-// AddTransaction try to add a transaction into the tool
+// AddTransaction try to add a transaction into the tool.
+// Non-bonus transactions added successfully are journaled, and will be recovered after restart
 func (pool *txPool) AddTransaction(tx *types.Transaction) (bool, error) {
-	return pool.tryAddTransaction(tx, 0)
+	b, err := pool.tryAddTransaction(tx, 0)
+	if b && tx.Type != types.TransactionTypeBonus {
+		if err := pool.journal.insert(tx); err != nil {
+			Logger.Errorf("journal tx error:hash=%v, err=%v", tx.Hash.Hex(), err)
+		}
+	}
+	return b, err
+}
+
+// loadJournal recovers the journaled transactions into the pool, invalid ones are dropped on next rotation
+func (pool *txPool) loadJournal() {
+	total, dropped := pool.journal.load(func(tx *types.Transaction) error {
+		_, err := pool.tryAddTransaction(tx, 0)
+		return err
+	})
+	Logger.Infof("load tx journal total %v, dropped %v", total, dropped)
+}
+
+func (pool *txPool) rotateJournalRoutine() bool {
+	cnt, err := pool.journal.rotate(func(hash common.Hash) bool {
+		return pool.received.contains(hash) || pool.asyncAdds.Contains(hash)
+	})
+	Logger.Infof("rotate tx journal, removed %v, %v", cnt, err)
+	return true
 }
 
 // AddTransaction try to add a list of transactions into the tool
@@ -162,6 +207,11 @@ func (pool *txPool) GetReceived() []*types.Transaction {
 	return pool.received.asSlice(maxTxPoolSize)
 }
 
+// ReplacementGasPrice returns the minimum gas price required to replace a pool transaction with the given gas price
+func (pool *txPool) ReplacementGasPrice(gasPrice uint64) uint64 {
+	return pool.received.replacementPrice(gasPrice)
+}
+
 // TxNum returns the number of transactions in the pool
 func (pool *txPool) TxNum() uint64 {
 	return uint64(pool.received.Len() + pool.bonPool.len())
@@ -178,15 +228,8 @@ func (pool *txPool) RecoverAndValidateTx(tx *types.Transaction) error {
 	if !tx.Hash.IsValid() {
 		return ErrHash
 	}
-	size := 0
-	if tx.Data != nil {
-		size += len(tx.Data)
-	}
-	if tx.ExtraData != nil {
-		size += len(tx.ExtraData)
-	}
-	if size > txMaxSize {
-		return fmt.Errorf("tx size(%v) should not larger than %v", size, txMaxSize)
+	if err := checkTxSize(tx); err != nil {
+		return err
 	}
 
 	if tx.Hash != tx.GenHash() {
@@ -203,11 +246,8 @@ func (pool *txPool) RecoverAndValidateTx(tx *types.Transaction) error {
 			return err
 		}
 	} else {
-		if tx.GasPrice == 0 {
-			return fmt.Errorf("illegal tx gasPrice")
-		}
-		if tx.GasLimit > gasLimitMax {
-			return fmt.Errorf("gasLimit too  big! max gas limit is 500000 Ra")
+		if err := pool.checkTxGas(tx); err != nil {
+			return err
 		}
 		var sign = common.BytesToSign(tx.Sign)
 		if sign == nil {
@@ -236,6 +276,45 @@ func (pool *txPool) RecoverAndValidateTx(tx *types.Transaction) error {
 	return nil
 }
 
+// CheckIntrinsic checks the size, gas price and gas limit of the transaction the same as it's added to the pool, the
+// signature and nonce are not checked
+func (pool *txPool) CheckIntrinsic(tx *types.Transaction) error {
+	if err := checkTxSize(tx); err != nil {
+		return err
+	}
+	if tx.Type == types.TransactionTypeBonus {
+		return nil
+	}
+	return pool.checkTxGas(tx)
+}
+
+func checkTxSize(tx *types.Transaction) error {
+	size := 0
+	if tx.Data != nil {
+		size += len(tx.Data)
+	}
+	if tx.ExtraData != nil {
+		size += len(tx.ExtraData)
+	}
+	if size > txMaxSize {
+		return fmt.Errorf("tx size(%v) should not larger than %v", size, txMaxSize)
+	}
+	return nil
+}
+
+func (pool *txPool) checkTxGas(tx *types.Transaction) error {
+	if tx.GasPrice == 0 {
+		return fmt.Errorf("illegal tx gasPrice")
+	}
+	if tx.GasPrice < pool.gasPriceFloor {
+		return fmt.Errorf("gasPrice too low! min gas price is %v Ra", pool.gasPriceFloor)
+	}
+	if tx.GasLimit > gasLimitMax {
+		return fmt.Errorf("gasLimit too  big! max gas limit is 500000 Ra")
+	}
+	return nil
+}
+
 func (pool *txPool) tryAdd(tx *types.Transaction) (bool, error) {
 	if tx == nil {
 		return false, ErrNil
@@ -247,20 +326,30 @@ func (pool *txPool) tryAdd(tx *types.Transaction) (bool, error) {
 		return false, fmt.Errorf("tx exist in %v", where)
 	}
 
-	pool.add(tx)
+	if err := pool.add(tx); err != nil {
+		return false, err
+	}
 
 	return true, nil
 }
 
-func (pool *txPool) add(tx *types.Transaction) bool {
+func (pool *txPool) add(tx *types.Transaction) error {
 	if tx.Type == types.TransactionTypeBonus {
 		pool.bonPool.add(tx)
 	} else {
-		pool.received.push(tx)
+		replaced, err := pool.received.push(tx)
+		if err != nil {
+			return err
+		}
+		if replaced != nil {
+			pool.asyncAdds.Remove(replaced.Hash)
+			Logger.Debugf("tx %v replaced by %v, source %v, nonce %v", replaced.Hash.Hex(), tx.Hash.Hex(), tx.Source.Hex(), tx.Nonce)
+		}
+		notify.BUS.Publish(notify.TxPoolAddSucc, &notify.TransactionMessage{Tx: tx})
 	}
 	TxSyncer.add(tx)
 
-	return true
+	return nil
 }
 
 func (pool *txPool) remove(txHash common.Hash) {
@@ -297,22 +386,41 @@ func (pool *txPool) packTx() []*types.Transaction {
 		accuSize += tx.Size()
 		return accuSize < txAccumulateSizeMaxPerBlock
 	})
-	if len(txs) < txAccumulateSizeMaxPerBlock {
-		for _, tx := range pool.received.asSlice(10000) {
-			//gas price too low
+	if accuSize < txAccumulateSizeMaxPerBlock {
+		// Accounts are visited in descending gas price order of their next transaction,
+		// so the rest are all too cheap once the lower bound is reached
+		pool.received.forEachPending(func(tx *types.Transaction) bool {
 			if tx.GasPrice < pool.gasPriceLowerBound {
-				continue
+				return false
 			}
 			txs = append(txs, tx)
 			accuSize += tx.Size()
-			if accuSize >= txAccumulateSizeMaxPerBlock {
-				break
-			}
-		}
+			return accuSize < txAccumulateSizeMaxPerBlock
+		})
 	}
 	return txs
 }
 
+// onBlockAddSuccess promotes the queued transactions of the accounts whose nonce changed in the block
+func (pool *txPool) onBlockAddSuccess(message notify.Message) {
+	block := message.GetData().(*types.Block)
+	if len(block.Transactions) == 0 {
+		return
+	}
+	seen := make(map[common.Address]struct{})
+	accounts := make([]common.Address, 0)
+	for _, tx := range block.Transactions {
+		if tx.Source == nil || tx.Type == types.TransactionTypeBonus {
+			continue
+		}
+		if _, ok := seen[*tx.Source]; !ok {
+			seen[*tx.Source] = struct{}{}
+			accounts = append(accounts, *tx.Source)
+		}
+	}
+	pool.received.promote(accounts)
+}
+
 // RemoveFromPool removes the transactions from pool by hash
 func (pool *txPool) RemoveFromPool(txs []common.Hash) {
 	pool.lock.Lock()
@@ -330,11 +438,13 @@ func (pool *txPool) BackToPool(txs []*types.Transaction) {
 		if txRaw.Type != types.TransactionTypeBonus && txRaw.Source == nil {
 			err := txRaw.RecoverSource()
 			if err != nil {
-				Logger.Errorf("backtopPool recover source fail:tx=%v", txRaw.Hash.Hex())
+				Logger.Errorf("backToPool recover source fail:tx=%v", txRaw.Hash.Hex())
 				continue
 			}
 		}
-		pool.add(txRaw)
+		if err := pool.add(txRaw); err != nil {
+			Logger.Debugf("backToPool add tx fail:tx=%v, err=%v", txRaw.Hash.Hex(), err)
+		}
 	}
 }
 
@@ -98,13 +98,16 @@ func TestMaxTxsPerBlock(t *testing.T) {
 	//chain.latestStateDB = chain.getDB()
 	pool := chain.GetTransactionPool()
 
-	for i := 0; i < 100000; i++ {
+	for i := 0; i < maxTxPoolSize; i++ {
 		transaction := genTestTx(11, "1", "2", uint64(i+1), 3)
 		_, err := pool.AddTransaction(transaction)
 		if err != nil {
-			t.Fatalf("fail to AddTransaction ")
+			t.Fatalf("fail to AddTransaction: %v", err)
 		}
 	}
+	if _, err := pool.AddTransaction(genTestTx(11, "1", "2", uint64(maxTxPoolSize+1), 3)); err != ErrPoolFull {
+		t.Fatalf("expect the pool full, got %v", err)
+	}
 
 	casting := pool.PackForCast()
 	//maxTxPoolSize
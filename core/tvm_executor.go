@@ -33,7 +33,8 @@ const CodeBytePrice = 0.3814697265625
 const MaxCastBlockTime = time.Second * 3
 
 type TVMExecutor struct {
-	bc BlockChain
+	bc     BlockChain
+	tracer tvm.Tracer // Captures the contract execution if set
 }
 
 func NewTVMExecutor(bc BlockChain) *TVMExecutor {
@@ -44,6 +45,14 @@ func NewTVMExecutor(bc BlockChain) *TVMExecutor {
 
 // Execute executes all types transactions and returns the receipts
 func (executor *TVMExecutor) Execute(accountdb *account.AccountDB, bh *types.BlockHeader, txs []*types.Transaction, pack bool, ts *common.TimeStatCtx) (state common.Hash, evits []common.Hash, executed []*types.Transaction, recps []*types.Receipt, err error) {
+	evictedTxs, transactions, receipts := executor.executeBlock(accountdb, bh, txs, pack)
+	state = accountdb.IntermediateRoot(true)
+	return state, evictedTxs, transactions, receipts, nil
+}
+
+// executeBlock executes the transactions and adds the proposal bonus to the castor without computing the state root,
+// so the journal of the account db still records the changes of the block
+func (executor *TVMExecutor) executeBlock(accountdb *account.AccountDB, bh *types.BlockHeader, txs []*types.Transaction, pack bool) (evits []common.Hash, executed []*types.Transaction, recps []*types.Receipt) {
 	beginTime := time.Now()
 	receipts := make([]*types.Receipt, 0)
 	transactions := make([]*types.Transaction, 0)
@@ -55,50 +64,21 @@ func (executor *TVMExecutor) Execute(accountdb *account.AccountDB, bh *types.Blo
 			Logger.Infof("Cast block execute tx time out!Tx hash:%s ", transaction.Hash.Hex())
 			break
 		}
-		var success = false
-		var contractAddress common.Address
-		var logs []*types.Log
-		var cumulativeGasUsed uint64
-
-		if !executor.validateNonce(accountdb, transaction) {
+		if !executor.validateNonce(accountdb, transaction) || !executor.validateGasPrice(transaction) {
 			evictedTxs = append(evictedTxs, transaction.Hash)
 			continue
 		}
 
-		switch transaction.Type {
-		case types.TransactionTypeTransfer:
-			success, _, cumulativeGasUsed = executor.executeTransferTx(accountdb, transaction, castor)
-		case types.TransactionTypeContractCreate:
-			success, _, cumulativeGasUsed, contractAddress = executor.executeContractCreateTx(accountdb, transaction, castor, bh)
-		case types.TransactionTypeContractCall:
-			success, _, cumulativeGasUsed, logs = executor.executeContractCallTx(accountdb, transaction, castor, bh)
-		case types.TransactionTypeBonus:
-			success = executor.executeBonusTx(accountdb, transaction, castor)
-			if !success {
-				evictedTxs = append(evictedTxs, transaction.Hash)
-				// Failed bonus tx should not be included in block
-				continue
-			}
-		case types.TransactionTypeMinerApply:
-			success = executor.executeMinerApplyTx(accountdb, transaction, bh.Height, castor)
-		case types.TransactionTypeMinerAbort:
-			success = executor.executeMinerAbortTx(accountdb, transaction, bh.Height, castor)
-		case types.TransactionTypeMinerRefund:
-			success = executor.executeMinerRefundTx(accountdb, transaction, bh.Height, castor)
-		case types.TransactionTypeMinerCancelStake:
-			success = executor.executeMinerCancelStakeTx(accountdb, transaction, bh.Height, castor)
-		case types.TransactionTypeMinerStake:
-			success = executor.executeMinerStakeTx(accountdb, transaction, bh.Height, castor)
+		success, txErr, gasUsed, contractAddress, logs := executor.executeTransaction(accountdb, bh, castor, transaction)
+		if !success && transaction.Type == types.TransactionTypeBonus {
+			evictedTxs = append(evictedTxs, transaction.Hash)
+			// Failed bonus tx should not be included in block
+			continue
 		}
 
 		idx := len(transactions)
 		transactions = append(transactions, transaction)
-		receipt := types.NewReceipt(nil, !success, cumulativeGasUsed)
-		receipt.Logs = logs
-		receipt.TxHash = transaction.Hash
-		receipt.ContractAddress = contractAddress
-		receipt.TxIndex = uint16(idx)
-		receipt.Height = bh.Height
+		receipt := newReceipt(transaction, success, txErr, gasUsed, contractAddress, logs, idx, bh.Height)
 		receipts = append(receipts, receipt)
 		//errs[i] = err
 		if transaction.Source != nil {
@@ -108,9 +88,77 @@ func (executor *TVMExecutor) Execute(accountdb *account.AccountDB, bh *types.Blo
 	}
 	//ts.AddStat("executeLoop", time.Since(b))
 	accountdb.AddBalance(castor, executor.bc.GetConsensusHelper().ProposalBonus())
+	return evictedTxs, transactions, receipts
+}
 
-	state = accountdb.IntermediateRoot(true)
-	return state, evictedTxs, transactions, receipts, nil
+// Simulate executes a single transaction on the given state as if it's packed into the block of the header, and returns
+// the receipt and the error explaining the failure if any. The state is modified, a throwaway one should be given
+func (executor *TVMExecutor) Simulate(accountdb *account.AccountDB, bh *types.BlockHeader, transaction *types.Transaction) (*types.Receipt, *types.TransactionError) {
+	if transaction.Type == types.TransactionTypeBonus {
+		return nil, types.NewTransactionError(types.SysError, "bonus transaction can not be simulated")
+	}
+	if !executor.validateNonce(accountdb, transaction) {
+		return nil, types.TxErrorNonce
+	}
+	if !executor.validateGasPrice(transaction) {
+		return nil, types.NewTransactionError(types.SysError, "gas price below the floor of the genesis")
+	}
+	success, err, gasUsed, contractAddress, logs := executor.executeTransaction(accountdb, bh, common.BytesToAddress(bh.Castor), transaction)
+	accountdb.SetNonce(*transaction.Source, transaction.Nonce)
+	return newReceipt(transaction, success, err, gasUsed, contractAddress, logs, 0, bh.Height), err
+}
+
+func newReceipt(transaction *types.Transaction, success bool, err *types.TransactionError, gasUsed uint64, contractAddress common.Address, logs []*types.Log, idx int, height uint64) *types.Receipt {
+	receipt := types.NewReceipt(nil, !success, cumulativeGasUsed(transaction, gasUsed))
+	receipt.Logs = logs
+	receipt.TxHash = transaction.Hash
+	receipt.ContractAddress = contractAddress
+	receipt.TxIndex = uint16(idx)
+	receipt.Height = height
+	receipt.GasUsed = gasUsed
+	receipt.Fee = gasUsed * transaction.GasPrice
+	if !success {
+		receipt.SetError(err)
+	}
+	return receipt
+}
+
+// cumulativeGasUsed returns the gas recorded in the receipt tree, which is kept as it used to be: the fee for the
+// transfer, the gas for the contract transactions and zero for the others. The real gas is in the gas used field
+func cumulativeGasUsed(transaction *types.Transaction, gasUsed uint64) uint64 {
+	switch transaction.Type {
+	case types.TransactionTypeTransfer:
+		return gasUsed * transaction.GasPrice
+	case types.TransactionTypeContractCreate, types.TransactionTypeContractCall:
+		return gasUsed
+	}
+	return 0
+}
+
+// executeTransaction executes the transaction according to its type and returns the error explaining the failure if
+// any and the gas charged
+func (executor *TVMExecutor) executeTransaction(accountdb *account.AccountDB, bh *types.BlockHeader, castor common.Address, transaction *types.Transaction) (success bool, err *types.TransactionError, gasUsed uint64, contractAddress common.Address, logs []*types.Log) {
+	switch transaction.Type {
+	case types.TransactionTypeTransfer:
+		success, err, gasUsed = executor.executeTransferTx(accountdb, transaction, castor)
+	case types.TransactionTypeContractCreate:
+		success, err, gasUsed, contractAddress = executor.executeContractCreateTx(accountdb, transaction, castor, bh)
+	case types.TransactionTypeContractCall:
+		success, err, gasUsed, logs = executor.executeContractCallTx(accountdb, transaction, castor, bh)
+	case types.TransactionTypeBonus:
+		success = executor.executeBonusTx(accountdb, transaction, castor)
+	case types.TransactionTypeMinerApply:
+		success, err, gasUsed = executor.executeMinerApplyTx(accountdb, transaction, bh.Height, castor)
+	case types.TransactionTypeMinerAbort:
+		success, err, gasUsed = executor.executeMinerAbortTx(accountdb, transaction, bh.Height, castor)
+	case types.TransactionTypeMinerRefund:
+		success, err, gasUsed = executor.executeMinerRefundTx(accountdb, transaction, bh.Height, castor)
+	case types.TransactionTypeMinerCancelStake:
+		success, err, gasUsed = executor.executeMinerCancelStakeTx(accountdb, transaction, bh.Height, castor)
+	case types.TransactionTypeMinerStake:
+		success, err, gasUsed = executor.executeMinerStakeTx(accountdb, transaction, bh.Height, castor)
+	}
+	return
 }
 
 func (executor *TVMExecutor) validateNonce(accountdb *account.AccountDB, transaction *types.Transaction) bool {
@@ -125,7 +173,20 @@ func (executor *TVMExecutor) validateNonce(accountdb *account.AccountDB, transac
 	return true
 }
 
-func (executor *TVMExecutor) executeTransferTx(accountdb *account.AccountDB, transaction *types.Transaction, castor common.Address) (success bool, err *types.TransactionError, cumulativeGasUsed uint64) {
+// validateGasPrice checks the gas price is not below the floor of the genesis spec, the bonus transactions pay no gas
+func (executor *TVMExecutor) validateGasPrice(transaction *types.Transaction) bool {
+	if transaction.Type == types.TransactionTypeBonus {
+		return true
+	}
+	genesis := executor.bc.Genesis()
+	if genesis == nil || transaction.GasPrice >= genesis.GasPriceFloor {
+		return true
+	}
+	Logger.Infof("Tx gas price below the floor! Hash:%s,gas price:%d,floor:%d", transaction.Hash.Hex(), transaction.GasPrice, genesis.GasPriceFloor)
+	return false
+}
+
+func (executor *TVMExecutor) executeTransferTx(accountdb *account.AccountDB, transaction *types.Transaction, castor common.Address) (success bool, err *types.TransactionError, gasUsed uint64) {
 	success = false
 
 	amount := new(big.Int).SetUint64(transaction.Value)
@@ -138,15 +199,15 @@ func (executor *TVMExecutor) executeTransferTx(accountdb *account.AccountDB, tra
 		transfer(accountdb, *transaction.Source, *transaction.Target, amount)
 		accountdb.SubBalance(*transaction.Source, gasFee)
 		accountdb.AddBalance(castor, gasFee)
-		cumulativeGasUsed = gasFee.Uint64()
+		gasUsed = intriGas
 		success = true
 	} else {
 		err = types.TxErrorBalanceNotEnough
 	}
-	return success, err, cumulativeGasUsed
+	return success, err, gasUsed
 }
 
-func (executor *TVMExecutor) executeContractCreateTx(accountdb *account.AccountDB, transaction *types.Transaction, castor common.Address, bh *types.BlockHeader) (success bool, err *types.TransactionError, cumulativeGasUsed uint64, contractAddress common.Address) {
+func (executor *TVMExecutor) executeContractCreateTx(accountdb *account.AccountDB, transaction *types.Transaction, castor common.Address, bh *types.BlockHeader) (success bool, err *types.TransactionError, gasUsed uint64, contractAddress common.Address) {
 	success = false
 	intriGas, err := intrinsicGas(transaction)
 	if err != nil {
@@ -158,6 +219,7 @@ func (executor *TVMExecutor) executeContractCreateTx(accountdb *account.AccountD
 	if canTransfer(accountdb, *transaction.Source, new(big.Int).SetUint64(0), gasLimitFee) {
 		accountdb.SubBalance(*transaction.Source, gasLimitFee)
 		controller := tvm.NewController(accountdb, BlockChainImpl, bh, transaction, intriGas, common.GlobalConf.GetString("tvm", "pylib", "lib"), MinerManagerImpl, GroupChainImpl)
+		controller.Tracer = executor.tracer
 		snapshot := controller.AccountDB.Snapshot()
 		contractAddress, err = createContract(accountdb, transaction)
 		if err != nil {
@@ -180,17 +242,17 @@ func (executor *TVMExecutor) executeContractCreateTx(accountdb *account.AccountD
 		accountdb.AddBalance(*transaction.Source, returnFee)
 		accountdb.AddBalance(castor, new(big.Int).Sub(gasLimitFee, returnFee))
 
-		cumulativeGasUsed = gasLimit - gasLeft
+		gasUsed = gasLimit - gasLeft
 	} else {
 		success = false
 		err = types.TxErrorBalanceNotEnough
 		Logger.Infof("ContractCreate balance not enough! transaction %s source %s  ", transaction.Hash.Hex(), transaction.Source.Hex())
 	}
 	Logger.Debugf("TVMExecutor Execute ContractCreate Transaction %s,success:%t", transaction.Hash.Hex(), success)
-	return success, err, cumulativeGasUsed, contractAddress
+	return success, err, gasUsed, contractAddress
 }
 
-func (executor *TVMExecutor) executeContractCallTx(accountdb *account.AccountDB, transaction *types.Transaction, castor common.Address, bh *types.BlockHeader) (success bool, err *types.TransactionError, cumulativeGasUsed uint64, logs []*types.Log) {
+func (executor *TVMExecutor) executeContractCallTx(accountdb *account.AccountDB, transaction *types.Transaction, castor common.Address, bh *types.BlockHeader) (success bool, err *types.TransactionError, gasUsed uint64, logs []*types.Log) {
 	success = false
 	transferAmount := new(big.Int).SetUint64(transaction.Value)
 	intriGas, err := intrinsicGas(transaction)
@@ -203,6 +265,7 @@ func (executor *TVMExecutor) executeContractCallTx(accountdb *account.AccountDB,
 	if canTransfer(accountdb, *transaction.Source, transferAmount, gasLimitFee) {
 		accountdb.SubBalance(*transaction.Source, gasLimitFee)
 		controller := tvm.NewController(accountdb, BlockChainImpl, bh, transaction, intriGas, common.GlobalConf.GetString("tvm", "pylib", "lib"), MinerManagerImpl, GroupChainImpl)
+		controller.Tracer = executor.tracer
 		contract := tvm.LoadContract(*transaction.Target)
 		if contract.Code == "" {
 			err = types.NewTransactionError(types.TxErrorCodeNoCode, fmt.Sprintf(types.NoCodeErrorMsg, *transaction.Target))
@@ -222,12 +285,12 @@ func (executor *TVMExecutor) executeContractCallTx(accountdb *account.AccountDB,
 		accountdb.AddBalance(*transaction.Source, returnFee)
 		accountdb.AddBalance(castor, new(big.Int).Sub(gasLimitFee, returnFee))
 
-		cumulativeGasUsed = gasLimit - gasLeft
+		gasUsed = gasLimit - gasLeft
 	} else {
 		err = types.TxErrorBalanceNotEnough
 	}
 	Logger.Debugf("TVMExecutor Execute ContractCall Transaction %s,success:%t", transaction.Hash.Hex(), success)
-	return success, err, cumulativeGasUsed, logs
+	return success, err, gasUsed, logs
 }
 
 func (executor *TVMExecutor) executeBonusTx(accountdb *account.AccountDB, transaction *types.Transaction, castor common.Address) (success bool) {
@@ -256,12 +319,12 @@ func (executor *TVMExecutor) executeBonusTx(accountdb *account.AccountDB, transa
 	return success
 }
 
-func (executor *TVMExecutor) executeMinerApplyTx(accountdb *account.AccountDB, transaction *types.Transaction, height uint64, castor common.Address) (success bool) {
+func (executor *TVMExecutor) executeMinerApplyTx(accountdb *account.AccountDB, transaction *types.Transaction, height uint64, castor common.Address) (success bool, err *types.TransactionError, gasUsed uint64) {
 	Logger.Debugf("Execute miner apply tx:%s,source: %v\n", transaction.Hash.Hex(), transaction.Source.Hex())
 	success = false
 	if transaction.Data == nil {
 		Logger.Debugf("TVMExecutor Execute MinerApply Fail(Tx data is nil) Source:%s Height:%d", transaction.Source.Hex(), height)
-		return success
+		return success, types.TxErrorDataNil, gasUsed
 	}
 
 	intriGas, err := intrinsicGas(transaction)
@@ -279,11 +342,13 @@ func (executor *TVMExecutor) executeMinerApplyTx(accountdb *account.AccountDB, t
 	if canTransfer(accountdb, *transaction.Source, amount, txExecuteFee) {
 		accountdb.SubBalance(*transaction.Source, txExecuteFee)
 		accountdb.AddBalance(castor, txExecuteFee)
+		gasUsed = intriGas
 
 		if mexist != nil {
 			if mexist.Status != types.MinerStatusNormal {
 				if mexist.Type == types.MinerTypeLight && (mexist.Stake+miner.Stake) < common.VerifyStake {
 					Logger.Debugf("TVMExecutor Execute MinerApply Fail((mexist.Stake + miner.Stake) < common.VerifyStake) Source:%s Height:%d", transaction.Source.Hex(), height)
+					err = types.TxErrorStakeNotEnough
 					return
 				}
 				snapshot := accountdb.Snapshot()
@@ -294,9 +359,11 @@ func (executor *TVMExecutor) executeMinerApplyTx(accountdb *account.AccountDB, t
 					success = true
 				} else {
 					accountdb.RevertToSnapshot(snapshot)
+					err = types.TxErrorStakeFail
 				}
 			} else {
 				Logger.Debugf("TVMExecutor Execute MinerApply Fail(Already Exist) Source %s", transaction.Source.Hex())
+				err = types.TxErrorMinerExist
 			}
 			return
 		}
@@ -307,19 +374,22 @@ func (executor *TVMExecutor) executeMinerApplyTx(accountdb *account.AccountDB, t
 			accountdb.SubBalance(*transaction.Source, amount)
 			Logger.Debugf("TVMExecutor Execute MinerApply Success Source:%s Height:%d", transaction.Source.Hex(), height)
 			success = true
+		} else {
+			err = types.TxErrorStakeFail
 		}
 	} else {
 		Logger.Debugf("TVMExecutor Execute MinerApply Fail(Balance Not Enough) Source:%s Height:%d", transaction.Source.Hex(), height)
+		err = types.TxErrorBalanceNotEnough
 	}
-	return success
+	return success, err, gasUsed
 }
 
-func (executor *TVMExecutor) executeMinerStakeTx(accountdb *account.AccountDB, transaction *types.Transaction, height uint64, castor common.Address) (success bool) {
+func (executor *TVMExecutor) executeMinerStakeTx(accountdb *account.AccountDB, transaction *types.Transaction, height uint64, castor common.Address) (success bool, err *types.TransactionError, gasUsed uint64) {
 	Logger.Debugf("Execute miner Stake tx:%s,source: %v\n", transaction.Hash.Hex(), transaction.Source.Hex())
 	success = false
 	if transaction.Data == nil {
 		Logger.Debugf("TVMExecutor Execute Miner Stake Fail(Tx data is nil) Source:%s Height:%d", transaction.Source.Hex(), height)
-		return
+		return success, types.TxErrorDataNil, gasUsed
 	}
 	intriGas, err := intrinsicGas(transaction)
 	if err != nil {
@@ -333,8 +403,10 @@ func (executor *TVMExecutor) executeMinerStakeTx(accountdb *account.AccountDB, t
 	if canTransfer(accountdb, *transaction.Source, amount, txExecuteFee) {
 		accountdb.SubBalance(*transaction.Source, txExecuteFee)
 		accountdb.AddBalance(castor, txExecuteFee)
+		gasUsed = intriGas
 		if mexist == nil {
 			success = false
+			err = types.TxErrorMinerNotExist
 			Logger.Debugf("TVMExecutor Execute Miner Stake Fail(Do not exist this Miner) Source:%s Height:%d", transaction.Source.Hex(), height)
 		} else {
 			snapshot := accountdb.Snapshot()
@@ -344,20 +416,22 @@ func (executor *TVMExecutor) executeMinerStakeTx(accountdb *account.AccountDB, t
 				success = true
 			} else {
 				accountdb.RevertToSnapshot(snapshot)
+				err = types.TxErrorStakeFail
 			}
 		}
 	} else {
 		Logger.Debugf("TVMExecutor Execute Miner Stake Fail(Balance Not Enough) Source:%s Height:%d", transaction.Source.Hex(), height)
+		err = types.TxErrorBalanceNotEnough
 	}
-	return success
+	return success, err, gasUsed
 }
 
-func (executor *TVMExecutor) executeMinerCancelStakeTx(accountdb *account.AccountDB, transaction *types.Transaction, height uint64, castor common.Address) (success bool) {
+func (executor *TVMExecutor) executeMinerCancelStakeTx(accountdb *account.AccountDB, transaction *types.Transaction, height uint64, castor common.Address) (success bool, err *types.TransactionError, gasUsed uint64) {
 	Logger.Debugf("Execute miner cancel pledge tx:%s,source: %v\n", transaction.Hash.Hex(), transaction.Source.Hex())
 	success = false
 	if transaction.Data == nil {
 		Logger.Debugf("TVMExecutor Execute MinerCancelStake Fail(Tx data is nil) Source:%s Height:%d", transaction.Source.Hex(), height)
-		return
+		return success, types.TxErrorDataNil, gasUsed
 	}
 
 	intriGas, err := intrinsicGas(transaction)
@@ -370,11 +444,12 @@ func (executor *TVMExecutor) executeMinerCancelStakeTx(accountdb *account.Accoun
 	mexist := MinerManagerImpl.GetMinerByID(id, _type, accountdb)
 	if mexist == nil {
 		Logger.Debugf("TVMExecutor Execute MinerCancelStake Fail(Can not find miner) Source %s", transaction.Source.Hex())
-		return
+		return success, types.TxErrorMinerNotExist, gasUsed
 	}
 	if canTransfer(accountdb, *transaction.Source, big.NewInt(0), txExecuteFee) {
 		accountdb.SubBalance(*transaction.Source, txExecuteFee)
 		accountdb.AddBalance(castor, txExecuteFee)
+		gasUsed = intriGas
 		snapshot := accountdb.Snapshot()
 		if MinerManagerImpl.CancelStake(transaction.Source[:], mexist, value, accountdb, height) &&
 			MinerManagerImpl.ReduceStake(mexist.ID, mexist, value, accountdb, height) {
@@ -383,12 +458,15 @@ func (executor *TVMExecutor) executeMinerCancelStakeTx(accountdb *account.Accoun
 		} else {
 			Logger.Debugf("TVMExecutor Execute MinerCancelStake Fail(CancelStake or ReduceStake error) Source %s", transaction.Source.Hex())
 			accountdb.RevertToSnapshot(snapshot)
+			err = types.TxErrorStakeFail
 		}
+	} else {
+		err = types.TxErrorBalanceNotEnough
 	}
 	return
 }
 
-func (executor *TVMExecutor) executeMinerAbortTx(accountdb *account.AccountDB, transaction *types.Transaction, height uint64, castor common.Address) (success bool) {
+func (executor *TVMExecutor) executeMinerAbortTx(accountdb *account.AccountDB, transaction *types.Transaction, height uint64, castor common.Address) (success bool, err *types.TransactionError, gasUsed uint64) {
 	success = false
 
 	intriGas, err := intrinsicGas(transaction)
@@ -399,17 +477,24 @@ func (executor *TVMExecutor) executeMinerAbortTx(accountdb *account.AccountDB, t
 	if canTransfer(accountdb, *transaction.Source, new(big.Int).SetUint64(0), txExecuteFee) {
 		accountdb.SubBalance(*transaction.Source, txExecuteFee)
 		accountdb.AddBalance(castor, txExecuteFee)
+		gasUsed = intriGas
 		if transaction.Data != nil {
 			success = MinerManagerImpl.abortMiner(transaction.Source[:], transaction.Data[0], height, accountdb)
+			if !success {
+				err = types.TxErrorAbortFail
+			}
+		} else {
+			err = types.TxErrorDataNil
 		}
 	} else {
 		Logger.Debugf("TVMExecutor Execute MinerAbort Fail(Balance Not Enough) Source:%s Height:%d ", transaction.Source.Hex(), height)
+		err = types.TxErrorBalanceNotEnough
 	}
 	Logger.Debugf("TVMExecutor Execute MinerAbort Tx %s,Source:%s, Success:%t", transaction.Hash.Hex(), transaction.Source.Hex(), success)
-	return success
+	return success, err, gasUsed
 }
 
-func (executor *TVMExecutor) executeMinerRefundTx(accountdb *account.AccountDB, transaction *types.Transaction, height uint64, castor common.Address) (success bool) {
+func (executor *TVMExecutor) executeMinerRefundTx(accountdb *account.AccountDB, transaction *types.Transaction, height uint64, castor common.Address) (success bool, err *types.TransactionError, gasUsed uint64) {
 	success = false
 	intriGas, err := intrinsicGas(transaction)
 	if err != nil {
@@ -420,9 +505,10 @@ func (executor *TVMExecutor) executeMinerRefundTx(accountdb *account.AccountDB,
 	if canTransfer(accountdb, *transaction.Source, new(big.Int).SetUint64(0), txExecuteFee) {
 		accountdb.SubBalance(*transaction.Source, txExecuteFee)
 		accountdb.AddBalance(castor, txExecuteFee)
+		gasUsed = intriGas
 	} else {
 		Logger.Debugf("TVMExecutor Execute MinerRefund Fail(Balance Not Enough) Hash:%s,Source:%s", transaction.Hash.Hex(), transaction.Source.Hex())
-		return success
+		return success, types.TxErrorBalanceNotEnough, gasUsed
 	}
 	var _type, id, _ = MinerManagerImpl.Transaction2MinerParams(transaction)
 	mexist := MinerManagerImpl.GetMinerByID(id, _type, accountdb)
@@ -439,6 +525,7 @@ func (executor *TVMExecutor) executeMinerRefundTx(accountdb *account.AccountDB,
 				value, ok := MinerManagerImpl.RefundStake(transaction.Source.Bytes(), mexist, accountdb)
 				if !ok {
 					success = false
+					err = types.TxErrorRefundFail
 					return
 				}
 				amount := new(big.Int).SetUint64(value)
@@ -447,11 +534,13 @@ func (executor *TVMExecutor) executeMinerRefundTx(accountdb *account.AccountDB,
 				success = true
 			} else {
 				Logger.Debugf("TVMExecutor Execute MinerRefund Heavy Fail(Refund height less than abortHeight+10) Hash%s", transaction.Source.Hex())
+				err = types.TxErrorRefundNotAllowed
 			}
 		} else {
 			value, ok := MinerManagerImpl.RefundStake(transaction.Source.Bytes(), mexist, accountdb)
 			if !ok {
 				success = false
+				err = types.TxErrorRefundFail
 				return
 			}
 			amount := new(big.Int).SetUint64(value)
@@ -461,8 +550,9 @@ func (executor *TVMExecutor) executeMinerRefundTx(accountdb *account.AccountDB,
 		}
 	} else {
 		Logger.Debugf("TVMExecutor Execute MinerRefund Fail(Not Exist Or Not Abort) %s", transaction.Source.Hex())
+		err = types.TxErrorMinerNotExist
 	}
-	return success
+	return success, err, gasUsed
 }
 
 func createContract(accountdb *account.AccountDB, transaction *types.Transaction) (common.Address, *types.TransactionError) {
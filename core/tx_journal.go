@@ -0,0 +1,104 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"sync"
+
+	"github.com/taschain/taschain/common"
+	"github.com/taschain/taschain/middleware/types"
+	"github.com/taschain/taschain/storage/tasdb"
+)
+
+const (
+	txJournalRotateRoutine  = "tx_journal_rotate"
+	txJournalRotateInterval = 600
+)
+
+// txJournal persists the locally submitted transactions so that they can be recovered into the pool
+// after restart. Transactions are keyed by hash, and those no longer in the pool are dropped on rotation
+type txJournal struct {
+	db   *tasdb.PrefixedDatabase
+	lock sync.Mutex
+}
+
+func newTxJournal(db *tasdb.PrefixedDatabase) *txJournal {
+	return &txJournal{
+		db: db,
+	}
+}
+
+// insert writes the transaction into the journal
+func (j *txJournal) insert(tx *types.Transaction) error {
+	bs, err := types.MarshalTransaction(tx)
+	if err != nil {
+		return err
+	}
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	return j.db.Put(tx.Hash.Bytes(), bs)
+}
+
+// load reads all the journaled transactions and feeds them to the given function one by one
+func (j *txJournal) load(add func(tx *types.Transaction) error) (total int, dropped int) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	iter := j.db.NewIterator()
+	defer iter.Release()
+
+	for iter.Next() {
+		total++
+		tx, err := types.UnMarshalTransaction(iter.Value())
+		if err != nil {
+			Logger.Errorf("unmarshal journal tx error:%v, key=%v", err, common.ToHex(iter.Key()))
+			dropped++
+			continue
+		}
+		if err := add(tx); err != nil {
+			Logger.Debugf("load journal tx fail:hash=%v, err=%v", tx.Hash.Hex(), err)
+			dropped++
+		}
+	}
+	return
+}
+
+// rotate removes the transactions for which the keep function returns false from the journal
+func (j *txJournal) rotate(keep func(hash common.Hash) bool) (int, error) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	iter := j.db.NewIterator()
+	defer iter.Release()
+
+	batch := j.db.NewBatch()
+	cnt := 0
+	for iter.Next() {
+		hash := common.BytesToHash(iter.Key())
+		if !keep(hash) {
+			batch.Delete(hash.Bytes())
+			cnt++
+		}
+	}
+	if cnt == 0 {
+		return 0, nil
+	}
+	if err := batch.Write(); err != nil {
+		return 0, err
+	}
+	return cnt, nil
+}
@@ -0,0 +1,152 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+
+	"github.com/taschain/taschain/common"
+	"github.com/taschain/taschain/middleware/types"
+	"github.com/taschain/taschain/storage/serialize"
+	"github.com/taschain/taschain/storage/trie"
+)
+
+// TxProof proves the transaction is included in the block. The tx tree of the header is the hash of all the
+// transaction hashes concatenated in order, so the proof carries all of them and the index of the proven one
+type TxProof struct {
+	BlockHash common.Hash
+	Height    uint64
+	TxTree    common.Hash
+	Index     int
+	TxHashes  []common.Hash
+}
+
+// ReceiptProof proves the receipt of the transaction is included in the block. The proof is the encoded receipt
+// trie nodes on the path to the key of the transaction index
+type ReceiptProof struct {
+	BlockHash   common.Hash
+	Height      uint64
+	ReceiptTree common.Hash
+	Index       int
+	Receipt     *types.Receipt
+	Proof       [][]byte
+}
+
+// VerifyTxProof checks the transaction hash is the one at the index of the proof under the tx tree of the header
+func VerifyTxProof(txTree common.Hash, txHash common.Hash, proof *TxProof) error {
+	if proof.Index < 0 || proof.Index >= len(proof.TxHashes) {
+		return fmt.Errorf("tx index %v out of range", proof.Index)
+	}
+	if proof.TxHashes[proof.Index] != txHash {
+		return fmt.Errorf("tx hash mismatch at index %v", proof.Index)
+	}
+	txs := make([]*types.Transaction, len(proof.TxHashes))
+	for i, hash := range proof.TxHashes {
+		txs[i] = &types.Transaction{Hash: hash}
+	}
+	if root := calcTxTree(txs); root != txTree {
+		return fmt.Errorf("tx tree mismatch: have %v, want %v", root.Hex(), txTree.Hex())
+	}
+	return nil
+}
+
+// VerifyReceiptProof checks the proof against the receipt tree of the header and returns the receipt of the
+// transaction at the index
+func VerifyReceiptProof(receiptTree common.Hash, index int, proof [][]byte) (*types.Receipt, error) {
+	enc, err := trie.VerifyProof(receiptTree, receiptKey(index), proof)
+	if err != nil {
+		return nil, err
+	}
+	if len(enc) == 0 {
+		return nil, fmt.Errorf("no receipt at index %v", index)
+	}
+	receipt := new(types.Receipt)
+	if err := serialize.DecodeBytes(enc, receipt); err != nil {
+		return nil, fmt.Errorf("decode receipt error: %v", err)
+	}
+	return receipt, nil
+}
+
+// txBlock returns the block on the canonical chain including the transaction, and the receipt of the transaction
+func (chain *FullBlockChain) txBlock(txHash common.Hash) (*types.Block, *types.Receipt, error) {
+	receipt, err := chain.GetReceipt(txHash)
+	if err != nil {
+		return nil, nil, err
+	}
+	b := chain.QueryBlockByHeight(receipt.Height)
+	if b == nil {
+		return nil, nil, fmt.Errorf("block at height %v not found", receipt.Height)
+	}
+	idx := int(receipt.TxIndex)
+	if idx >= len(b.Transactions) || b.Transactions[idx].Hash != txHash {
+		return nil, nil, fmt.Errorf("tx %v not found in block %v", txHash.Hex(), b.Header.Hash.Hex())
+	}
+	return b, receipt, nil
+}
+
+// GetTxProof returns the proof of the transaction included in the block on the canonical chain
+func (chain *FullBlockChain) GetTxProof(txHash common.Hash) (*TxProof, error) {
+	b, receipt, err := chain.txBlock(txHash)
+	if err != nil {
+		return nil, err
+	}
+	proof := &TxProof{
+		BlockHash: b.Header.Hash,
+		Height:    b.Header.Height,
+		TxTree:    b.Header.TxTree,
+		Index:     int(receipt.TxIndex),
+		TxHashes:  make([]common.Hash, len(b.Transactions)),
+	}
+	for i, tx := range b.Transactions {
+		proof.TxHashes[i] = tx.Hash
+	}
+	return proof, nil
+}
+
+// GetReceiptProof returns the proof of the receipt of the transaction included in the block on the canonical chain.
+// The receipt trie is rebuilt from the stored receipts of the block, which must match the receipt tree of the header
+func (chain *FullBlockChain) GetReceiptProof(txHash common.Hash) (*ReceiptProof, error) {
+	b, receipt, err := chain.txBlock(txHash)
+	if err != nil {
+		return nil, err
+	}
+	receipts := make(types.Receipts, len(b.Transactions))
+	for i, tx := range b.Transactions {
+		if receipts[i] = chain.transactionPool.GetReceipt(tx.Hash); receipts[i] == nil {
+			if b.Header.Height <= chain.fastSyncedHeight {
+				return nil, fmt.Errorf("receipts of block %v are %v", b.Header.Hash.Hex(), ErrFastSynced)
+			}
+			return nil, fmt.Errorf("receipt of tx %v not found", tx.Hash.Hex())
+		}
+	}
+	tr := buildReceiptsTrie(receipts)
+	if root := common.BytesToHash(tr.Hash().Bytes()); root != b.Header.ReceiptTree {
+		return nil, fmt.Errorf("receipt tree mismatch: have %v, want %v", root.Hex(), b.Header.ReceiptTree.Hex())
+	}
+	idx := int(receipt.TxIndex)
+	proof, err := tr.Prove(receiptKey(idx))
+	if err != nil {
+		return nil, err
+	}
+	return &ReceiptProof{
+		BlockHash:   b.Header.Hash,
+		Height:      b.Header.Height,
+		ReceiptTree: b.Header.ReceiptTree,
+		Index:       idx,
+		Receipt:     receipts[idx],
+		Proof:       proof,
+	}, nil
+}
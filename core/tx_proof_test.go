@@ -0,0 +1,112 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/taschain/taschain/common"
+	"github.com/taschain/taschain/middleware/types"
+	"github.com/vmihailenco/msgpack"
+)
+
+func TestTxProof(t *testing.T) {
+	txs := getRandomTxs()
+	hashes := make([]common.Hash, len(txs))
+	for i, tx := range txs {
+		tx.Hash = tx.GenHash()
+		hashes[i] = tx.Hash
+	}
+	root := calcTxTree(txs)
+	for i, tx := range txs {
+		proof := &TxProof{TxTree: root, Index: i, TxHashes: hashes}
+		if err := VerifyTxProof(root, tx.Hash, proof); err != nil {
+			t.Fatalf("verify tx %v error %v", i, err)
+		}
+	}
+	proof := &TxProof{TxTree: root, Index: 1, TxHashes: hashes}
+	if err := VerifyTxProof(root, hashes[2], proof); err == nil {
+		t.Errorf("wrong index accepted")
+	}
+	proof.Index = len(hashes)
+	if err := VerifyTxProof(root, hashes[1], proof); err == nil {
+		t.Errorf("index out of range accepted")
+	}
+	proof = &TxProof{TxTree: root, Index: 0, TxHashes: hashes[:len(hashes)-1]}
+	if err := VerifyTxProof(root, hashes[0], proof); err == nil {
+		t.Errorf("incomplete tx hashes accepted")
+	}
+}
+
+func TestReceiptProof(t *testing.T) {
+	txs := getRandomTxs()
+	receipts := make(types.Receipts, len(txs))
+	for i, tx := range txs {
+		tx.Hash = tx.GenHash()
+		logs := []*types.Log{{Address: common.BytesToAddress([]byte{byte(i)}), Data: []byte{byte(i)}, TxHash: tx.Hash}}
+		receipts[i] = newReceipt(tx, i%2 == 0, nil, uint64(i*100), common.Address{}, logs, i, 10)
+	}
+	root := calcReceiptsTree(receipts)
+
+	// The proofs are built from the receipts loaded from the store
+	for i, r := range receipts {
+		data, err := msgpack.Marshal(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		receipts[i] = new(types.Receipt)
+		if err := msgpack.Unmarshal(data, receipts[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tr := buildReceiptsTrie(receipts)
+	if common.BytesToHash(tr.Hash().Bytes()) != root {
+		t.Fatalf("receipt tree of the stored receipts mismatch")
+	}
+	for i := range receipts {
+		proof, err := tr.Prove(receiptKey(i))
+		if err != nil {
+			t.Fatalf("prove receipt %v error %v", i, err)
+		}
+		r, err := VerifyReceiptProof(root, i, proof)
+		if err != nil {
+			t.Fatalf("verify receipt %v error %v", i, err)
+		}
+		if r.TxHash != txs[i].Hash || int(r.TxIndex) != i || r.Status != receipts[i].Status {
+			t.Errorf("receipt %v mismatch: %v", i, r)
+		}
+	}
+	proof, _ := tr.Prove(receiptKey(3))
+	if _, err := VerifyReceiptProof(root, 4, proof); err == nil {
+		t.Errorf("proof of another index accepted")
+	}
+	if _, err := VerifyReceiptProof(root, len(receipts), proof); err == nil {
+		t.Errorf("absent receipt accepted")
+	}
+}
+
+func TestReceiptNotFoundFastSynced(t *testing.T) {
+	hash := common.BytesToHash([]byte{1})
+	chain := &FullBlockChain{}
+	if err := chain.receiptNotFound(hash); strings.Contains(err.Error(), ErrFastSynced.Error()) {
+		t.Errorf("fast sync reported on a full synced chain: %v", err)
+	}
+	chain.fastSyncedHeight = 100
+	if err := chain.receiptNotFound(hash); !strings.Contains(err.Error(), ErrFastSynced.Error()) {
+		t.Errorf("fast sync not reported: %v", err)
+	}
+}
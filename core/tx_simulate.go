@@ -0,0 +1,100 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/taschain/taschain/common"
+	"github.com/taschain/taschain/middleware/types"
+	"github.com/taschain/taschain/storage/account"
+)
+
+// AccountDelta is the balance and nonce change of an account caused by the simulated transaction
+type AccountDelta struct {
+	Address       common.Address
+	BalanceBefore *big.Int
+	BalanceAfter  *big.Int
+	NonceBefore   uint64
+	NonceAfter    uint64
+}
+
+// SimulateResult is the result of the transaction executed on a throwaway state
+type SimulateResult struct {
+	Receipt *types.Receipt
+	Err     *types.TransactionError
+	Deltas  []*AccountDelta
+}
+
+// SimulateTransaction executes the transaction on a throwaway snapshot of the state at the top block as if it's
+// packed into the next block. The source of the transaction must be set, while the signature is not verified. The
+// transaction must pass the size, gas price and gas limit checks of the pool. The deltas cover the source, the target
+// and the created contract. The castor of the next block is unknown, so the fee is only reflected in the source balance
+func (chain *FullBlockChain) SimulateTransaction(tx *types.Transaction) (*SimulateResult, error) {
+	if tx.Source == nil {
+		return nil, fmt.Errorf("source address required")
+	}
+	if err := chain.transactionPool.CheckIntrinsic(tx); err != nil {
+		return nil, err
+	}
+	// The tvm is not reentrant, simulations are serialized with block casting and adding
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	top := chain.QueryTopBlock()
+	before, err := account.NewAccountDB(top.StateTree, chain.stateCache)
+	if err != nil {
+		return nil, err
+	}
+	state, err := account.NewAccountDB(top.StateTree, chain.stateCache)
+	if err != nil {
+		return nil, err
+	}
+	bh := *top
+	bh.Height = top.Height + 1
+	bh.PreHash = top.Hash
+
+	receipt, txErr := chain.executor.Simulate(state, &bh, tx)
+	result := &SimulateResult{
+		Receipt: receipt,
+		Err:     txErr,
+		Deltas:  make([]*AccountDelta, 0),
+	}
+
+	addrs := []common.Address{*tx.Source}
+	if tx.Target != nil {
+		addrs = append(addrs, *tx.Target)
+	}
+	if receipt != nil && receipt.ContractAddress != (common.Address{}) {
+		addrs = append(addrs, receipt.ContractAddress)
+	}
+	seen := make(map[common.Address]bool)
+	for _, addr := range addrs {
+		if seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		result.Deltas = append(result.Deltas, &AccountDelta{
+			Address:       addr,
+			BalanceBefore: before.GetBalance(addr),
+			BalanceAfter:  state.GetBalance(addr),
+			NonceBefore:   before.GetNonce(addr),
+			NonceAfter:    state.GetNonce(addr),
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,108 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+
+	"github.com/taschain/taschain/common"
+	"github.com/taschain/taschain/middleware/types"
+	"github.com/taschain/taschain/tvm"
+)
+
+// TxTrace is the execution trace of a transaction re-executed on the state it was executed on
+type TxTrace struct {
+	TxHash  common.Hash
+	Receipt *types.Receipt
+	Err     *types.TransactionError
+
+	// Call is the call tree of the contract execution, nil if the transaction runs no contract
+	Call *tvm.CallFrame
+}
+
+// TraceTransaction re-executes the transaction on the state of the parent block, after the transactions before it in
+// the block, and returns its trace. The state of the parent block must not have been pruned
+func (chain *FullBlockChain) TraceTransaction(txHash common.Hash) (*TxTrace, error) {
+	b, receipt, err := chain.txBlock(txHash)
+	if err != nil {
+		return nil, err
+	}
+	traces, err := chain.traceBlock(b, int(receipt.TxIndex))
+	if err != nil {
+		return nil, err
+	}
+	return traces[0], nil
+}
+
+// TraceBlock re-executes all the transactions of the block on the state of the parent block and returns their traces
+func (chain *FullBlockChain) TraceBlock(hash common.Hash) ([]*TxTrace, error) {
+	b := chain.QueryBlockByHash(hash)
+	if b == nil {
+		return nil, fmt.Errorf("block %v not found", hash.Hex())
+	}
+	return chain.traceBlock(b, -1)
+}
+
+// traceBlock re-executes the transactions of the block up to the given index, and traces the transaction at the
+// index or all the transactions if the index is negative
+func (chain *FullBlockChain) traceBlock(b *types.Block, index int) ([]*TxTrace, error) {
+	if b.Header.Height == 0 {
+		return nil, fmt.Errorf("genesis block can't be traced")
+	}
+	pre := chain.QueryBlockHeaderByHash(b.Header.PreHash)
+	if pre == nil {
+		return nil, fmt.Errorf("parent block %v not found", b.Header.PreHash.Hex())
+	}
+	state, err := chain.stateAt(pre)
+	if err != nil {
+		return nil, err
+	}
+
+	// The tvm is not reentrant, traces are serialized with block casting and adding
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	executor := NewTVMExecutor(chain)
+	castor := common.BytesToAddress(b.Header.Castor)
+	traces := make([]*TxTrace, 0)
+	for i, tx := range b.Transactions {
+		if index >= 0 && i > index {
+			break
+		}
+		tracer := tvm.NewCallTracer()
+		executor.tracer = nil
+		if index < 0 || i == index {
+			executor.tracer = tracer
+		}
+		success, txErr, gasUsed, contractAddress, logs := executor.executeTransaction(state, b.Header, castor, tx)
+		if tx.Source != nil {
+			state.SetNonce(*tx.Source, tx.Nonce)
+		}
+		if executor.tracer == nil {
+			continue
+		}
+		trace := &TxTrace{
+			TxHash:  tx.Hash,
+			Receipt: newReceipt(tx, success, txErr, gasUsed, contractAddress, logs, i, b.Header.Height),
+			Call:    tracer.Result(),
+		}
+		if !success {
+			trace.Err = txErr
+		}
+		traces = append(traces, trace)
+	}
+	return traces, nil
+}
@@ -21,6 +21,8 @@ const (
 
 	GroupAddSucc = "group_add_succ"
 
+	ChainReorg = "chain_reorg"
+
 	NewBlock = "new_block"
 
 	NewBlockHeader = "new_block_header"
@@ -63,4 +65,6 @@ const (
 	TxSyncResponse = "tx_sync_response"
 
 	TxPoolAddTxs = "tx_pool_add_txs"
+
+	TxPoolAddSucc = "tx_pool_add_succ"
 )
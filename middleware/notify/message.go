@@ -30,6 +30,22 @@ func (m *BlockOnChainSuccMessage) GetData() interface{} {
 	return m.Block
 }
 
+// ChainReorgMessage is published when the chain is rewound to the common ancestor. Removed are the blocks of the
+// old branch from the old top, Added are the blocks of the new branch added in the same operation from the ancestor,
+// the later ones are notified by BlockAddSucc only
+type ChainReorgMessage struct {
+	Ancestor *types.BlockHeader
+	Removed  []*types.BlockHeader
+	Added    []*types.BlockHeader
+}
+
+func (m *ChainReorgMessage) GetRaw() []byte {
+	return []byte{}
+}
+func (m *ChainReorgMessage) GetData() interface{} {
+	return m
+}
+
 type GroupMessage struct {
 	Group *types.Group
 }
@@ -41,6 +57,17 @@ func (m *GroupMessage) GetData() interface{} {
 	return m.Group
 }
 
+type TransactionMessage struct {
+	Tx *types.Transaction
+}
+
+func (m *TransactionMessage) GetRaw() []byte {
+	return []byte{}
+}
+func (m *TransactionMessage) GetData() interface{} {
+	return m.Tx
+}
+
 // DefaultMessage is a default implementation of the Message interface.
 // It can meet most of demands abort chain event
 type DefaultMessage struct {
@@ -44,6 +44,15 @@ const (
 	TxErrorCodeContractAddressConflict = 2
 	TxErrorCodeDeployGasNotEnough      = 3
 	TxErrorCodeNoCode                  = 4
+	TxErrorCodeDataNil                 = 5
+	TxErrorCodeMinerNotExist           = 6
+	TxErrorCodeMinerExist              = 7
+	TxErrorCodeStakeNotEnough          = 8
+	TxErrorCodeStakeFail               = 9
+	TxErrorCodeAbortFail               = 10
+	TxErrorCodeRefundNotAllowed        = 11
+	TxErrorCodeRefundFail              = 12
+	TxErrorCodeNonce                   = 13
 
 	SyntaxError  = 1001
 	GasNotEnough = 1002
@@ -71,6 +80,15 @@ var (
 	TxErrorBalanceNotEnough   = NewTransactionError(TxErrorCodeBalanceNotEnough, "balance not enough")
 	TxErrorDeployGasNotEnough = NewTransactionError(TxErrorCodeDeployGasNotEnough, "gas not enough")
 	TxErrorABIJSON            = NewTransactionError(SysABIJSONError, "abi json format error")
+	TxErrorDataNil            = NewTransactionError(TxErrorCodeDataNil, "tx data is nil")
+	TxErrorMinerNotExist      = NewTransactionError(TxErrorCodeMinerNotExist, "miner not exist")
+	TxErrorMinerExist         = NewTransactionError(TxErrorCodeMinerExist, "miner already exist")
+	TxErrorStakeNotEnough     = NewTransactionError(TxErrorCodeStakeNotEnough, "stake not enough")
+	TxErrorStakeFail          = NewTransactionError(TxErrorCodeStakeFail, "stake operation failed")
+	TxErrorAbortFail          = NewTransactionError(TxErrorCodeAbortFail, "miner abort failed")
+	TxErrorRefundNotAllowed   = NewTransactionError(TxErrorCodeRefundNotAllowed, "refund not allowed at current height")
+	TxErrorRefundFail         = NewTransactionError(TxErrorCodeRefundFail, "refund stake failed")
+	TxErrorNonce              = NewTransactionError(TxErrorCodeNonce, "nonce error")
 )
 
 type TransactionError struct {
@@ -0,0 +1,256 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/taschain/taschain/common"
+	"gopkg.in/yaml.v3"
+)
+
+// Genesis is the declarative spec of the genesis block and the chain wide parameters.
+// All the amounts are denominated in RA
+type Genesis struct {
+	ChainID       uint16                    `json:"chainId"`
+	Timestamp     int64                     `json:"timestamp"` // unix seconds of the genesis block
+	ExtraData     string                    `json:"extraData"`
+	GasPriceFloor uint64                    `json:"gasPriceFloor"` // transactions paying less are rejected by the pool and in blocks, 0 means no floor
+	Alloc         map[string]GenesisAccount `json:"alloc"`         // initial balances keyed by the hex address
+
+	// TestAccounts are exempted from the nonce check for performance testing
+	TestAccounts []string `json:"testAccounts,omitempty"`
+
+	// MinerStake and MinerBalance apply to the genesis group members not listed in Miners
+	MinerStake   uint64         `json:"minerStake"`
+	MinerBalance *big.Int       `json:"minerBalance"`
+	Miners       []GenesisMiner `json:"miners,omitempty"`
+
+	// Consensus overrides the consensus params of the config file, nil means no override
+	Consensus *GenesisConsensus `json:"consensus,omitempty"`
+}
+
+// GenesisAccount is the initial state of an account
+type GenesisAccount struct {
+	Balance *big.Int `json:"balance"`
+}
+
+// GenesisMiner is the stake and the balance of a genesis group member
+type GenesisMiner struct {
+	ID      string   `json:"id"` // hex id of the member in the genesis group
+	Stake   uint64   `json:"stake"`
+	Balance *big.Int `json:"balance"`
+}
+
+// GenesisConsensus defines the consensus params fixed at genesis, zero fields keep the defaults
+type GenesisConsensus struct {
+	GroupMemberMax      int    `json:"groupMemberMax,omitempty"`
+	GroupMemberMin      int    `json:"groupMemberMin,omitempty"`
+	MaxGroupCastTime    int    `json:"maxGroupCastTime,omitempty"`
+	MaxWaitBlockTime    int    `json:"maxWaitBlockTime,omitempty"`
+	Epoch               uint64 `json:"epoch,omitempty"`
+	MinerMaxJoinGroup   int    `json:"minerMaxJoinGroup,omitempty"`
+	CandidatesMinRatio  int    `json:"candidatesMinRatio,omitempty"`
+	GroupCreateGap      uint64 `json:"groupCreateGap,omitempty"`
+	GroupWaitPongGap    uint64 `json:"groupWaitPongGap,omitempty"`
+	GroupReadyGap       uint64 `json:"groupReadyGap,omitempty"`
+	GroupWorkGap        uint64 `json:"groupWorkGap,omitempty"`
+	GroupWorkDuration   uint64 `json:"groupWorkDuration,omitempty"`
+	GroupCreateInterval uint64 `json:"groupCreateInterval,omitempty"`
+	ProposalBonus       uint64 `json:"proposalBonus,omitempty"`
+	PackBonus           uint64 `json:"packBonus,omitempty"`
+	VerifyBonus         uint64 `json:"verifyBonus,omitempty"`
+	VerifierStake       uint64 `json:"verifierStake,omitempty"`
+}
+
+// LoadGenesis reads and validates the genesis spec from the json file, or the yaml file if the extension is .yaml or
+// .yml. Unknown fields are rejected
+func LoadGenesis(path string) (*Genesis, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return ParseGenesisYAML(data)
+	}
+	return ParseGenesis(data)
+}
+
+// ParseGenesisYAML decodes and validates the yaml genesis spec, which has the same fields as the json one
+func ParseGenesisYAML(data []byte) (*Genesis, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid genesis: %v", err)
+	}
+	v, err := yamlToJSON(&doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid genesis: %v", err)
+	}
+	js, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid genesis: %v", err)
+	}
+	return ParseGenesis(js)
+}
+
+var decimalInt = regexp.MustCompile(`^[-+]?[0-9]+$`)
+
+// yamlToJSON converts the yaml node to the value encoding the same json. Decimal integers are kept verbatim so that
+// the balances beyond uint64 are exact, other integer forms like the hex ids are kept as strings
+func yamlToJSON(n *yaml.Node) (interface{}, error) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return nil, nil
+		}
+		return yamlToJSON(n.Content[0])
+	case yaml.AliasNode:
+		return yamlToJSON(n.Alias)
+	case yaml.SequenceNode:
+		list := make([]interface{}, len(n.Content))
+		for i, c := range n.Content {
+			v, err := yamlToJSON(c)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = v
+		}
+		return list, nil
+	case yaml.MappingNode:
+		m := make(map[string]interface{}, len(n.Content)/2)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k := n.Content[i]
+			if k.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("line %v: non scalar key", k.Line)
+			}
+			if _, ok := m[k.Value]; ok {
+				return nil, fmt.Errorf("line %v: duplicate key %v", k.Line, k.Value)
+			}
+			v, err := yamlToJSON(n.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			m[k.Value] = v
+		}
+		return m, nil
+	case yaml.ScalarNode:
+		tag := n.ShortTag()
+		// Integers beyond uint64 are resolved as floats
+		if (tag == "!!int" || tag == "!!float") && decimalInt.MatchString(n.Value) {
+			return json.Number(strings.TrimPrefix(n.Value, "+")), nil
+		}
+		switch tag {
+		case "!!null":
+			return nil, nil
+		case "!!bool", "!!float":
+			var v interface{}
+			if err := n.Decode(&v); err != nil {
+				return nil, err
+			}
+			return v, nil
+		}
+		return n.Value, nil
+	}
+	return nil, fmt.Errorf("line %v: unsupported yaml node", n.Line)
+}
+
+// ParseGenesis decodes and validates the json genesis spec
+func ParseGenesis(data []byte) (*Genesis, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	genesis := new(Genesis)
+	if err := dec.Decode(genesis); err != nil {
+		return nil, fmt.Errorf("invalid genesis: %v", err)
+	}
+	if err := genesis.Validate(); err != nil {
+		return nil, err
+	}
+	return genesis, nil
+}
+
+func validGenesisAddress(addr string) bool {
+	if !common.IsHex(addr) {
+		return false
+	}
+	return len(common.FromHex(addr)) == common.AddressLength
+}
+
+func negative(v *big.Int) bool {
+	return v != nil && v.Sign() < 0
+}
+
+// Validate checks the spec is well formed
+func (g *Genesis) Validate() error {
+	if g.Timestamp < 0 {
+		return fmt.Errorf("invalid genesis timestamp %v", g.Timestamp)
+	}
+	for addr, acc := range g.Alloc {
+		if !validGenesisAddress(addr) {
+			return fmt.Errorf("invalid genesis alloc address %v", addr)
+		}
+		if negative(acc.Balance) {
+			return fmt.Errorf("negative genesis balance of %v", addr)
+		}
+	}
+	for _, addr := range g.TestAccounts {
+		if !validGenesisAddress(addr) {
+			return fmt.Errorf("invalid genesis test account %v", addr)
+		}
+	}
+	if negative(g.MinerBalance) {
+		return fmt.Errorf("negative genesis miner balance")
+	}
+	ids := make(map[string]bool)
+	for _, m := range g.Miners {
+		if !common.IsHex(m.ID) {
+			return fmt.Errorf("invalid genesis miner id %v", m.ID)
+		}
+		if ids[m.ID] {
+			return fmt.Errorf("duplicate genesis miner %v", m.ID)
+		}
+		ids[m.ID] = true
+		if negative(m.Balance) {
+			return fmt.Errorf("negative genesis balance of miner %v", m.ID)
+		}
+	}
+	if c := g.Consensus; c != nil {
+		if c.GroupMemberMin < 0 || c.GroupMemberMax < 0 || (c.GroupMemberMax > 0 && c.GroupMemberMin > c.GroupMemberMax) {
+			return fmt.Errorf("invalid genesis group member range [%v, %v]", c.GroupMemberMin, c.GroupMemberMax)
+		}
+		if c.MaxGroupCastTime < 0 || c.MaxWaitBlockTime < 0 || c.MinerMaxJoinGroup < 0 || c.CandidatesMinRatio < 0 {
+			return fmt.Errorf("negative genesis consensus param")
+		}
+	}
+	return nil
+}
+
+// Miner returns the genesis spec of the member with the given hex id, nil if not listed
+func (g *Genesis) Miner(id string) *GenesisMiner {
+	for i := range g.Miners {
+		if g.Miners[i].ID == id {
+			return &g.Miners[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"testing"
+)
+
+const addr4Test = "0xf77fa9ca98c46d534bd3d40c3488ed7a85c314db0fd1e79c6ccc75d79bd680bd"
+
+func TestParseGenesis(t *testing.T) {
+	spec := `{
+		"chainId": 7,
+		"timestamp": 1556150400,
+		"extraData": "private",
+		"gasPriceFloor": 100,
+		"alloc": {"` + addr4Test + `": {"balance": 100000000000000000}},
+		"minerStake": 100000000000,
+		"miners": [{"id": "0x77696c64206368696c6472656e", "stake": 500000000000}],
+		"consensus": {"epoch": 10, "groupMemberMin": 3, "groupMemberMax": 7}
+	}`
+	g, err := ParseGenesis([]byte(spec))
+	if err != nil {
+		t.Fatalf("parse genesis error:%v", err)
+	}
+	if g.ChainID != 7 || g.GasPriceFloor != 100 || g.Consensus.Epoch != 10 {
+		t.Errorf("parsed genesis mismatch:%+v", g)
+	}
+	if g.Alloc[addr4Test].Balance.String() != "100000000000000000" {
+		t.Errorf("parsed balance mismatch:%v", g.Alloc[addr4Test].Balance)
+	}
+	if m := g.Miner("0x77696c64206368696c6472656e"); m == nil || m.Stake != 500000000000 {
+		t.Errorf("parsed miner mismatch:%+v", m)
+	}
+}
+
+func TestParseGenesisInvalid(t *testing.T) {
+	specs := []string{
+		`{"chainName": "tas"}`,
+		`{"alloc": {"0x01": {"balance": 1}}}`,
+		`{"alloc": {"` + addr4Test + `": {"balance": -1}}}`,
+		`{"testAccounts": ["abc"]}`,
+		`{"miners": [{"id": "0x01"}, {"id": "0x01"}]}`,
+		`{"consensus": {"groupMemberMin": 8, "groupMemberMax": 7}}`,
+	}
+	for i, spec := range specs {
+		if _, err := ParseGenesis([]byte(spec)); err == nil {
+			t.Errorf("spec %v should be rejected", i)
+		}
+	}
+}
+
+func TestParseGenesisYAML(t *testing.T) {
+	spec := `
+chainId: 7
+timestamp: 1556150400
+extraData: private
+gasPriceFloor: 100
+alloc:
+  "` + addr4Test + `":
+    balance: 100000000000000000000000000
+minerStake: 100000000000
+miners:
+  - id: 0x77696c64206368696c6472656e
+    stake: 500000000000
+consensus:
+  epoch: 10
+  groupMemberMin: 3
+  groupMemberMax: 7
+`
+	g, err := ParseGenesisYAML([]byte(spec))
+	if err != nil {
+		t.Fatalf("parse genesis error:%v", err)
+	}
+	if g.ChainID != 7 || g.GasPriceFloor != 100 || g.ExtraData != "private" || g.Consensus.Epoch != 10 {
+		t.Errorf("parsed genesis mismatch:%+v", g)
+	}
+	if g.Alloc[addr4Test].Balance.String() != "100000000000000000000000000" {
+		t.Errorf("parsed balance mismatch:%v", g.Alloc[addr4Test].Balance)
+	}
+	if m := g.Miner("0x77696c64206368696c6472656e"); m == nil || m.Stake != 500000000000 {
+		t.Errorf("parsed miner mismatch:%+v", m)
+	}
+	if _, err := ParseGenesisYAML([]byte("chainName: tas\n")); err == nil {
+		t.Errorf("unknown field accepted")
+	}
+}
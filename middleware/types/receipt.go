@@ -18,9 +18,11 @@ package types
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"unsafe"
 
 	"github.com/taschain/taschain/common"
+	"github.com/vmihailenco/msgpack"
 )
 
 //go:generate gencodec -type Receipt -field-override receiptMarshaling -out gen_receipt_json.go
@@ -47,6 +49,51 @@ type Receipt struct {
 	ContractAddress common.Address `json:"contractAddress"`
 	Height          uint64         `json:"height"`
 	TxIndex         uint16         `json:"tx_index"`
+
+	// The execution details below are stored with the receipt but not committed by the receipt tree
+	GasUsed   uint64 `json:"gasUsed"`
+	Fee       uint64 `json:"fee"`
+	ErrorCode int    `json:"errorCode"`
+	ErrorMsg  string `json:"errorMsg"`
+}
+
+// consensusReceipt is the part of the receipt committed by the receipt tree of the block. The fields and their order
+// must stay the same as the original receipt, so that the encoding and the receipt tree don't change
+type consensusReceipt struct {
+	PostState         []byte
+	Status            uint
+	CumulativeGasUsed uint64
+	Bloom             Bloom
+	Logs              []*Log
+
+	TxHash          common.Hash
+	ContractAddress common.Address
+	Height          uint64
+	TxIndex         uint16
+}
+
+// Encode writes the encoding of the consensus fields, which is the value of the receipt in the receipt tree
+func (r *Receipt) Encode(w io.Writer) error {
+	return msgpack.NewEncoder(w).Encode(&consensusReceipt{
+		PostState:         r.PostState,
+		Status:            r.Status,
+		CumulativeGasUsed: r.CumulativeGasUsed,
+		Bloom:             r.Bloom,
+		Logs:              r.Logs,
+		TxHash:            r.TxHash,
+		ContractAddress:   r.ContractAddress,
+		Height:            r.Height,
+		TxIndex:           r.TxIndex,
+	})
+}
+
+// SetError records the reason of the failed execution
+func (r *Receipt) SetError(err *TransactionError) {
+	if err == nil {
+		return
+	}
+	r.ErrorCode = err.Code
+	r.ErrorMsg = err.Message
 }
 
 func NewReceipt(root []byte, failed bool, cumulativeGasUsed uint64) *Receipt {
@@ -0,0 +1,85 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/taschain/taschain/common"
+	"github.com/vmihailenco/msgpack"
+)
+
+// legacyReceipt is the receipt before the execution details were added
+type legacyReceipt struct {
+	PostState         []byte `json:"-"`
+	Status            uint   `json:"status"`
+	CumulativeGasUsed uint64 `json:"cumulativeGasUsed"`
+	Bloom             Bloom  `json:"-"`
+	Logs              []*Log `json:"logs"`
+
+	TxHash          common.Hash    `json:"transactionHash" gencodec:"required"`
+	ContractAddress common.Address `json:"contractAddress"`
+	Height          uint64         `json:"height"`
+	TxIndex         uint16         `json:"tx_index"`
+}
+
+func TestReceiptEncodeCompatible(t *testing.T) {
+	logs := []*Log{{Address: common.BytesToAddress([]byte{1}), Data: []byte{2}}}
+	r := NewReceipt(nil, true, 3000)
+	r.Logs = logs
+	r.Bloom = CreateBloom(Receipts{r})
+	r.TxHash = common.BytesToHash([]byte{3})
+	r.Height = 10
+	r.TxIndex = 2
+	r.GasUsed = 3000
+	r.Fee = 3000 * 500
+	r.SetError(TxErrorBalanceNotEnough)
+
+	legacy := &legacyReceipt{
+		Status:            r.Status,
+		CumulativeGasUsed: r.CumulativeGasUsed,
+		Bloom:             r.Bloom,
+		Logs:              logs,
+		TxHash:            r.TxHash,
+		Height:            r.Height,
+		TxIndex:           r.TxIndex,
+	}
+	want, err := msgpack.Marshal(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	if err := r.Encode(buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("receipt encoding changed:\nhave %x\nwant %x", buf.Bytes(), want)
+	}
+
+	// The stored receipt keeps the execution details
+	data, err := msgpack.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := new(Receipt)
+	if err := msgpack.Unmarshal(data, stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored.ErrorCode != TxErrorCodeBalanceNotEnough || stored.ErrorMsg != TxErrorBalanceNotEnough.Message || stored.GasUsed != r.GasUsed || stored.Fee != r.Fee {
+		t.Errorf("execution details lost: %+v", stored)
+	}
+}
@@ -76,6 +76,10 @@ const (
 	TxSyncNotify   uint32 = 10010
 	TxSyncReq      uint32 = 10011
 	TxSyncResponse uint32 = 10012
+
+	ReqStateInfo uint32 = 10013
+
+	StateInfoMsg uint32 = 10014
 )
 
 type Message struct {
@@ -258,6 +258,10 @@ func (s *Server) handleMessageInner(message *Message, from string) {
 			topicID = notify.ChainPieceBlockReq
 		case ChainPieceBlock:
 			topicID = notify.ChainPieceBlock
+		case ReqStateInfo:
+			topicID = notify.StateInfoReq
+		case StateInfoMsg:
+			topicID = notify.StateInfo
 		}
 		if topicID != "" {
 			msg := newNotifyMessage(message, from)
@@ -78,6 +78,10 @@ type Trie interface {
 	// NodeIterator returns an iterator that returns nodes of the trie. Iteration
 	// starts at the key after the given start key.
 	NodeIterator(startKey []byte) trie.NodeIterator
+
+	// Prove returns the encoded trie nodes on the path to the key, which proves the value or
+	// the absence of the key under the root hash
+	Prove(key []byte) ([][]byte, error)
 }
 
 // NewDatabase creates a backing store for state. The returned database
@@ -17,6 +17,7 @@ package account
 
 import (
 	"math/big"
+	"strings"
 	"testing"
 
 	"github.com/taschain/taschain/common"
@@ -88,3 +89,47 @@ func TestAccountDB_SetCode(t *testing.T) {
 		t.Errorf("wrong value: %s,expect value code", sta)
 	}
 }
+
+func TestAccountDB_DataPage(t *testing.T) {
+	db, _ := tasdb.NewMemDatabase()
+	defer db.Close()
+	triedb := NewDatabase(db)
+	addr := common.BytesToAddress([]byte("1"))
+	state, _ := NewAccountDB(common.Hash{}, triedb)
+	state.SetCode(addr, []byte("code"))
+	for _, key := range []string{"a", "b@1", "b@2", "b@3", "b@4", "b@5", "c"} {
+		state.SetData(addr, key, []byte("v"+key))
+	}
+	root, _ := state.Commit(false)
+	triedb.TrieDB().Commit(root, false)
+	state, _ = NewAccountDB(root, triedb)
+
+	all := make([]string, 0)
+	cursor := ""
+	for pages := 0; ; pages++ {
+		keys, values, next := state.DataPage(addr, "b@", cursor, 2)
+		for i, key := range keys {
+			if string(values[i]) != "v"+key {
+				t.Errorf("wrong value of %v: %s", key, values[i])
+			}
+		}
+		all = append(all, keys...)
+		if next == "" {
+			if pages != 2 {
+				t.Errorf("pages %v, expect 3", pages+1)
+			}
+			break
+		}
+		cursor = next
+	}
+	if strings.Join(all, ",") != "b@1,b@2,b@3,b@4,b@5" {
+		t.Errorf("wrong keys: %v", all)
+	}
+
+	if keys, _, next := state.DataPage(addr, "", "b@5", 10); strings.Join(keys, ",") != "b@5,c" || next != "" {
+		t.Errorf("wrong keys from the cursor: %v, next %v", keys, next)
+	}
+	if keys, _, _ := state.DataPage(common.BytesToAddress([]byte("2")), "", "", 10); len(keys) != 0 {
+		t.Errorf("keys of the absent account: %v", keys)
+	}
+}
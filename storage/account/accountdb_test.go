@@ -338,3 +338,34 @@ func (test *snapshotTest) checkEqual(state, checkstate *AccountDB) error {
 //		c.Fatal("expected no dirty state object")
 //	}
 //}
+
+func TestDirties(t *testing.T) {
+	db, _ := tasdb.NewMemDatabase()
+	state, _ := NewAccountDB(common.Hash{}, NewDatabase(db))
+
+	a, b, c := common.BytesToAddress([]byte{1}), common.BytesToAddress([]byte{2}), common.BytesToAddress([]byte{3})
+	state.AddBalance(a, big.NewInt(10))
+	state.SetData(b, "y", []byte{1})
+	state.SetData(b, "x", []byte{2})
+	state.SetData(b, "y", []byte{3})
+	snapshot := state.Snapshot()
+	state.SetNonce(c, 1)
+	state.SetData(b, "z", []byte{4})
+	state.RevertToSnapshot(snapshot)
+
+	dirties := state.Dirties()
+	if len(dirties) != 2 {
+		t.Fatalf("dirty accounts %v, want 2", len(dirties))
+	}
+	if keys, ok := dirties[a]; !ok || len(keys) != 0 {
+		t.Errorf("account a dirty keys %v", keys)
+	}
+	if keys := dirties[b]; !reflect.DeepEqual(keys, []string{"x", "y"}) {
+		t.Errorf("account b dirty keys %v", keys)
+	}
+
+	state.IntermediateRoot(true)
+	if len(state.Dirties()) != 0 {
+		t.Errorf("dirties not cleared with the journal")
+	}
+}
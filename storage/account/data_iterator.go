@@ -18,6 +18,7 @@ package account
 import (
 	"strings"
 
+	"github.com/taschain/taschain/common"
 	"github.com/taschain/taschain/storage/trie"
 )
 
@@ -45,3 +46,31 @@ func (di *DataIterator) GetValue() []byte {
 	}
 	return di.Value
 }
+
+// DataPage returns at most limit key-value pairs of the contract storage whose keys start with the prefix, in the
+// order of the keys beginning at the cursor, or at the prefix if the cursor is empty. The next is the cursor of the
+// following page, empty if there are no more keys. Only the storage committed to the trie is read
+func (adb *AccountDB) DataPage(addr common.Address, prefix, cursor string, limit int) (keys []string, values [][]byte, next string) {
+	keys, values = make([]string, 0), make([][]byte, 0)
+	start := prefix
+	if cursor > start {
+		start = cursor
+	}
+	iter := adb.DataIterator(addr, start)
+	if iter == nil {
+		return
+	}
+	for iter.Next() {
+		key := string(iter.Key)
+		if !strings.HasPrefix(key, prefix) {
+			break
+		}
+		if len(keys) >= limit {
+			next = key
+			break
+		}
+		keys = append(keys, key)
+		values = append(values, iter.Value)
+	}
+	return
+}
@@ -0,0 +1,66 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package account
+
+import (
+	"fmt"
+
+	"github.com/taschain/taschain/common"
+	"github.com/taschain/taschain/storage/serialize"
+	"github.com/taschain/taschain/storage/trie"
+)
+
+// GetProof returns the merkle proof of the account in the account trie. The proof must be generated
+// from a committed state, the uncommitted modifications are not reflected
+func (adb *AccountDB) GetProof(addr common.Address) ([][]byte, error) {
+	return adb.trie.Prove(addr[:])
+}
+
+// GetStorageProof returns the merkle proof of the data key in the storage trie of the account.
+// The proof of a non-existent account is empty
+func (adb *AccountDB) GetStorageProof(addr common.Address, key string) ([][]byte, error) {
+	tr := adb.StorageTrie(addr)
+	if tr == nil {
+		return [][]byte{}, nil
+	}
+	return tr.Prove([]byte(key))
+}
+
+// VerifyAccountProof checks the account proof against the state root and returns the proven account,
+// or nil if the proof shows the account doesn't exist
+func VerifyAccountProof(root common.Hash, addr common.Address, proof [][]byte) (*Account, error) {
+	enc, err := trie.VerifyProof(root, addr[:], proof)
+	if err != nil {
+		return nil, err
+	}
+	if len(enc) == 0 {
+		return nil, nil
+	}
+	var data Account
+	if err := serialize.DecodeBytes(enc, &data); err != nil {
+		return nil, fmt.Errorf("decode account error: %v", err)
+	}
+	return &data, nil
+}
+
+// VerifyStorageProof checks the storage proof against the storage root of the account and returns the proven value,
+// or nil if the proof shows the key doesn't exist
+func VerifyStorageProof(storageRoot common.Hash, key string, proof [][]byte) ([]byte, error) {
+	if storageRoot == emptyData {
+		return nil, nil
+	}
+	return trie.VerifyProof(storageRoot, []byte(key), proof)
+}
@@ -0,0 +1,85 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package account
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/taschain/taschain/common"
+)
+
+func TestAccountProof(t *testing.T) {
+	sdb, root := makeSyncState4Test(t)
+	state, err := NewAccountDB(root, sdb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := byte(0); i < 100; i++ {
+		addr := common.BytesToAddress([]byte{i})
+		proof, err := state.GetProof(addr)
+		if err != nil {
+			t.Fatalf("prove account %v error %v", i, err)
+		}
+		data, err := VerifyAccountProof(root, addr, proof)
+		if err != nil || data == nil {
+			t.Fatalf("verify account %v error %v", i, err)
+		}
+		if data.Nonce != uint64(i)+1 {
+			t.Errorf("account %v nonce mismatch: have %v, want %v", i, data.Nonce, uint64(i)+1)
+		}
+		if i%3 != 0 {
+			continue
+		}
+		key := string([]byte{i})
+		proof, err = state.GetStorageProof(addr, key)
+		if err != nil {
+			t.Fatalf("prove storage %v error %v", i, err)
+		}
+		value, err := VerifyStorageProof(data.Root, key, proof)
+		if err != nil || !bytes.Equal(value, []byte{i, i, i}) {
+			t.Errorf("verify storage %v error: value %v, err %v", i, value, err)
+		}
+	}
+
+	// Absence of the account and the storage key
+	addr := common.BytesToAddress([]byte{200})
+	proof, err := state.GetProof(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := VerifyAccountProof(root, addr, proof); data != nil || err != nil {
+		t.Errorf("absent account proven: data %v, err %v", data, err)
+	}
+	addr = common.BytesToAddress([]byte{3})
+	data := state.getAccountObject(addr).data
+	proof, _ = state.GetStorageProof(addr, "absent")
+	if value, err := VerifyStorageProof(data.Root, "absent", proof); value != nil || err != nil {
+		t.Errorf("absent key proven: value %v, err %v", value, err)
+	}
+
+	// Tampered proof
+	addr = common.BytesToAddress([]byte{1})
+	proof, _ = state.GetProof(addr)
+	last := proof[len(proof)-1]
+	last[len(last)-1]++
+	if _, err := VerifyAccountProof(root, addr, proof); err == nil {
+		t.Errorf("tampered proof accepted")
+	}
+	if _, err := VerifyAccountProof(root, addr, proof[:len(proof)-1]); err == nil {
+		t.Errorf("incomplete proof accepted")
+	}
+}
@@ -0,0 +1,44 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package account
+
+import (
+	"github.com/taschain/taschain/common"
+	"github.com/taschain/taschain/storage/serialize"
+	"github.com/taschain/taschain/storage/trie"
+)
+
+// NewStateSync creates a scheduler downloading the whole state of the given root, including the
+// account trie, the storage tries and the contract codes
+func NewStateSync(root common.Hash, database trie.DatabaseReader) *trie.Sync {
+	var syncer *trie.Sync
+	callback := func(leaf []byte, parent common.Hash) error {
+		var obj Account
+		if err := serialize.DecodeBytes(leaf, &obj); err != nil {
+			return err
+		}
+		if obj.Root != emptyData {
+			syncer.AddSubTrie(obj.Root, 64, parent, nil)
+		}
+		code := common.BytesToHash(obj.CodeHash)
+		if code != emptyCode {
+			syncer.AddRawEntry(code, 64, parent)
+		}
+		return nil
+	}
+	syncer = trie.NewSync(root, database, callback)
+	return syncer
+}
@@ -0,0 +1,125 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package account
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/taschain/taschain/common"
+	"github.com/taschain/taschain/storage/tasdb"
+	"github.com/taschain/taschain/storage/trie"
+)
+
+func makeSyncState4Test(t *testing.T) (AccountDatabase, common.Hash) {
+	db, _ := tasdb.NewMemDatabase()
+	sdb := NewDatabase(db)
+	state, _ := NewAccountDB(common.Hash{}, sdb)
+	for i := byte(0); i < 100; i++ {
+		addr := common.BytesToAddress([]byte{i})
+		state.SetNonce(addr, uint64(i)+1)
+		if i%3 == 0 {
+			state.SetData(addr, string([]byte{i}), []byte{i, i, i})
+		}
+		if i%5 == 0 {
+			state.SetCode(addr, []byte{i, i, i, i, i})
+		}
+	}
+	root, err := state.Commit(false)
+	if err != nil {
+		t.Fatalf("commit state error %v", err)
+	}
+	if err := sdb.TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("commit trie error %v", err)
+	}
+	return sdb, root
+}
+
+// syncState4Test downloads the state from the source, the fetch function can tamper the delivered data
+func syncState4Test(src AccountDatabase, root common.Hash, dst tasdb.Database, fetch func(hash common.Hash, data []byte) []byte) error {
+	sched := NewStateSync(root, dst)
+	for sched.Pending() > 0 {
+		hashes := sched.Missing(16)
+		results := make([]trie.SyncResult, len(hashes))
+		for i, hash := range hashes {
+			data, err := src.TrieDB().Node(hash)
+			if err != nil {
+				return err
+			}
+			results[i] = trie.SyncResult{Hash: hash, Data: fetch(hash, data)}
+		}
+		if _, _, err := sched.Process(results); err != nil {
+			return err
+		}
+		if _, err := sched.Commit(dst); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestStateSync(t *testing.T) {
+	src, root := makeSyncState4Test(t)
+	dst, _ := tasdb.NewMemDatabase()
+	if err := syncState4Test(src, root, dst, func(hash common.Hash, data []byte) []byte { return data }); err != nil {
+		t.Fatalf("sync state error %v", err)
+	}
+
+	state, err := NewAccountDB(root, NewDatabase(dst))
+	if err != nil {
+		t.Fatalf("open synced state error %v", err)
+	}
+	for i := byte(0); i < 100; i++ {
+		addr := common.BytesToAddress([]byte{i})
+		if nonce := state.GetNonce(addr); nonce != uint64(i)+1 {
+			t.Errorf("nonce of %v mismatch: have %v, want %v", i, nonce, uint64(i)+1)
+		}
+		if i%3 == 0 {
+			if data := state.GetData(addr, string([]byte{i})); !bytes.Equal(data, []byte{i, i, i}) {
+				t.Errorf("data of %v mismatch: have %x", i, data)
+			}
+		}
+		if i%5 == 0 {
+			if code := state.GetCode(addr); !bytes.Equal(code, []byte{i, i, i, i, i}) {
+				t.Errorf("code of %v mismatch: have %x", i, code)
+			}
+		}
+	}
+}
+
+func TestStateSyncTampered(t *testing.T) {
+	src, root := makeSyncState4Test(t)
+	dst, _ := tasdb.NewMemDatabase()
+	tampered := false
+	err := syncState4Test(src, root, dst, func(hash common.Hash, data []byte) []byte {
+		if hash == root {
+			return data
+		}
+		tampered = true
+		forged := append([]byte{}, data...)
+		forged[len(forged)-1] ^= 0xff
+		return forged
+	})
+	if !tampered {
+		t.Fatalf("nothing tampered")
+	}
+	if err != trie.ErrHashMismatch {
+		t.Fatalf("tampered node accepted, err %v", err)
+	}
+	if ok, _ := dst.Has(root.Bytes()); ok {
+		t.Errorf("incomplete state committed")
+	}
+}
@@ -17,6 +17,7 @@ package account
 
 import (
 	"math/big"
+	"sort"
 
 	"github.com/taschain/taschain/common"
 )
@@ -118,3 +119,46 @@ func (ch storageChange) undo(s *AccountDB) {
 func (ch refundChange) undo(s *AccountDB) {
 	s.refund = ch.prev
 }
+
+// Dirties returns the accounts changed since the journal was last cleared by Finalise, along with the sorted storage
+// keys written of each account. Changes reverted by RevertToSnapshot are no longer in the journal and not returned
+func (adb *AccountDB) Dirties() map[common.Address][]string {
+	keys := make(map[common.Address]map[string]struct{})
+	dirty := func(addr common.Address) map[string]struct{} {
+		if _, ok := keys[addr]; !ok {
+			keys[addr] = make(map[string]struct{})
+		}
+		return keys[addr]
+	}
+	for _, entry := range adb.transitions {
+		switch ch := entry.(type) {
+		case createObjectChange:
+			dirty(*ch.account)
+		case resetObjectChange:
+			dirty(ch.prev.address)
+		case suicideChange:
+			dirty(*ch.account)
+		case balanceChange:
+			dirty(*ch.account)
+		case nonceChange:
+			dirty(*ch.account)
+		case codeChange:
+			dirty(*ch.account)
+		case touchChange:
+			dirty(*ch.account)
+		case storageChange:
+			dirty(*ch.account)[ch.key] = struct{}{}
+		}
+	}
+
+	ret := make(map[common.Address][]string, len(keys))
+	for addr, set := range keys {
+		sorted := make([]string, 0, len(set))
+		for key := range set {
+			sorted = append(sorted, key)
+		}
+		sort.Strings(sorted)
+		ret[addr] = sorted
+	}
+	return ret
+}
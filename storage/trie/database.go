@@ -430,8 +430,10 @@ func (db *NodeDatabase) Dereference(root common.Hash) {
 	//memcacheGCSizeMeter.Mark(int64(storage - db.nodesSize))
 	//memcacheGCNodesMeter.Mark(int64(nodes - len(db.nodes)))
 
-	common.DefaultLogger.Debug("Dereferenced trie from memory database", "nodes", nodes-len(db.nodes), "size", storage-db.nodesSize, "time", time.Since(start),
-		"gcnodes", db.gcnodes, "gcsize", db.gcsize, "gctime", db.gctime, "livenodes", len(db.nodes), "livesize", db.nodesSize)
+	if common.DefaultLogger != nil {
+		common.DefaultLogger.Debug("Dereferenced trie from memory database", "nodes", nodes-len(db.nodes), "size", storage-db.nodesSize, "time", time.Since(start),
+			"gcnodes", db.gcnodes, "gcsize", db.gcsize, "gctime", db.gctime, "livenodes", len(db.nodes), "livesize", db.nodesSize)
+	}
 }
 
 // dereference is the private locked version of Dereference.
@@ -570,8 +572,10 @@ func (db *NodeDatabase) Cap(limit common.StorageSize) error {
 	//memcacheFlushSizeMeter.Mark(int64(storage - db.nodesSize))
 	//memcacheFlushNodesMeter.Mark(int64(nodes - len(db.nodes)))
 
-	common.DefaultLogger.Debug("Persisted nodes from memory database", "nodes", nodes-len(db.nodes), "size", storage-db.nodesSize, "time", time.Since(start),
-		"flushnodes", db.flushnodes, "flushsize", db.flushsize, "flushtime", db.flushtime, "livenodes", len(db.nodes), "livesize", db.nodesSize)
+	if common.DefaultLogger != nil {
+		common.DefaultLogger.Debug("Persisted nodes from memory database", "nodes", nodes-len(db.nodes), "size", storage-db.nodesSize, "time", time.Since(start),
+			"flushnodes", db.flushnodes, "flushsize", db.flushsize, "flushtime", db.flushtime, "livenodes", len(db.nodes), "livesize", db.nodesSize)
+	}
 
 	return nil
 }
@@ -49,6 +49,7 @@ type AccountDB interface {
 	RemoveData(common.Address, string)
 	DataIterator(common.Address, string) *trie.Iterator
 	DataNext(iterator uintptr) string
+	DataPage(addr common.Address, prefix, cursor string, limit int) (keys []string, values [][]byte, next string)
 
 	Suicide(common.Address) bool
 	HasSuicided(common.Address) bool
@@ -13,6 +13,9 @@
 //   You should have received a copy of the GNU General Public License
 //   along with this program.  If not, see <https://www.gnu.org/licenses/>.
 
+//go:build tvm_python
+// +build tvm_python
+
 package tvm
 
 /*
@@ -24,7 +27,6 @@ import (
 	"unsafe"
 
 	"github.com/taschain/taschain/common"
-	"github.com/taschain/taschain/middleware/types"
 )
 
 //export Transfer
@@ -33,61 +35,46 @@ func Transfer(toAddressStr *C.char, value *C.char) {
 	if !ok {
 		return
 	}
-	contractAddr := controller.VM.ContractAddress
-	contractValue := controller.AccountDB.GetBalance(*contractAddr)
-	if contractValue.Cmp(transValue) < 0 {
-		return
-	}
 	toAddress := common.HexToAddress(C.GoString(toAddressStr))
-	controller.AccountDB.AddBalance(toAddress, transValue)
-	controller.AccountDB.SubBalance(*contractAddr, transValue)
+	controller.Transfer(*currentVM().ContractAddress, toAddress, transValue)
 }
 
 //export GetBalance
 func GetBalance(addressC *C.char) *C.char {
 	address := common.HexToAddress(C.GoString(addressC))
-	value := controller.AccountDB.GetBalance(address)
+	value := controller.GetBalance(address)
 	return C.CString(value.String())
 }
 
 //export GetData
 func GetData(hashC *C.char) *C.char {
-	//hash := common.StringToHash(C.GoString(hashC))
-	address := *controller.VM.ContractAddress
-	state := controller.AccountDB.GetData(address, C.GoString(hashC))
+	state := controller.GetData(*currentVM().ContractAddress, C.GoString(hashC))
 	return C.CString(string(state))
 }
 
 //export SetData
 func SetData(keyC *C.char, data *C.char) {
-	address := *controller.VM.ContractAddress
-	key := C.GoString(keyC)
-	state := []byte(C.GoString(data))
-	controller.AccountDB.SetData(address, key, state)
+	controller.SetData(*currentVM().ContractAddress, C.GoString(keyC), []byte(C.GoString(data)))
 }
 
 //export BlockHash
 func BlockHash(height C.ulonglong) *C.char {
-	block := controller.Reader.QueryBlockHeaderByHeight(uint64(height))
-	if block == nil {
-		return C.CString("0x0000000000000000000000000000000000000000000000000000000000000000")
-	}
-	return C.CString(block.Hash.Hex())
+	return C.CString(controller.BlockHash(uint64(height)).Hex())
 }
 
 //export Number
 func Number() C.ulonglong {
-	return C.ulonglong(controller.BlockHeader.Height)
+	return C.ulonglong(controller.Number())
 }
 
 //export Timestamp
 func Timestamp() C.ulonglong {
-	return C.ulonglong(uint64(controller.BlockHeader.CurTime.Unix()))
+	return C.ulonglong(controller.Timestamp())
 }
 
 //export TxGasLimit
 func TxGasLimit() C.ulonglong {
-	return C.ulonglong(controller.Transaction.GetGasLimit())
+	return C.ulonglong(controller.TxGasLimit())
 }
 
 //export ContractCall
@@ -106,101 +93,41 @@ func ContractCall(addressC *C.char, funName *C.char, jsonParms *C.char, cResult
 
 //export EventCall
 func EventCall(eventName *C.char, index *C.char, data *C.char) *C.char {
-
-	var log types.Log
-	log.Topics = append(log.Topics, common.BytesToHash(common.Sha256([]byte(C.GoString(eventName)))))
-	log.Topics = append(log.Topics, common.BytesToHash(common.Sha256([]byte(C.GoString(index)))))
-	for i := 0; i < len(C.GoString(data)); i++ {
-		log.Data = append(log.Data, C.GoString(data)[i])
-	}
-	log.TxHash = controller.Transaction.GetHash()
-	log.Address = *controller.VM.ContractAddress //*(controller.Transaction.Target)
-	log.BlockNumber = controller.BlockHeader.Height
-	//block is running ,no blockhash this time
-	// log.BlockHash = controller.BlockHeader.Hash
-
-	controller.VM.Logs = append(controller.VM.Logs, &log)
+	vm := currentVM()
+	logData := append([]byte(nil), C.GoString(data)...)
+	log := controller.NewLog(*vm.ContractAddress, C.GoString(eventName), C.GoString(index), logData)
+	vm.Logs = append(vm.Logs, log)
 
 	return nil //C.CString(contractResult);
 }
 
 //export RemoveData
 func RemoveData(key *C.char) {
-	address := *controller.VM.ContractAddress
-	controller.AccountDB.RemoveData(address, C.GoString(key))
+	controller.RemoveData(*currentVM().ContractAddress, C.GoString(key))
 }
 
 //export MinerStake
 func MinerStake(minerAddr *C.char, _type int, cvalue *C.char) bool {
-	ss := controller.AccountDB.Snapshot()
 	value, ok := big.NewInt(0).SetString(C.GoString(cvalue), 10)
 	if !ok {
 		return false
 	}
-	source := controller.VM.ContractAddress
 	miner := common.HexToAddress(C.GoString(minerAddr))
-	if canTransfer(controller.AccountDB, *source, value) {
-		mexist := controller.mm.GetMinerByID(miner.Bytes(), byte(_type), controller.AccountDB)
-		if mexist != nil &&
-			controller.mm.AddStake(mexist.ID, mexist, value.Uint64(), controller.AccountDB) &&
-			controller.mm.AddStakeDetail(source.Bytes(), mexist, value.Uint64(), controller.AccountDB) {
-			controller.AccountDB.SubBalance(*source, value)
-			return true
-		}
-	}
-	controller.AccountDB.RevertToSnapshot(ss)
-	return false
+	return controller.MinerStake(*currentVM().ContractAddress, miner, byte(_type), value)
 }
 
 //export MinerCancelStake
 func MinerCancelStake(minerAddr *C.char, _type int, cvalue *C.char) bool {
-	ss := controller.AccountDB.Snapshot()
 	value, ok := big.NewInt(0).SetString(C.GoString(cvalue), 10)
 	if !ok {
 		return false
 	}
-	source := controller.VM.ContractAddress
 	miner := common.HexToAddress(C.GoString(minerAddr))
-	mexist := controller.mm.GetMinerByID(miner.Bytes(), byte(_type), controller.AccountDB)
-	if mexist != nil &&
-		controller.mm.CancelStake(source.Bytes(), mexist, value.Uint64(), controller.AccountDB, controller.BlockHeader.Height) &&
-		controller.mm.ReduceStake(mexist.ID, mexist, value.Uint64(), controller.AccountDB, controller.BlockHeader.Height) {
-		return true
-	}
-	controller.AccountDB.RevertToSnapshot(ss)
-	return false
+	return controller.MinerCancelStake(*currentVM().ContractAddress, miner, byte(_type), value)
 }
 
 //export MinerRefundStake
 func MinerRefundStake(minerAddr *C.char, _type int) bool {
-	var success = false
-	ss := controller.AccountDB.Snapshot()
-	source := controller.VM.ContractAddress
 	miner := common.HexToAddress(C.GoString(minerAddr))
-	mexist := controller.mm.GetMinerByID(miner.Bytes(), byte(_type), controller.AccountDB)
-	height := controller.BlockHeader.Height
-	if mexist != nil {
-		if mexist.Type == types.MinerTypeHeavy {
-			latestCancelPledgeHeight := controller.mm.GetLatestCancelStakeHeight(source.Bytes(), mexist, controller.AccountDB)
-			if height > latestCancelPledgeHeight+10 || (mexist.Status == types.MinerStatusAbort && height > mexist.AbortHeight+10) {
-				value, ok := controller.mm.RefundStake(source.Bytes(), mexist, controller.AccountDB)
-				if ok {
-					refundValue := big.NewInt(0).SetUint64(value)
-					controller.AccountDB.AddBalance(*source, refundValue)
-					success = true
-				}
-			}
-		} else {
-			value, ok := controller.mm.RefundStake(source.Bytes(), mexist, controller.AccountDB)
-			if ok {
-				refundValue := big.NewInt(0).SetUint64(value)
-				controller.AccountDB.AddBalance(*source, refundValue)
-				success = true
-			}
-		}
-	}
-	if !success {
-		controller.AccountDB.RevertToSnapshot(ss)
-	}
-	return success
+	return controller.MinerRefundStake(*currentVM().ContractAddress, miner, byte(_type))
 }
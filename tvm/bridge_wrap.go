@@ -13,6 +13,9 @@
 //   You should have received a copy of the GNU General Public License
 //   along with this program.  If not, see <https://www.gnu.org/licenses/>.
 
+//go:build tvm_python
+// +build tvm_python
+
 package tvm
 
 /*
@@ -125,13 +128,6 @@ type CallTask struct {
 	Params       string
 }
 
-type ExecuteResult struct {
-	ResultType int
-	ErrorCode  int
-	Content    string
-	Abi        string
-}
-
 // CallContract Execute the function of a contract which python code store in contractAddr
 func CallContract(contractAddr string, funcName string, params string) *ExecuteResult {
 	result := &ExecuteResult{}
@@ -143,15 +139,16 @@ func CallContract(contractAddr string, funcName string, params string) *ExecuteR
 		result.Content = fmt.Sprint(types.NoCodeErrorMsg, conAddr)
 		return result
 	}
-	oneVM := &TVM{contract, controller.VM.ContractAddress, nil}
+	engine := controller.engine.(*pyEngine)
+	oneVM := &TVM{contract, engine.vm.ContractAddress, nil}
 
 	// prepare vm environment
-	controller.VM.createContext()
-	finished := controller.StoreVMContext(oneVM)
+	engine.vm.createContext()
+	finished := engine.storeVMContext(oneVM)
 	defer func() {
 		// recover vm environment
 		if finished {
-			controller.VM.removeContext()
+			engine.vm.removeContext()
 		}
 	}()
 	if !finished {
@@ -161,8 +158,23 @@ func CallContract(contractAddr string, funcName string, params string) *ExecuteR
 		return result
 	}
 
+	if strings.EqualFold("[true]", params) {
+		params = "[true]"
+	} else if strings.EqualFold("[false]", params) {
+		params = "[false]"
+	}
+	abiJSON := fmt.Sprintf(`{"FuncName": "%s", "Args": %s}`, funcName, params)
+	controller.traceEnter(FrameCall, *oneVM.Sender, conAddr, abiJSON, 0, uint64(engine.vm.Gas()))
+	defer func() {
+		var err *types.TransactionError
+		if result.ResultType == C.RETURN_TYPE_EXCEPTION {
+			err = types.NewTransactionError(result.ErrorCode, result.Content)
+		}
+		controller.traceExit(uint64(engine.vm.Gas()), err)
+	}()
+
 	msg := Msg{Data: []byte{}, Value: 0, Sender: conAddr.Hex()}
-	errorCode, errorMsg, _ := controller.VM.CreateContractInstance(msg)
+	errorCode, errorMsg, _ := engine.vm.CreateContractInstance(msg)
 	if errorCode != 0 {
 		result.ResultType = C.RETURN_TYPE_EXCEPTION
 		result.ErrorCode = errorCode
@@ -170,13 +182,7 @@ func CallContract(contractAddr string, funcName string, params string) *ExecuteR
 		return result
 	}
 
-	if strings.EqualFold("[true]", params) {
-		params = "[true]"
-	} else if strings.EqualFold("[false]", params) {
-		params = "[false]"
-	}
 	abi := ABI{}
-	abiJSON := fmt.Sprintf(`{"FuncName": "%s", "Args": %s}`, funcName, params)
 	abiJSONError := json.Unmarshal([]byte(abiJSON), &abi)
 	if abiJSONError != nil {
 		result.ResultType = C.RETURN_TYPE_EXCEPTION
@@ -184,14 +190,15 @@ func CallContract(contractAddr string, funcName string, params string) *ExecuteR
 		result.Content = types.ABIJSONErrorMsg
 		return result
 	}
-	errorCode, errorMsg = controller.VM.checkABI(abi)
+	errorCode, errorMsg = engine.vm.checkABI(abi)
 	if errorCode != 0 {
 		result.ResultType = C.RETURN_TYPE_EXCEPTION
 		result.ErrorCode = errorCode
 		result.Content = errorMsg
 		return result
 	}
-	return controller.VM.executeABIKindEval(abi)
+	result = engine.vm.executeABIKindEval(abi)
+	return result
 }
 
 func bridgeInit() {
@@ -212,22 +219,6 @@ func bridgeInit() {
 	C.miner_refund_stake = (C.miner_refund_stake_fn_t)(unsafe.Pointer(C.wrap_miner_refund_stake))
 }
 
-// Contract Contract contains the base message of a contract
-type Contract struct {
-	Code            string          `json:"code"`
-	ContractName    string          `json:"contract_name"`
-	ContractAddress *common.Address `json:"-"`
-}
-
-// LoadContract Load a contract-instance from a contract address
-func LoadContract(address common.Address) *Contract {
-	jsonString := controller.AccountDB.GetCode(address)
-	con := &Contract{}
-	_ = json.Unmarshal([]byte(jsonString), con)
-	con.ContractAddress = &address
-	return con
-}
-
 // TVM TVM is the role who execute contract code
 type TVM struct {
 	*Contract
@@ -295,13 +286,6 @@ func (tvm *TVM) storeData() (int, string) {
 	return tvm.ExecuteScriptVMSucceed(script)
 }
 
-// Msg Msg is msg instance which store running message when running a contract
-type Msg struct {
-	Data   []byte
-	Value  uint64
-	Sender string
-}
-
 // CreateContractInstance Create contract instance
 func (tvm *TVM) CreateContractInstance(msg Msg) (int, string, int) {
 	errorCode, errorMsg := tvm.loadMsg(msg)
@@ -421,12 +405,6 @@ func (tvm *TVM) removeContext() {
 	C.tvm_remove_context()
 }
 
-// ABI ABI stores the calling msg when execute contract
-type ABI struct {
-	FuncName string
-	Args     []interface{}
-}
-
 func (tvm *TVM) jsonValueToBuf(buf *bytes.Buffer, value interface{}) {
 	switch value.(type) {
 	case float64:
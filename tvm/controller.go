@@ -43,10 +43,10 @@ type Controller struct {
 	Transaction ControllerTransactionInterface
 	AccountDB   vm.AccountDB
 	Reader      vm.ChainReader
-	VM          *TVM
 	LibPath     string
-	VMStack     []*TVM
 	GasLeft     uint64
+	Tracer      Tracer // Captures the execution if set, it's reset by NewController
+	engine      Engine
 	mm          MinerManager
 	gcm         GroupChainManager
 }
@@ -82,33 +82,39 @@ func NewController(accountDB vm.AccountDB,
 	controller.Transaction = transaction
 	controller.AccountDB = accountDB
 	controller.Reader = chainReader
-	controller.VM = nil
 	controller.LibPath = libPath
-	controller.VMStack = make([]*TVM, 0)
 	controller.GasLeft = transaction.GetGasLimit() - gasUsed
 	controller.mm = manager
 	controller.gcm = chainManager
+	controller.Tracer = nil
+	controller.engine = newEngine(controller)
 	return controller
 }
 
+// LoadContract Load a contract-instance from a contract address
+func LoadContract(address common.Address) *Contract {
+	return controller.loadContract(address)
+}
+
+func (con *Controller) loadContract(address common.Address) *Contract {
+	jsonString := con.AccountDB.GetCode(address)
+	contract := &Contract{}
+	_ = json.Unmarshal([]byte(jsonString), contract)
+	contract.ContractAddress = &address
+	return contract
+}
+
 // Deploy Deploy a contract instance
 func (con *Controller) Deploy(contract *Contract) (int, string) {
-	con.VM = NewTVM(con.Transaction.GetSource(), contract, con.LibPath)
-	defer func() {
-		con.VM.DelTVM()
-	}()
-	con.VM.SetGas(int(con.GasLeft))
 	msg := Msg{Data: []byte{}, Value: con.Transaction.GetValue(), Sender: con.Transaction.GetSource().Hex()}
-	errorCodeDeploy, errorDeployMsg := con.VM.Deploy(msg)
-
-	if errorCodeDeploy != 0 {
-		return errorCodeDeploy, errorDeployMsg
-	}
-	errorCodeStore, errorStoreMsg := con.VM.storeData()
-	if errorCodeStore != 0 {
-		return errorCodeStore, errorStoreMsg
+	con.traceEnter(FrameCreate, *con.Transaction.GetSource(), *contract.ContractAddress, "", msg.Value, con.GasLeft)
+	gasLeft, err := con.engine.Deploy(contract, msg, con.GasLeft)
+	if err != nil {
+		con.traceExit(con.GasLeft, err)
+		return err.Code, err.Message
 	}
-	con.GasLeft = uint64(con.VM.Gas())
+	con.traceExit(gasLeft, nil)
+	con.GasLeft = gasLeft
 	return 0, ""
 }
 
@@ -121,88 +127,57 @@ func transfer(db vm.AccountDB, sender, recipient common.Address, amount *big.Int
 	db.AddBalance(recipient, amount)
 }
 
-// ExecuteABI Execute the contract with abi
-func (con *Controller) ExecuteABI(sender *common.Address, contract *Contract, abiJSON string) (bool, []*types.Log, *types.TransactionError) {
-	con.VM = NewTVM(sender, contract, con.LibPath)
-	con.VM.SetGas(int(con.GasLeft))
-	defer func() {
-		con.VM.DelTVM()
-		con.GasLeft = uint64(con.VM.Gas())
-	}()
+// transferValue transfers the value of the transaction from the sender to the contract
+func (con *Controller) transferValue(sender *common.Address) *types.TransactionError {
 	if con.Transaction.GetValue() > 0 {
 		amount := new(big.Int).SetUint64(con.Transaction.GetValue())
-		if canTransfer(con.AccountDB, *sender, amount) {
-			transfer(con.AccountDB, *sender, *con.Transaction.GetTarget(), amount)
-		} else {
-			return false, nil, types.TxErrorBalanceNotEnough
+		if !canTransfer(con.AccountDB, *sender, amount) {
+			return types.TxErrorBalanceNotEnough
+		}
+		transfer(con.AccountDB, *sender, *con.Transaction.GetTarget(), amount)
+		if con.Tracer != nil {
+			con.Tracer.CaptureTransfer(*sender, *con.Transaction.GetTarget(), amount)
 		}
 	}
-	msg := Msg{Data: con.Transaction.GetData(), Value: con.Transaction.GetValue(), Sender: con.Transaction.GetSource().Hex()}
-	errorCode, errorMsg, libLen := con.VM.CreateContractInstance(msg)
-	if errorCode != 0 {
-		return false, nil, types.NewTransactionError(errorCode, errorMsg)
-	}
-	abi := ABI{}
-	abiJSONError := json.Unmarshal([]byte(abiJSON), &abi)
-	if abiJSONError != nil {
-		return false, nil, types.TxErrorABIJSON
-	}
-	errorCode, errorMsg = con.VM.checkABI(abi) //checkABI
-	if errorCode != 0 {
-		return false, nil, types.NewTransactionError(errorCode, errorMsg)
-	}
-	con.VM.SetLibLine(libLen)
-	errorCode, errorMsg = con.VM.executABIVMSucceed(abi) //execute
-	if errorCode != 0 {
-		return false, nil, types.NewTransactionError(errorCode, errorMsg)
+	return nil
+}
+
+// ExecuteABI Execute the contract with abi
+func (con *Controller) ExecuteABI(sender *common.Address, contract *Contract, abiJSON string) (bool, []*types.Log, *types.TransactionError) {
+	con.traceEnter(FrameCall, *sender, *contract.ContractAddress, abiJSON, con.Transaction.GetValue(), con.GasLeft)
+	if err := con.transferValue(sender); err != nil {
+		con.traceExit(con.GasLeft, err)
+		return false, nil, err
 	}
-	errorCode, errorMsg = con.VM.storeData() //store
-	if errorCode != 0 {
-		return false, nil, types.NewTransactionError(errorCode, errorMsg)
+	msg := Msg{Data: con.Transaction.GetData(), Value: con.Transaction.GetValue(), Sender: con.Transaction.GetSource().Hex()}
+	logs, gasLeft, err := con.engine.Execute(contract, msg, abiJSON, con.GasLeft)
+	con.GasLeft = gasLeft
+	con.traceExit(gasLeft, err)
+	if err != nil {
+		return false, nil, err
 	}
-	return true, con.VM.Logs, nil
+	return true, logs, nil
 }
 
 // ExecuteAbiEval Execute the contract with abi and returns result
 func (con *Controller) ExecuteAbiEval(sender *common.Address, contract *Contract, abiJSON string) *ExecuteResult {
-	con.VM = NewTVM(sender, contract, con.LibPath)
-	con.VM.SetGas(int(con.GasLeft))
-	defer func() {
-		con.VM.DelTVM()
-		con.GasLeft = uint64(con.VM.Gas())
-	}()
-	if con.Transaction.GetValue() > 0 {
-		amount := big.NewInt(int64(con.Transaction.GetValue()))
-		if canTransfer(con.AccountDB, *sender, amount) {
-			transfer(con.AccountDB, *sender, *con.Transaction.GetTarget(), amount)
-		} else {
-			return nil
-		}
+	result, _, _ := con.ExecuteABICall(sender, contract, abiJSON)
+	return result
+}
+
+// ExecuteABICall Execute the contract with abi and returns the result, logs and error.
+// The result is nil if the execution fails before the function returns, and is the exception if the function raises
+func (con *Controller) ExecuteABICall(sender *common.Address, contract *Contract, abiJSON string) (*ExecuteResult, []*types.Log, *types.TransactionError) {
+	con.traceEnter(FrameCall, *sender, *contract.ContractAddress, abiJSON, con.Transaction.GetValue(), con.GasLeft)
+	if err := con.transferValue(sender); err != nil {
+		con.traceExit(con.GasLeft, err)
+		return nil, nil, err
 	}
 	msg := Msg{Data: con.Transaction.GetData(), Value: con.Transaction.GetValue(), Sender: sender.Hex()}
-	errorCode, _, libLen := con.VM.CreateContractInstance(msg)
-	if errorCode != 0 {
-		return nil
-	}
-	abi := ABI{}
-	abiJSONError := json.Unmarshal([]byte(abiJSON), &abi)
-	if abiJSONError != nil {
-		return nil
-	}
-	errorCode, _ = con.VM.checkABI(abi) //checkABI
-	if errorCode != 0 {
-		return nil
-	}
-	con.VM.SetLibLine(libLen)
-	result := con.VM.executeABIKindEval(abi) //execute
-	if result.ResultType == 4 /*C.RETURN_TYPE_EXCEPTION*/ {
-		return result
-	}
-	errorCode, _ = con.VM.storeData() //store
-	if errorCode != 0 {
-		return nil
-	}
-	return result
+	result, logs, gasLeft, err := con.engine.Call(contract, msg, abiJSON, con.GasLeft)
+	con.GasLeft = gasLeft
+	con.traceExit(gasLeft, err)
+	return result, logs, err
 }
 
 // GetGasLeft get gas left
@@ -19,5 +19,10 @@ Package tvm implements the TVM bridge.
 TVM is TASChain Virtual Machine.
 
 TASChain Contract language is Python, execute at TVM.
+
+The controller runs the contracts with an Engine. The python engine is built with the tvm_python tag, which requires
+the ctvm library, and is the default engine when built in. Otherwise the go engine is the default, which only runs
+the native contracts registered in process by the contract name and rejects the python contracts deployed on chain.
+It's deterministic and meant for tests and devnets, not a replacement for the python engine on existing chains.
 */
 package tvm
@@ -0,0 +1,142 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package tvm
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/taschain/taschain/common"
+	"github.com/taschain/taschain/middleware/types"
+)
+
+// Names of the built-in engines
+const (
+	PythonEngine = "python"
+	GoEngine     = "go"
+)
+
+// Result types of the ExecuteResult, the values are the same as the ctvm library
+const (
+	ResultTypeNone      = 0
+	ResultTypeInt       = 1
+	ResultTypeString    = 2
+	ResultTypeBool      = 3
+	ResultTypeException = 4
+)
+
+// ExecuteResult is the result of the contract function, the content is the error message if it's an exception
+type ExecuteResult struct {
+	ResultType int
+	ErrorCode  int
+	Content    string
+	Abi        string
+}
+
+// Contract Contract contains the base message of a contract
+type Contract struct {
+	Code            string          `json:"code"`
+	ContractName    string          `json:"contract_name"`
+	ContractAddress *common.Address `json:"-"`
+}
+
+// Msg Msg is msg instance which store running message when running a contract
+type Msg struct {
+	Data   []byte
+	Value  uint64
+	Sender string
+}
+
+// ABI ABI stores the calling msg when execute contract
+type ABI struct {
+	FuncName string
+	Args     []interface{}
+}
+
+// Engine executes the contract code for the controller and reaches the chain through the host functions of the
+// controller. The gas given is the gas the engine can use, and the gas left is returned
+type Engine interface {
+	// Deploy runs the deploy of the contract and stores the contract data. No gas is charged if it fails
+	Deploy(contract *Contract, msg Msg, gas uint64) (gasLeft uint64, err *types.TransactionError)
+
+	// Execute runs the function of the contract in the abi json and stores the contract data if it succeeds
+	Execute(contract *Contract, msg Msg, abiJSON string, gas uint64) (logs []*types.Log, gasLeft uint64, err *types.TransactionError)
+
+	// Call runs the function the same as Execute and returns the result of the function, it's used by the read-only
+	// calls against a throwaway state. The result is nil if it fails before the function returns, and is the
+	// exception if the function raises
+	Call(contract *Contract, msg Msg, abiJSON string, gas uint64) (result *ExecuteResult, logs []*types.Log, gasLeft uint64, err *types.TransactionError)
+}
+
+// Host is the host functions the engine calls back into the chain, the contract is the address of the running one
+type Host interface {
+	Transfer(contract, to common.Address, value *big.Int) bool
+	GetBalance(addr common.Address) *big.Int
+	GetData(contract common.Address, key string) []byte
+	SetData(contract common.Address, key string, value []byte)
+	RemoveData(contract common.Address, key string)
+	BlockHash(height uint64) common.Hash
+	Number() uint64
+	Timestamp() uint64
+	TxGasLimit() uint64
+	NewLog(contract common.Address, name, index string, data []byte) *types.Log
+	MinerStake(contract, miner common.Address, minerType byte, value *big.Int) bool
+	MinerCancelStake(contract, miner common.Address, minerType byte, value *big.Int) bool
+	MinerRefundStake(contract, miner common.Address, minerType byte) bool
+}
+
+// EngineFactory creates the engine for the controller
+type EngineFactory func(con *Controller) Engine
+
+var (
+	engines    = make(map[string]EngineFactory)
+	engineName string
+)
+
+// RegisterEngine registers the engine factory with the name
+func RegisterEngine(name string, factory EngineFactory) {
+	engines[name] = factory
+}
+
+// SelectEngine sets the engine of the controllers created afterwards. All the nodes of a chain must use the same
+// engine, as the contract data are stored differently
+func SelectEngine(name string) error {
+	if _, ok := engines[name]; !ok {
+		if name == PythonEngine {
+			return fmt.Errorf("tvm engine %v is not built in, build with the tvm_python tag", name)
+		}
+		return fmt.Errorf("unknown tvm engine %v", name)
+	}
+	engineName = name
+	return nil
+}
+
+// EngineName returns the name of the engine the controllers use
+func EngineName() string {
+	if engineName != "" {
+		return engineName
+	}
+	if _, ok := engines[PythonEngine]; ok {
+		return PythonEngine
+	}
+	return GoEngine
+}
+
+// newEngine creates the selected engine, the python one is the default if it's built in with the tvm_python tag,
+// otherwise the go one
+func newEngine(con *Controller) Engine {
+	return engines[EngineName()](con)
+}
@@ -0,0 +1,337 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package tvm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/taschain/taschain/common"
+	"github.com/taschain/taschain/middleware/types"
+)
+
+// Gas costs of the host functions in the go engine
+const (
+	nativeGasCall      = 1000
+	nativeGasRead      = 200
+	nativeGasWrite     = 5000
+	nativeGasWriteByte = 20
+	nativeGasTransfer  = 2000
+	nativeGasLog       = 400
+	nativeGasLogByte   = 8
+	nativeGasChain     = 20
+	nativeGasStake     = 5000
+)
+
+// nativeDeployFunc is the function run at deploy, it can't be called by the abi
+const nativeDeployFunc = "deploy"
+
+var errOutOfGas = errors.New("gas not enough")
+
+// NativeFunc is the function of a native contract. The args are decoded from the abi json, so numbers are float64
+type NativeFunc func(ctx *NativeContext, args []interface{}) (interface{}, error)
+
+// NativeContract is the functions of a native contract by name
+type NativeContract map[string]NativeFunc
+
+var nativeContracts = make(map[string]NativeContract)
+
+// RegisterNativeContract registers the native contract run by the go engine. The contracts deployed with the
+// contract name run the registered functions, the code of the contract is stored but not run
+func RegisterNativeContract(name string, contract NativeContract) {
+	nativeContracts[name] = contract
+}
+
+func init() {
+	RegisterEngine(GoEngine, newGoEngine)
+}
+
+// goEngine is the deterministic engine running the native contracts registered in process. The code of the deployed
+// contract is never run, so the python contracts are rejected as unsupported, it's not a reference implementation
+// of the python engine. It's meant for tests and devnets built without the tvm_python tag
+type goEngine struct {
+	con   *Controller
+	gas   uint64
+	depth int
+	logs  []*types.Log
+}
+
+func newGoEngine(con *Controller) Engine {
+	return &goEngine{con: con}
+}
+
+func (e *goEngine) reset(gas uint64) {
+	e.gas = gas
+	e.depth = 0
+	e.logs = nil
+}
+
+func nativeContractOf(contract *Contract) (NativeContract, *types.TransactionError) {
+	if contract.Code == "" {
+		return nil, types.NewTransactionError(types.NoCodeErr, fmt.Sprintf(types.NoCodeErrorMsg, contract.ContractAddress.Hex()))
+	}
+	fns, ok := nativeContracts[contract.ContractName]
+	if !ok {
+		return nil, types.NewTransactionError(types.SysError, fmt.Sprintf("unsupported contract %v: the go engine only runs the registered native contracts, not the contract code", contract.ContractName))
+	}
+	return fns, nil
+}
+
+func (e *goEngine) context(contract *Contract, msg Msg) *NativeContext {
+	return &NativeContext{
+		engine:   e,
+		Contract: *contract.ContractAddress,
+		Sender:   common.HexToAddress(msg.Sender),
+		Value:    msg.Value,
+	}
+}
+
+// run runs the function with the base call cost charged, the panics of the function are turned into errors
+func (e *goEngine) run(ctx *NativeContext, fn NativeFunc, args []interface{}) (ret interface{}, err *types.TransactionError) {
+	defer func() {
+		if r := recover(); r != nil {
+			ret = nil
+			if r == errOutOfGas {
+				err = types.NewTransactionError(types.GasNotEnough, errOutOfGas.Error())
+			} else {
+				err = types.NewTransactionError(types.SysError, fmt.Sprint(r))
+			}
+		}
+	}()
+	ctx.UseGas(nativeGasCall)
+	ret, callErr := fn(ctx, args)
+	if callErr != nil {
+		return nil, types.NewTransactionError(types.SysError, callErr.Error())
+	}
+	return ret, nil
+}
+
+// Deploy implements Engine
+func (e *goEngine) Deploy(contract *Contract, msg Msg, gas uint64) (uint64, *types.TransactionError) {
+	e.reset(gas)
+	fns, err := nativeContractOf(contract)
+	if err != nil {
+		return gas, err
+	}
+	fn, ok := fns[nativeDeployFunc]
+	if !ok {
+		fn = func(ctx *NativeContext, args []interface{}) (interface{}, error) { return nil, nil }
+	}
+	snapshot := e.con.AccountDB.Snapshot()
+	if _, err = e.run(e.context(contract, msg), fn, nil); err != nil {
+		e.con.AccountDB.RevertToSnapshot(snapshot)
+		return gas, err
+	}
+	return e.gas, nil
+}
+
+// invoke runs the function in the abi json, the writes of the function are reverted if it fails. The ran flag
+// tells whether the function is found and run
+func (e *goEngine) invoke(contract *Contract, msg Msg, abiJSON string, gas uint64) (ret interface{}, ran bool, err *types.TransactionError) {
+	e.reset(gas)
+	fns, err := nativeContractOf(contract)
+	if err != nil {
+		return nil, false, err
+	}
+	abi := ABI{}
+	if json.Unmarshal([]byte(abiJSON), &abi) != nil {
+		return nil, false, types.TxErrorABIJSON
+	}
+	fn, ok := fns[abi.FuncName]
+	if !ok || abi.FuncName == nativeDeployFunc {
+		return nil, false, types.NewTransactionError(types.SysCheckABIError, fmt.Sprintf("checkABI failed. abi:%s", abi.FuncName))
+	}
+	snapshot := e.con.AccountDB.Snapshot()
+	if ret, err = e.run(e.context(contract, msg), fn, abi.Args); err != nil {
+		e.con.AccountDB.RevertToSnapshot(snapshot)
+		e.logs = nil
+		return nil, true, err
+	}
+	return ret, true, nil
+}
+
+// Execute implements Engine
+func (e *goEngine) Execute(contract *Contract, msg Msg, abiJSON string, gas uint64) ([]*types.Log, uint64, *types.TransactionError) {
+	if _, _, err := e.invoke(contract, msg, abiJSON, gas); err != nil {
+		return nil, e.gas, err
+	}
+	return e.logs, e.gas, nil
+}
+
+// Call implements Engine
+func (e *goEngine) Call(contract *Contract, msg Msg, abiJSON string, gas uint64) (*ExecuteResult, []*types.Log, uint64, *types.TransactionError) {
+	ret, ran, err := e.invoke(contract, msg, abiJSON, gas)
+	if err != nil {
+		if !ran {
+			return nil, nil, e.gas, err
+		}
+		return &ExecuteResult{ResultType: ResultTypeException, ErrorCode: err.Code, Content: err.Message}, nil, e.gas, err
+	}
+	result := &ExecuteResult{}
+	switch v := ret.(type) {
+	case nil:
+		result.ResultType = ResultTypeNone
+	case bool:
+		result.ResultType = ResultTypeBool
+		result.Content = strconv.FormatBool(v)
+	case string:
+		result.ResultType = ResultTypeString
+		result.Content = v
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, *big.Int:
+		result.ResultType = ResultTypeInt
+		result.Content = fmt.Sprint(v)
+	default:
+		bs, jsonErr := json.Marshal(v)
+		if jsonErr != nil {
+			err = types.NewTransactionError(types.SysError, jsonErr.Error())
+			return &ExecuteResult{ResultType: ResultTypeException, ErrorCode: err.Code, Content: err.Message}, nil, e.gas, err
+		}
+		result.ResultType = ResultTypeString
+		result.Content = string(bs)
+	}
+	return result, e.logs, e.gas, nil
+}
+
+// NativeContext is passed to the native functions to reach the chain. Each host function charges its gas, and the
+// function aborts if the gas runs out
+type NativeContext struct {
+	engine *goEngine
+
+	Contract common.Address
+	Sender   common.Address
+	Value    uint64
+}
+
+// UseGas charges the gas of the running transaction, it aborts the function if the gas runs out
+func (ctx *NativeContext) UseGas(gas uint64) {
+	if ctx.engine.gas < gas {
+		ctx.engine.gas = 0
+		panic(errOutOfGas)
+	}
+	ctx.engine.gas -= gas
+}
+
+// GetData returns the data of the key in the contract storage
+func (ctx *NativeContext) GetData(key string) []byte {
+	ctx.UseGas(nativeGasRead)
+	return ctx.engine.con.GetData(ctx.Contract, key)
+}
+
+// SetData sets the data of the key in the contract storage
+func (ctx *NativeContext) SetData(key string, value []byte) {
+	ctx.UseGas(nativeGasWrite + uint64(len(key)+len(value))*nativeGasWriteByte)
+	ctx.engine.con.SetData(ctx.Contract, key, value)
+}
+
+// RemoveData removes the key from the contract storage
+func (ctx *NativeContext) RemoveData(key string) {
+	ctx.UseGas(nativeGasWrite)
+	ctx.engine.con.RemoveData(ctx.Contract, key)
+}
+
+// Balance returns the balance of the address
+func (ctx *NativeContext) Balance(addr common.Address) *big.Int {
+	ctx.UseGas(nativeGasRead)
+	return ctx.engine.con.GetBalance(addr)
+}
+
+// Transfer transfers the value from the contract, false returned if the contract balance is not enough
+func (ctx *NativeContext) Transfer(to common.Address, value *big.Int) bool {
+	ctx.UseGas(nativeGasTransfer)
+	return ctx.engine.con.Transfer(ctx.Contract, to, value)
+}
+
+// Emit adds the event log of the contract
+func (ctx *NativeContext) Emit(name, index string, data []byte) {
+	ctx.UseGas(nativeGasLog + uint64(len(data))*nativeGasLogByte)
+	ctx.engine.logs = append(ctx.engine.logs, ctx.engine.con.NewLog(ctx.Contract, name, index, data))
+}
+
+// Number returns the height of the block executing the transaction
+func (ctx *NativeContext) Number() uint64 {
+	ctx.UseGas(nativeGasChain)
+	return ctx.engine.con.Number()
+}
+
+// Timestamp returns the unix time of the block executing the transaction
+func (ctx *NativeContext) Timestamp() uint64 {
+	ctx.UseGas(nativeGasChain)
+	return ctx.engine.con.Timestamp()
+}
+
+// BlockHash returns the hash of the block at the height
+func (ctx *NativeContext) BlockHash(height uint64) common.Hash {
+	ctx.UseGas(nativeGasRead)
+	return ctx.engine.con.BlockHash(height)
+}
+
+// MinerStake stakes the value from the contract to the miner
+func (ctx *NativeContext) MinerStake(miner common.Address, minerType byte, value *big.Int) bool {
+	ctx.UseGas(nativeGasStake)
+	return ctx.engine.con.MinerStake(ctx.Contract, miner, minerType, value)
+}
+
+// MinerCancelStake cancels the value the contract staked to the miner
+func (ctx *NativeContext) MinerCancelStake(miner common.Address, minerType byte, value *big.Int) bool {
+	ctx.UseGas(nativeGasStake)
+	return ctx.engine.con.MinerCancelStake(ctx.Contract, miner, minerType, value)
+}
+
+// MinerRefundStake refunds the stake the contract cancelled from the miner
+func (ctx *NativeContext) MinerRefundStake(miner common.Address, minerType byte) bool {
+	ctx.UseGas(nativeGasStake)
+	return ctx.engine.con.MinerRefundStake(ctx.Contract, miner, minerType)
+}
+
+// Call calls the function of the contract at the address with the contract as the sender. The writes and logs of
+// the callee are reverted if it fails, and the caller aborts too if the gas runs out
+func (ctx *NativeContext) Call(addr common.Address, funcName string, args ...interface{}) (interface{}, error) {
+	e := ctx.engine
+	if e.depth >= MaxDepth {
+		return nil, errors.New(types.CallMaxDeepErrorMsg)
+	}
+	contract := e.con.loadContract(addr)
+	fns, txErr := nativeContractOf(contract)
+	if txErr != nil {
+		return nil, errors.New(txErr.Message)
+	}
+	fn, ok := fns[funcName]
+	if !ok || funcName == nativeDeployFunc {
+		return nil, fmt.Errorf("function %v not found in contract %v", funcName, addr.Hex())
+	}
+
+	snapshot := e.con.AccountDB.Snapshot()
+	logs := len(e.logs)
+	if e.con.Tracer != nil {
+		e.con.Tracer.CaptureEnter(FrameCall, ctx.Contract, addr, funcName, args, 0, e.gas)
+	}
+	e.depth++
+	ret, txErr := e.run(&NativeContext{engine: e, Contract: addr, Sender: ctx.Contract}, fn, args)
+	e.depth--
+	e.con.traceExit(e.gas, txErr)
+	if txErr != nil {
+		e.con.AccountDB.RevertToSnapshot(snapshot)
+		e.logs = e.logs[:logs]
+		if txErr.Code == types.GasNotEnough {
+			panic(errOutOfGas)
+		}
+		return nil, errors.New(txErr.Message)
+	}
+	return ret, nil
+}
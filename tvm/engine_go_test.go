@@ -0,0 +1,140 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package tvm
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/taschain/taschain/common"
+	"github.com/taschain/taschain/middleware/types"
+	"github.com/taschain/taschain/storage/account"
+	"github.com/taschain/taschain/storage/tasdb"
+)
+
+func init() {
+	RegisterNativeContract("counter", NativeContract{
+		"deploy": func(ctx *NativeContext, args []interface{}) (interface{}, error) {
+			ctx.SetData("count", []byte("0"))
+			return nil, nil
+		},
+		"add": func(ctx *NativeContext, args []interface{}) (interface{}, error) {
+			count, _ := strconv.Atoi(string(ctx.GetData("count")))
+			count += int(args[0].(float64))
+			ctx.SetData("count", []byte(strconv.Itoa(count)))
+			ctx.Emit("added", "count", []byte(strconv.Itoa(count)))
+			return nil, nil
+		},
+		"get": func(ctx *NativeContext, args []interface{}) (interface{}, error) {
+			count, _ := strconv.Atoi(string(ctx.GetData("count")))
+			return count, nil
+		},
+		"fail": func(ctx *NativeContext, args []interface{}) (interface{}, error) {
+			ctx.SetData("count", []byte("100"))
+			return nil, errors.New("failed")
+		},
+		"burn": func(ctx *NativeContext, args []interface{}) (interface{}, error) {
+			for {
+				ctx.UseGas(1000)
+			}
+		},
+		"proxy": func(ctx *NativeContext, args []interface{}) (interface{}, error) {
+			return ctx.Call(ctx.Contract, args[0].(string), args[1:]...)
+		},
+	})
+}
+
+func newGoEngineController(t *testing.T, addr common.Address, gasLimit uint64) *Controller {
+	mem, _ := tasdb.NewMemDatabase()
+	db, err := account.NewAccountDB(common.Hash{}, account.NewDatabase(mem))
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := json.Marshal(&Contract{Code: "native", ContractName: "counter"})
+	db.CreateAccount(addr)
+	db.SetCode(addr, code)
+
+	source := common.HexToAddress("0x1")
+	tx := types.Transaction{Source: &source, Target: &addr, GasLimit: gasLimit}
+	header := &types.BlockHeader{Height: 1}
+	return NewController(db, nil, header, tx, 0, "", nil, nil)
+}
+
+func TestGoEngine(t *testing.T) {
+	common.InitConf("../cmd/gtas/cli/tas.ini")
+	if err := SelectEngine("unknown"); err == nil {
+		t.Fatal("unknown engine selected")
+	}
+	if err := SelectEngine(GoEngine); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { engineName = "" }()
+
+	// The python contract is not run by the go engine
+	python := &Contract{Code: "class Token(object):", ContractName: "Token", ContractAddress: &common.Address{}}
+	if code, msg := newGoEngineController(t, common.HexToAddress("0x3"), 100000).Deploy(python); code != types.SysError || !strings.HasPrefix(msg, "unsupported contract") {
+		t.Errorf("python contract deployed: %v %v", code, msg)
+	}
+
+	addr := common.HexToAddress("0x2")
+	con := newGoEngineController(t, addr, 100000)
+	if code, msg := con.Deploy(LoadContract(addr)); code != 0 {
+		t.Fatalf("deploy failed: %v %v", code, msg)
+	}
+	if used := 100000 - con.GetGasLeft(); used != nativeGasCall+nativeGasWrite+6*nativeGasWriteByte {
+		t.Errorf("deploy gas used %v", used)
+	}
+
+	contract := LoadContract(addr)
+	source := common.HexToAddress("0x1")
+	success, logs, err := con.ExecuteABI(&source, contract, `{"FuncName":"add","Args":[5]}`)
+	if !success || err != nil || len(logs) != 1 || string(logs[0].Data) != "5" {
+		t.Fatalf("add: %v %v %v", success, logs, err)
+	}
+
+	result, _, err := con.ExecuteABICall(&source, contract, `{"FuncName":"get","Args":[]}`)
+	if err != nil || result.ResultType != ResultTypeInt || result.Content != "5" {
+		t.Errorf("get: %+v %v", result, err)
+	}
+	result, _, err = con.ExecuteABICall(&source, contract, `{"FuncName":"proxy","Args":["get"]}`)
+	if err != nil || result.Content != "5" {
+		t.Errorf("proxy get: %+v %v", result, err)
+	}
+
+	result, _, err = con.ExecuteABICall(&source, contract, `{"FuncName":"fail","Args":[]}`)
+	if err == nil || err.Code != types.SysError || result.ResultType != ResultTypeException {
+		t.Errorf("fail: %+v %v", result, err)
+	}
+	if v := string(con.AccountDB.GetData(addr, "count")); v != "5" {
+		t.Errorf("failed call not reverted, count %v", v)
+	}
+
+	if _, _, err = con.ExecuteABI(&source, contract, `{"FuncName":"deploy","Args":[]}`); err == nil || err.Code != types.SysCheckABIError {
+		t.Errorf("deploy called by abi: %v", err)
+	}
+	if _, _, err = con.ExecuteABI(&source, contract, `{"FuncName":"add",`); err == nil || err.Code != types.SysABIJSONError {
+		t.Errorf("bad abi json: %v", err)
+	}
+	if _, _, err = con.ExecuteABI(&source, contract, `{"FuncName":"burn","Args":[]}`); err == nil || err.Code != types.GasNotEnough {
+		t.Errorf("burn: %v", err)
+	}
+	if con.GetGasLeft() != 0 {
+		t.Errorf("gas left %v after running out of gas", con.GetGasLeft())
+	}
+}
@@ -0,0 +1,143 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+//go:build tvm_python
+// +build tvm_python
+
+package tvm
+
+import (
+	"encoding/json"
+
+	"github.com/taschain/taschain/common"
+	"github.com/taschain/taschain/middleware/types"
+)
+
+func init() {
+	RegisterEngine(PythonEngine, newPyEngine)
+}
+
+// pyEngine runs the python contracts in the ctvm library. The library calls back into the bridge functions, which
+// reach the chain through the global controller and the vm on top of the engine
+type pyEngine struct {
+	con     *Controller
+	vm      *TVM
+	vmStack []*TVM
+}
+
+func newPyEngine(con *Controller) Engine {
+	return &pyEngine{con: con, vmStack: make([]*TVM, 0)}
+}
+
+// currentVM returns the python vm running the contract of the global controller
+func currentVM() *TVM {
+	return controller.engine.(*pyEngine).vm
+}
+
+// storeVMContext pushes the running vm and runs the new one
+func (e *pyEngine) storeVMContext(newTvm *TVM) bool {
+	if len(e.vmStack) >= MaxDepth {
+		return false
+	}
+	e.vmStack = append(e.vmStack, e.vm)
+	e.vm = newTvm
+	return true
+}
+
+func (e *pyEngine) newVM(contract *Contract, msg Msg, gas uint64) {
+	sender := common.HexToAddress(msg.Sender)
+	e.vm = NewTVM(&sender, contract, e.con.LibPath)
+	e.vm.SetGas(int(gas))
+}
+
+// Deploy implements Engine
+func (e *pyEngine) Deploy(contract *Contract, msg Msg, gas uint64) (uint64, *types.TransactionError) {
+	e.newVM(contract, msg, gas)
+	defer func() {
+		e.vm.DelTVM()
+	}()
+	errorCode, errorMsg := e.vm.Deploy(msg)
+	if errorCode != 0 {
+		return gas, types.NewTransactionError(errorCode, errorMsg)
+	}
+	errorCode, errorMsg = e.vm.storeData()
+	if errorCode != 0 {
+		return gas, types.NewTransactionError(errorCode, errorMsg)
+	}
+	return uint64(e.vm.Gas()), nil
+}
+
+// prepare creates the contract instance and checks the abi before running the function
+func (e *pyEngine) prepare(msg Msg, abiJSON string) (ABI, *types.TransactionError) {
+	abi := ABI{}
+	errorCode, errorMsg, libLen := e.vm.CreateContractInstance(msg)
+	if errorCode != 0 {
+		return abi, types.NewTransactionError(errorCode, errorMsg)
+	}
+	abiJSONError := json.Unmarshal([]byte(abiJSON), &abi)
+	if abiJSONError != nil {
+		return abi, types.TxErrorABIJSON
+	}
+	errorCode, errorMsg = e.vm.checkABI(abi) //checkABI
+	if errorCode != 0 {
+		return abi, types.NewTransactionError(errorCode, errorMsg)
+	}
+	e.vm.SetLibLine(libLen)
+	return abi, nil
+}
+
+// Execute implements Engine
+func (e *pyEngine) Execute(contract *Contract, msg Msg, abiJSON string, gas uint64) (logs []*types.Log, gasLeft uint64, err *types.TransactionError) {
+	e.newVM(contract, msg, gas)
+	defer func() {
+		e.vm.DelTVM()
+		gasLeft = uint64(e.vm.Gas())
+	}()
+	abi, err := e.prepare(msg, abiJSON)
+	if err != nil {
+		return nil, 0, err
+	}
+	errorCode, errorMsg := e.vm.executABIVMSucceed(abi) //execute
+	if errorCode != 0 {
+		return nil, 0, types.NewTransactionError(errorCode, errorMsg)
+	}
+	errorCode, errorMsg = e.vm.storeData() //store
+	if errorCode != 0 {
+		return nil, 0, types.NewTransactionError(errorCode, errorMsg)
+	}
+	return e.vm.Logs, 0, nil
+}
+
+// Call implements Engine
+func (e *pyEngine) Call(contract *Contract, msg Msg, abiJSON string, gas uint64) (result *ExecuteResult, logs []*types.Log, gasLeft uint64, err *types.TransactionError) {
+	e.newVM(contract, msg, gas)
+	defer func() {
+		e.vm.DelTVM()
+		gasLeft = uint64(e.vm.Gas())
+	}()
+	abi, err := e.prepare(msg, abiJSON)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	result = e.vm.executeABIKindEval(abi) //execute
+	if result.ResultType == ResultTypeException {
+		return result, nil, 0, types.NewTransactionError(result.ErrorCode, result.Content)
+	}
+	errorCode, errorMsg := e.vm.storeData() //store
+	if errorCode != 0 {
+		return nil, nil, 0, types.NewTransactionError(errorCode, errorMsg)
+	}
+	return result, e.vm.Logs, 0, nil
+}
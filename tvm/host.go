@@ -0,0 +1,170 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package tvm
+
+import (
+	"math/big"
+
+	"github.com/taschain/taschain/common"
+	"github.com/taschain/taschain/middleware/types"
+)
+
+// Transfer transfers the value from the contract, nothing happens if the contract balance is not enough
+func (con *Controller) Transfer(contract, to common.Address, value *big.Int) bool {
+	if con.AccountDB.GetBalance(contract).Cmp(value) < 0 {
+		return false
+	}
+	con.AccountDB.AddBalance(to, value)
+	con.AccountDB.SubBalance(contract, value)
+	if con.Tracer != nil {
+		con.Tracer.CaptureTransfer(contract, to, value)
+	}
+	return true
+}
+
+// GetBalance returns the balance of the address
+func (con *Controller) GetBalance(addr common.Address) *big.Int {
+	return con.AccountDB.GetBalance(addr)
+}
+
+// GetData returns the data of the key in the contract storage
+func (con *Controller) GetData(contract common.Address, key string) []byte {
+	value := con.AccountDB.GetData(contract, key)
+	if con.Tracer != nil {
+		con.Tracer.CaptureStorage(StorageRead, contract, key, value)
+	}
+	return value
+}
+
+// SetData sets the data of the key in the contract storage
+func (con *Controller) SetData(contract common.Address, key string, value []byte) {
+	con.AccountDB.SetData(contract, key, value)
+	if con.Tracer != nil {
+		con.Tracer.CaptureStorage(StorageWrite, contract, key, value)
+	}
+}
+
+// RemoveData removes the key from the contract storage
+func (con *Controller) RemoveData(contract common.Address, key string) {
+	con.AccountDB.RemoveData(contract, key)
+	if con.Tracer != nil {
+		con.Tracer.CaptureStorage(StorageRemove, contract, key, nil)
+	}
+}
+
+// BlockHash returns the hash of the block at the height, the empty hash if not found
+func (con *Controller) BlockHash(height uint64) common.Hash {
+	block := con.Reader.QueryBlockHeaderByHeight(height)
+	if block == nil {
+		return common.Hash{}
+	}
+	return block.Hash
+}
+
+// Number returns the height of the block executing the transaction
+func (con *Controller) Number() uint64 {
+	return con.BlockHeader.Height
+}
+
+// Timestamp returns the unix time of the block executing the transaction
+func (con *Controller) Timestamp() uint64 {
+	return uint64(con.BlockHeader.CurTime.Unix())
+}
+
+// TxGasLimit returns the gas limit of the transaction
+func (con *Controller) TxGasLimit() uint64 {
+	return con.Transaction.GetGasLimit()
+}
+
+// NewLog creates the log of the event emitted by the contract
+func (con *Controller) NewLog(contract common.Address, name, index string, data []byte) *types.Log {
+	log := &types.Log{
+		Topics: []common.Hash{
+			common.BytesToHash(common.Sha256([]byte(name))),
+			common.BytesToHash(common.Sha256([]byte(index))),
+		},
+		Data:    data,
+		TxHash:  con.Transaction.GetHash(),
+		Address: contract,
+		// The block is running, no block hash this time
+		BlockNumber: con.BlockHeader.Height,
+	}
+	if con.Tracer != nil {
+		con.Tracer.CaptureLog(log)
+	}
+	return log
+}
+
+// MinerStake stakes the value from the contract to the miner
+func (con *Controller) MinerStake(contract, miner common.Address, minerType byte, value *big.Int) bool {
+	ss := con.AccountDB.Snapshot()
+	if canTransfer(con.AccountDB, contract, value) {
+		mexist := con.mm.GetMinerByID(miner.Bytes(), minerType, con.AccountDB)
+		if mexist != nil &&
+			con.mm.AddStake(mexist.ID, mexist, value.Uint64(), con.AccountDB) &&
+			con.mm.AddStakeDetail(contract.Bytes(), mexist, value.Uint64(), con.AccountDB) {
+			con.AccountDB.SubBalance(contract, value)
+			return true
+		}
+	}
+	con.AccountDB.RevertToSnapshot(ss)
+	return false
+}
+
+// MinerCancelStake cancels the value the contract staked to the miner
+func (con *Controller) MinerCancelStake(contract, miner common.Address, minerType byte, value *big.Int) bool {
+	ss := con.AccountDB.Snapshot()
+	mexist := con.mm.GetMinerByID(miner.Bytes(), minerType, con.AccountDB)
+	if mexist != nil &&
+		con.mm.CancelStake(contract.Bytes(), mexist, value.Uint64(), con.AccountDB, con.BlockHeader.Height) &&
+		con.mm.ReduceStake(mexist.ID, mexist, value.Uint64(), con.AccountDB, con.BlockHeader.Height) {
+		return true
+	}
+	con.AccountDB.RevertToSnapshot(ss)
+	return false
+}
+
+// MinerRefundStake refunds the stake the contract cancelled from the miner
+func (con *Controller) MinerRefundStake(contract, miner common.Address, minerType byte) bool {
+	var success = false
+	ss := con.AccountDB.Snapshot()
+	mexist := con.mm.GetMinerByID(miner.Bytes(), minerType, con.AccountDB)
+	height := con.BlockHeader.Height
+	if mexist != nil {
+		if mexist.Type == types.MinerTypeHeavy {
+			latestCancelPledgeHeight := con.mm.GetLatestCancelStakeHeight(contract.Bytes(), mexist, con.AccountDB)
+			if height > latestCancelPledgeHeight+10 || (mexist.Status == types.MinerStatusAbort && height > mexist.AbortHeight+10) {
+				value, ok := con.mm.RefundStake(contract.Bytes(), mexist, con.AccountDB)
+				if ok {
+					refundValue := big.NewInt(0).SetUint64(value)
+					con.AccountDB.AddBalance(contract, refundValue)
+					success = true
+				}
+			}
+		} else {
+			value, ok := con.mm.RefundStake(contract.Bytes(), mexist, con.AccountDB)
+			if ok {
+				refundValue := big.NewInt(0).SetUint64(value)
+				con.AccountDB.AddBalance(contract, refundValue)
+				success = true
+			}
+		}
+	}
+	if !success {
+		con.AccountDB.RevertToSnapshot(ss)
+	}
+	return success
+}
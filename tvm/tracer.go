@@ -0,0 +1,169 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package tvm
+
+import (
+	"encoding/json"
+	"math/big"
+
+	"github.com/taschain/taschain/common"
+	"github.com/taschain/taschain/middleware/types"
+)
+
+// Types of the call frames
+const (
+	FrameCreate = "create"
+	FrameCall   = "call"
+)
+
+// Storage operations captured by the tracer
+const (
+	StorageRead   = "read"
+	StorageWrite  = "write"
+	StorageRemove = "remove"
+)
+
+// Tracer captures the contract execution of the controller. The frames are entered and exited in pairs, the other
+// events belong to the innermost frame entered
+type Tracer interface {
+	CaptureEnter(frameType string, from, to common.Address, funcName string, args []interface{}, value uint64, gas uint64)
+	CaptureExit(gasLeft uint64, err *types.TransactionError)
+	CaptureStorage(op string, contract common.Address, key string, value []byte)
+	CaptureTransfer(from, to common.Address, value *big.Int)
+	CaptureLog(log *types.Log)
+}
+
+// StorageAccess is a read, write or remove of the contract storage
+type StorageAccess struct {
+	Op       string         `json:"op"`
+	Contract common.Address `json:"contract"`
+	Key      string         `json:"key"`
+	Value    string         `json:"value,omitempty"`
+}
+
+// ValueTransfer is a transfer of the value made by the frame
+type ValueTransfer struct {
+	From  common.Address `json:"from"`
+	To    common.Address `json:"to"`
+	Value *big.Int       `json:"value"`
+}
+
+// CallFrame is the trace of a contract deploy or call, the nested contract calls are in the calls
+type CallFrame struct {
+	Type      string           `json:"type"`
+	From      common.Address   `json:"from"`
+	To        common.Address   `json:"to"`
+	Function  string           `json:"function,omitempty"`
+	Args      []interface{}    `json:"args,omitempty"`
+	Value     uint64           `json:"value"`
+	Gas       uint64           `json:"gas"`
+	GasUsed   uint64           `json:"gas_used"`
+	ErrorCode int              `json:"error_code,omitempty"`
+	Error     string           `json:"error_msg,omitempty"`
+	Storage   []*StorageAccess `json:"storage,omitempty"`
+	Transfers []*ValueTransfer `json:"transfers,omitempty"`
+	Logs      []*types.Log     `json:"logs,omitempty"`
+	Calls     []*CallFrame     `json:"calls,omitempty"`
+}
+
+// CallTracer builds the tree of the call frames
+type CallTracer struct {
+	root  *CallFrame
+	stack []*CallFrame
+}
+
+// NewCallTracer creates the call tracer
+func NewCallTracer() *CallTracer {
+	return &CallTracer{stack: make([]*CallFrame, 0)}
+}
+
+// Result returns the outermost frame, nil if no contract executed
+func (t *CallTracer) Result() *CallFrame {
+	return t.root
+}
+
+func (t *CallTracer) current() *CallFrame {
+	if len(t.stack) == 0 {
+		return nil
+	}
+	return t.stack[len(t.stack)-1]
+}
+
+// CaptureEnter implements Tracer
+func (t *CallTracer) CaptureEnter(frameType string, from, to common.Address, funcName string, args []interface{}, value uint64, gas uint64) {
+	frame := &CallFrame{Type: frameType, From: from, To: to, Function: funcName, Args: args, Value: value, Gas: gas}
+	if parent := t.current(); parent != nil {
+		parent.Calls = append(parent.Calls, frame)
+	} else if t.root == nil {
+		t.root = frame
+	}
+	t.stack = append(t.stack, frame)
+}
+
+// CaptureExit implements Tracer
+func (t *CallTracer) CaptureExit(gasLeft uint64, err *types.TransactionError) {
+	frame := t.current()
+	if frame == nil {
+		return
+	}
+	t.stack = t.stack[:len(t.stack)-1]
+	if gasLeft < frame.Gas {
+		frame.GasUsed = frame.Gas - gasLeft
+	}
+	if err != nil {
+		frame.ErrorCode = err.Code
+		frame.Error = err.Message
+	}
+}
+
+// CaptureStorage implements Tracer
+func (t *CallTracer) CaptureStorage(op string, contract common.Address, key string, value []byte) {
+	if frame := t.current(); frame != nil {
+		frame.Storage = append(frame.Storage, &StorageAccess{Op: op, Contract: contract, Key: key, Value: string(value)})
+	}
+}
+
+// CaptureTransfer implements Tracer
+func (t *CallTracer) CaptureTransfer(from, to common.Address, value *big.Int) {
+	if frame := t.current(); frame != nil {
+		frame.Transfers = append(frame.Transfers, &ValueTransfer{From: from, To: to, Value: new(big.Int).Set(value)})
+	}
+}
+
+// CaptureLog implements Tracer
+func (t *CallTracer) CaptureLog(log *types.Log) {
+	if frame := t.current(); frame != nil {
+		frame.Logs = append(frame.Logs, log)
+	}
+}
+
+// traceEnter enters the frame if the controller is traced, the function and args are taken from the abi json
+func (con *Controller) traceEnter(frameType string, from, to common.Address, abiJSON string, value uint64, gas uint64) {
+	if con.Tracer == nil {
+		return
+	}
+	abi := ABI{}
+	if abiJSON != "" {
+		_ = json.Unmarshal([]byte(abiJSON), &abi)
+	}
+	con.Tracer.CaptureEnter(frameType, from, to, abi.FuncName, abi.Args, value, gas)
+}
+
+func (con *Controller) traceExit(gasLeft uint64, err *types.TransactionError) {
+	if con.Tracer != nil {
+		con.Tracer.CaptureExit(gasLeft, err)
+	}
+}
@@ -0,0 +1,75 @@
+//   Copyright (C) 2018 TASChain
+//
+//   This program is free software: you can redistribute it and/or modify
+//   it under the terms of the GNU General Public License as published by
+//   the Free Software Foundation, either version 3 of the License, or
+//   (at your option) any later version.
+//
+//   This program is distributed in the hope that it will be useful,
+//   but WITHOUT ANY WARRANTY; without even the implied warranty of
+//   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//   GNU General Public License for more details.
+//
+//   You should have received a copy of the GNU General Public License
+//   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package tvm
+
+import (
+	"testing"
+
+	"github.com/taschain/taschain/common"
+	"github.com/taschain/taschain/middleware/types"
+)
+
+func TestCallTracer(t *testing.T) {
+	common.InitConf("../cmd/gtas/cli/tas.ini")
+	if err := SelectEngine(GoEngine); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { engineName = "" }()
+
+	addr := common.HexToAddress("0x2")
+	source := common.HexToAddress("0x1")
+	con := newGoEngineController(t, addr, 100000)
+	tracer := NewCallTracer()
+	con.Tracer = tracer
+	if _, _, err := con.ExecuteABI(&source, LoadContract(addr), `{"FuncName":"proxy","Args":["add",3]}`); err != nil {
+		t.Fatal(err)
+	}
+
+	root := tracer.Result()
+	if root == nil || root.Type != FrameCall || root.From != source || root.To != addr || root.Function != "proxy" {
+		t.Fatalf("unexpected root frame %+v", root)
+	}
+	if root.GasUsed != 100000-con.GetGasLeft() {
+		t.Errorf("root gas used %v, want %v", root.GasUsed, 100000-con.GetGasLeft())
+	}
+	if len(root.Calls) != 1 {
+		t.Fatalf("nested calls %v", len(root.Calls))
+	}
+	nested := root.Calls[0]
+	if nested.Function != "add" || nested.From != addr || nested.GasUsed == 0 || nested.GasUsed >= root.GasUsed {
+		t.Errorf("unexpected nested frame %+v", nested)
+	}
+	if len(nested.Storage) != 2 || nested.Storage[0].Op != StorageRead || nested.Storage[1].Op != StorageWrite || nested.Storage[1].Value != "3" {
+		t.Errorf("unexpected storage accesses %+v", nested.Storage)
+	}
+	if len(nested.Logs) != 1 || len(root.Logs) != 0 {
+		t.Errorf("logs not captured in the nested frame")
+	}
+
+	tracer = NewCallTracer()
+	con.Tracer = tracer
+	if _, _, err := con.ExecuteABI(&source, LoadContract(addr), `{"FuncName":"fail","Args":[]}`); err == nil {
+		t.Fatal("fail succeeded")
+	}
+	if root = tracer.Result(); root.ErrorCode != types.SysError || root.Error != "failed" || len(root.Storage) != 1 {
+		t.Errorf("unexpected failed frame %+v", root)
+	}
+
+	// The tracer is reset for the next transaction
+	if con = newGoEngineController(t, addr, 100000); con.Tracer != nil {
+		t.Errorf("tracer not reset")
+	}
+}